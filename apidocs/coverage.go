@@ -0,0 +1,101 @@
+package apidocs
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// document is the part of an OpenAPI document needed to compare it with registered routes
+type document struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// operationMethods are the keys of an OpenAPI path item that describe an operation
+var operationMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Undocumented returns "METHOD /path" for every route registered on e that has no operation
+// in spec. Routes added by Register are ignored. Paths are compared in OpenAPI form, so the
+// echo route /users/:id matches the spec path /users/{id}.
+func Undocumented(e *echo.Echo, spec []byte) ([]string, error) {
+	documented, err := operations(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for key := range registered(e) {
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// Unregistered returns "METHOD /path" for every operation in spec that no route on e serves,
+// so the document does not keep describing endpoints that were removed
+func Unregistered(e *echo.Echo, spec []byte) ([]string, error) {
+	documented, err := operations(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := registered(e)
+	var stale []string
+	for key := range documented {
+		if !routes[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+func operations(spec []byte) (map[string]bool, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+
+	ops := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			if operationMethods[method] {
+				ops[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+	return ops, nil
+}
+
+func registered(e *echo.Echo) map[string]bool {
+	routes := make(map[string]bool)
+	for _, r := range e.Routes() {
+		if r.Path == SpecPath || r.Path == UIPath || strings.HasPrefix(r.Path, UIPath+"/") {
+			continue
+		}
+		if r.Method == echo.RouteNotFound {
+			continue
+		}
+		routes[r.Method+" "+openAPIPath(r.Path)] = true
+	}
+	return routes
+}
+
+// openAPIPath converts echo path parameters (:id) to OpenAPI templates ({id})
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
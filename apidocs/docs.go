@@ -0,0 +1,75 @@
+package apidocs
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+// Paths served by Register
+const (
+	SpecPath = "/openapi.json"
+	UIPath   = "/docs"
+)
+
+// indexHTML loads the bundled Swagger UI assets and points it at SpecPath
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="` + UIPath + `/swagger-ui.css">
+  <link rel="icon" type="image/png" href="` + UIPath + `/favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="` + UIPath + `/swagger-ui-bundle.js"></script>
+  <script src="` + UIPath + `/swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: "` + SpecPath + `",
+        dom_id: "#swagger-ui",
+        deepLinking: true,
+        presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+        layout: "StandaloneLayout"
+      });
+    };
+  </script>
+</body>
+</html>
+`
+
+type asset struct {
+	contentType string
+	body        []byte
+}
+
+// assets are the Swagger UI files needed by indexHTML, served from memory
+var assets = map[string]asset{
+	"swagger-ui.css":                  {"text/css; charset=utf-8", swaggerFiles.FileSwaggerUICSS},
+	"swagger-ui-bundle.js":            {"application/javascript; charset=utf-8", swaggerFiles.FileSwaggerUIBundleJs},
+	"swagger-ui-standalone-preset.js": {"application/javascript; charset=utf-8", swaggerFiles.FileSwaggerUIStandalonePresetJs},
+	"favicon-32x32.png":               {"image/png", swaggerFiles.FileFavicon32x32Png},
+	"favicon-16x16.png":               {"image/png", swaggerFiles.FileFavicon16x16Png},
+}
+
+// Register serves spec at SpecPath and a Swagger UI for it at UIPath. The UI is bundled,
+// so the documentation works without access to a CDN.
+func Register(e *echo.Echo, spec []byte) {
+	e.GET(SpecPath, func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, spec)
+	})
+	e.GET(UIPath, func(c echo.Context) error {
+		return c.HTML(http.StatusOK, indexHTML)
+	})
+	e.GET(UIPath+"/:file", func(c echo.Context) error {
+		a, ok := assets[c.Param("file")]
+		if !ok {
+			return echo.ErrNotFound
+		}
+		c.Response().Header().Set("Cache-Control", "public, max-age=86400")
+		return c.Blob(http.StatusOK, a.contentType, a.body)
+	})
+}
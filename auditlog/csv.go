@@ -0,0 +1,52 @@
+package auditlog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"id", "occurred_at", "actor_id", "subject_type", "subject_id", "action", "outcome", "ip_address", "user_agent", "metadata"}
+
+// WriteCSV writes the events with a header row, metadata is JSON encoded
+func WriteCSV(w io.Writer, events []Event) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+		err = writer.Write([]string{
+			event.ID,
+			event.OccurredAt.UTC().Format(time.RFC3339),
+			csvCell(event.ActorID),
+			csvCell(event.SubjectType),
+			csvCell(event.SubjectID),
+			csvCell(event.Action),
+			event.Outcome,
+			csvCell(event.IPAddress),
+			csvCell(event.UserAgent),
+			csvCell(string(metadata)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell prefixes values that spreadsheets would evaluate as formulas, e.g. a crafted User-Agent
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
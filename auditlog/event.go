@@ -0,0 +1,103 @@
+package auditlog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Outcome values stored in Event.Outcome
+const (
+	Success = "success"
+	Failure = "failure"
+	Denied  = "denied"
+)
+
+// Event is a row of the append-only audit_events table
+type Event struct {
+	ID          string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OccurredAt  time.Time `gorm:"not null;index" json:"occurred_at"`
+	ActorID     string    `gorm:"index" json:"actor_id,omitempty"` // Empty for anonymous requests such as a failed login
+	SubjectType string    `json:"subject_type,omitempty"`          // e.g. user, api_key, oauth_client
+	SubjectID   string    `gorm:"index" json:"subject_id,omitempty"`
+	Action      string    `gorm:"not null;index" json:"action"` // e.g. user.login, user.password_change
+	Outcome     string    `gorm:"not null" json:"outcome"`
+	IPAddress   string    `json:"ip_address,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Metadata    Metadata  `gorm:"type:jsonb;not null;default:'{}'" json:"metadata"`
+}
+
+// TableName keeps the table name independent of the struct name
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+// Actor is who performed an action and from where
+type Actor struct {
+	ID        string
+	IPAddress string
+	UserAgent string
+}
+
+// Event starts an event performed by the actor
+func (a Actor) Event(action, outcome string) Event {
+	return Event{
+		ActorID:   a.ID,
+		Action:    action,
+		Outcome:   outcome,
+		IPAddress: a.IPAddress,
+		UserAgent: a.UserAgent,
+	}
+}
+
+// On sets the subject of the event
+func (e Event) On(subjectType, subjectID string) Event {
+	e.SubjectType = subjectType
+	e.SubjectID = subjectID
+	return e
+}
+
+// With adds metadata to the event. Rows are append-only and are not anonymized when a user is
+// purged, so metadata should reference users by ID rather than hold usernames or emails.
+func (e Event) With(key string, value interface{}) Event {
+	metadata := make(Metadata, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	e.Metadata = metadata
+	return e
+}
+
+// Metadata is free-form event detail stored as jsonb
+type Metadata map[string]interface{}
+
+// Value implements driver.Valuer
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (m *Metadata) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("auditlog: unsupported metadata type")
+	}
+	return json.Unmarshal(b, m)
+}
@@ -0,0 +1,184 @@
+package auditlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink persists a batch of events
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+// Config configures Logger
+type Config struct {
+	QueueSize     int           // Defaults to 1024
+	BatchSize     int           // Defaults to 100
+	FlushInterval time.Duration // Defaults to 1s
+	MaxRetries    int           // Defaults to 5, a batch is dropped after that
+}
+
+// Stats are counters of a Logger since it started
+type Stats struct {
+	Queued  int    `json:"queued"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"` // Queue full or retries exhausted
+}
+
+// Logger records events through a bounded in-process queue so that a slow or unavailable
+// sink never blocks or fails the business operation. A nil *Logger discards events.
+type Logger struct {
+	sink   Sink
+	cfg    Config
+	queue  chan Event
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewLogger starts the background writer
+func NewLogger(sink Sink, cfg Config) *Logger {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+
+	l := &Logger{
+		sink:  sink,
+		cfg:   cfg,
+		queue: make(chan Event, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Record queues the event without blocking. The event is dropped when the queue is full.
+func (l *Logger) Record(event Event) {
+	if l == nil {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.Metadata == nil {
+		event.Metadata = Metadata{}
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+
+	select {
+	case l.queue <- event:
+	default:
+		if l.dropped.Add(1)%100 == 1 {
+			slog.Warn("auditlog: queue full, dropping event", "action", event.Action)
+		}
+	}
+}
+
+// Close stops accepting events and waits until the queue is written or ctx is done
+func (l *Logger) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the queue length and counters
+func (l *Logger) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+	return Stats{
+		Queued:  len(l.queue),
+		Written: l.written.Load(),
+		Dropped: l.dropped.Load(),
+	}
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case event, ok := <-l.queue:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= l.cfg.BatchSize {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes the batch, retrying with backoff while the sink is unavailable
+func (l *Logger) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := l.sink.Write(ctx, batch)
+		cancel()
+		if err == nil {
+			l.written.Add(uint64(len(batch)))
+			return
+		}
+		if attempt >= l.cfg.MaxRetries {
+			l.dropped.Add(uint64(len(batch)))
+			slog.Error("auditlog: dropping events", "events", len(batch), "attempts", attempt+1, "error", err)
+			return
+		}
+		time.Sleep(backoff)
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxLimit caps the number of events returned by one query
+const MaxLimit = 10000
+
+// Filter narrows a query on audit events. Zero values match everything.
+type Filter struct {
+	ActorID   string
+	SubjectID string
+	Action    string
+	Outcome   string
+	From      time.Time
+	To        time.Time
+	Limit     int // Defaults to 100
+	Offset    int
+}
+
+// ParseFilter reads actor_id, subject_id, action, outcome, from, to (RFC 3339), limit and offset
+func ParseFilter(values url.Values) (Filter, error) {
+	filter := Filter{
+		ActorID:   values.Get("actor_id"),
+		SubjectID: values.Get("subject_id"),
+		Action:    values.Get("action"),
+		Outcome:   values.Get("outcome"),
+	}
+
+	var err error
+	if from := values.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, errors.New("from must be an RFC 3339 timestamp")
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, errors.New("to must be an RFC 3339 timestamp")
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 || filter.Limit > MaxLimit {
+			return filter, errors.New("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+		}
+	}
+	if offset := values.Get("offset"); offset != "" {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil || filter.Offset < 0 {
+			return filter, errors.New("offset must be a non-negative number")
+		}
+	}
+	return filter, nil
+}
+
+// Store reads and writes audit events with GORM. It is the Sink used by Logger.
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// appendOnlyStatements install the trigger that rejects UPDATE and DELETE on audit_events
+var appendOnlyStatements = []string{
+	`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+	"DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events",
+	`CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+}
+
+// InstallAppendOnly makes audit_events append-only with a trigger, like the SQL migrations do.
+// Call it after AutoMigrate created the table, AutoMigrate cannot create triggers. It is safe to
+// run on every start.
+func InstallAppendOnly(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range appendOnlyStatements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Write inserts the batch in a single statement
+func (s *Store) Write(ctx context.Context, events []Event) error {
+	return s.db.WithContext(ctx).Create(&events).Error
+}
+
+// Find returns matching events, newest first
+func (s *Store) Find(ctx context.Context, filter Filter) ([]Event, error) {
+	query := s.db.WithContext(ctx).Model(&Event{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.SubjectID != "" {
+		query = query.Where("subject_id = ?", filter.SubjectID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("occurred_at < ?", filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var events []Event
+	err := query.Order("occurred_at DESC").Limit(limit).Offset(filter.Offset).Find(&events).Error
+	return events, err
+}
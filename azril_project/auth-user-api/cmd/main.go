@@ -1,14 +1,26 @@
 package main
 
 import (
-    "fmt"
+    "context"
     "log"
+    "log/slog"
+    "time"
     "auth-user-api/controllers"
     "auth-user-api/repository"
     "auth-user-api/services"
     "auth-user-api/models"
     "auth-user-api/utils"
     "auth-user-api/middleware"  // Tambahkan ini
+    "auth-user-api/locales"
+    "auditlog"
+    "envelope"
+    "health"
+    "i18n"
+    "idempotency"
+    "jwtauth"
+    "logging"
+    "metrics"
+    "ratelimit"
 
     "github.com/labstack/echo/v4"
     echoMiddleware "github.com/labstack/echo/v4/middleware"
@@ -16,13 +28,50 @@ import (
     "gorm.io/gorm"
 )
 
+// tables adalah model yang dibuat AutoMigrate, /readyz juga memeriksa semua kolomnya sudah ada
+var tables = []interface{}{
+    &models.User{},
+    &models.Session{},
+    &models.OAuthClient{},
+    &models.OAuthAuthorizationCode{},
+    &models.OAuthRefreshToken{},
+    &models.OAuthConsent{},
+    &models.APIKey{},
+    &models.MagicLink{},
+    &models.UserStatusChange{},
+    &auditlog.Event{},
+    &idempotency.Record{},
+    &ratelimit.Bucket{},
+}
+
 func main() {
+    // Log JSON ke stdout, setiap baris dari satu request membawa request_id yang sama
+    if _, err := logging.Setup(utils.NewLogConfigFromEnv()); err != nil {
+        log.Fatalf("Failed to set up logging: %v", err)
+    }
+
     // Konfigurasi Database
     dsn := "host=localhost user=postgres password=arnoarno dbname=api-auth port=5432 sslmode=disable TimeZone=Asia/Jakarta"
-    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+        // SQL tercatat di level debug tanpa nilai parameternya, query lambat sebagai warning
+        Logger: logging.NewGORMLogger(utils.NewSlowQueryThresholdFromEnv()),
+    })
     if err != nil {
         log.Fatalf("Failed to connect to database: %v", err)
     }
+    // Durasi dan error query serta statistik pool koneksi untuk /metrics
+    if err := db.Use(metrics.GORMPlugin{DBName: "api-auth"}); err != nil {
+        log.Fatalf("Failed to register database metrics: %v", err)
+    }
+    // Pool koneksi ditutup paling akhir, setelah semua yang memakai database berhenti
+    defer closeDB(db)
+
+    // /healthz dan /readyz sudah menjawab selama inisialisasi, /readyz baru 200 setelah server.Serve
+    checker := health.New(0)
+    server, err := health.Start(utils.NewServerConfigFromEnv(), checker)
+    if err != nil {
+        log.Fatalf("Failed to start server: %v", err)
+    }
 
     // Jalankan Migrasi
     err = db.Exec("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\";").Error
@@ -30,42 +79,163 @@ func main() {
         log.Fatalf("Failed to create extension: %v", err)
     }
 
-    err = db.AutoMigrate(&models.User{})
+    if err := repository.DropUserUniqueConstraints(db); err != nil {
+        log.Fatalf("Failed to drop old user unique constraints: %v", err)
+    }
+
+    err = db.AutoMigrate(tables...)
     if err != nil {
         log.Fatalf("Failed to migrate database: %v", err)
     }
+    if err := repository.CreateUserUniqueIndexes(db); err != nil {
+        log.Fatalf("Failed to create user unique indexes: %v", err)
+    }
+    // Trigger dari migrations/009, AutoMigrate tidak membuat trigger
+    if err := auditlog.InstallAppendOnly(db); err != nil {
+        log.Fatalf("Failed to make audit log append-only: %v", err)
+    }
+
+    // Audit log ditulis lewat antrean di memori agar database yang lambat tidak menggagalkan request
+    auditStore := auditlog.NewStore(db)
+    auditLogger := auditlog.NewLogger(auditStore, auditlog.Config{})
+    defer func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        if err := auditLogger.Close(ctx); err != nil {
+            slog.Error("Failed to flush audit log", "error", err)
+        }
+    }()
 
     // Inisialisasi Repository, Service, dan Controller
     userRepo := repository.NewUserRepository(db)
-    userService := services.NewUserService(userRepo)
-    userController := controllers.NewUserController(userService)
+    sessionRepo := repository.NewSessionRepository(db)
+    oauthRepo := repository.NewOAuthRepository(db)
+    apiKeyRepo := repository.NewAPIKeyRepository(db)
+    magicLinkRepo := repository.NewMagicLinkRepository(db)
+    userCache := services.NewUserCache(10000, 30*time.Second)
+    // Statistik yang sama dengan GET /admin/stats/user-cache, diekspor sebagai cache_*{cache="user"}
+    if err := metrics.RegisterCache("user", cacheStats(userCache.Stats)); err != nil {
+        log.Fatalf("Failed to register user cache metrics: %v", err)
+    }
+    userService := services.NewUserService(userRepo, userCache, auditLogger)
+    // Status session dan client juga dicek di setiap request, bukan hanya data user
+    sessionCache := services.NewCache[models.Session](10000, 30*time.Second)
+    clientCache := services.NewCache[models.OAuthClient](1000, 5*time.Minute)
+    if err := metrics.RegisterCache("session", cacheStats(sessionCache.Stats)); err != nil {
+        log.Fatalf("Failed to register session cache metrics: %v", err)
+    }
+    if err := metrics.RegisterCache("client", cacheStats(clientCache.Stats)); err != nil {
+        log.Fatalf("Failed to register client cache metrics: %v", err)
+    }
+    sessionService := services.NewSessionService(sessionRepo, sessionCache)
+    jwtConfig := utils.NewJWTConfigFromEnv()
+    oidcConfig, err := utils.NewOIDCConfigFromEnv()
+    if err != nil {
+        log.Fatalf("Failed to load OIDC signing key: %v", err)
+    }
+    oauthService := services.NewOAuthService(oauthRepo, userService, sessionService, jwtConfig, utils.NewOAuthConfigFromEnv(), oidcConfig, auditLogger)
+    tokenService := services.NewTokenService(oauthRepo, userService, sessionService, clientCache, jwtConfig)
+    apiKeyService := services.NewAPIKeyService(apiKeyRepo, userService, auditLogger)
+    magicLinkConfig := utils.NewMagicLinkConfigFromEnv(jwtConfig)
+    magicLinkService := services.NewMagicLinkService(magicLinkRepo, userService, utils.NewMailerFromEnv(), magicLinkConfig, auditLogger)
+    // Link yang masih dikirim di background diselesaikan sebelum audit log dan database ditutup
+    defer magicLinkService.Wait()
+    // Mode cookie untuk frontend browser, aktif jika AUTH_COOKIE_MODE=true
+    cookieConfig := utils.NewCookieConfigFromEnv()
+    userController := controllers.NewUserController(userService, sessionService, magicLinkService, jwtConfig, cookieConfig)
+    sessionController := controllers.NewSessionController(sessionService, userService)
+    oauthController := controllers.NewOAuthController(oauthService, userService, tokenService)
+    oidcController := controllers.NewOIDCController(userService, oidcConfig)
+    apiKeyController := controllers.NewAPIKeyController(apiKeyService, userService)
+    auditController := controllers.NewAuditController(auditStore, auditLogger)
+
+    // Last-seen session ditulis ke database secara berkala, bukan di setiap request
+    stopFlusher := sessionService.StartLastSeenFlusher(time.Minute)
+    defer stopFlusher()
+
+    // User yang dihapus lebih lama dari USER_RETENTION di-purge otomatis, hanya jika diaktifkan
+    retention := utils.NewRetentionConfigFromEnv()
+    if retention.Period > 0 {
+        stopPurger := userService.StartRetentionPurger(retention.Interval, retention.Period)
+        defer stopPurger()
+    }
+
+    checker.Add("database", health.Ping(db))
+    checker.Add("migrations", health.Migrated(db, tables...))
+    checker.Add("keys", health.Loaded("signing keys", func() bool {
+        return len(jwtConfig.SigningKey) > 0 && oidcConfig.SigningKey != nil
+    }))
+
+    // Katalog pesan service ini, melengkapi katalog bawaan package i18n
+    i18n.MustLoad(locales.Files, ".")
 
     // Inisialisasi Echo
     e := echo.New()
+    // IP client untuk rate limit dan log, X-Forwarded-For hanya dibaca dari TRUSTED_PROXIES
+    e.IPExtractor = utils.NewIPExtractorFromEnv()
 
     // Middleware
-    e.Use(echoMiddleware.Logger())
+    e.Use(logging.Middleware()) // Paling awal agar middleware lain dan query database sudah membawa request ID
+    e.Use(metrics.Middleware()) // Sebelum Recover agar request yang panic tercatat sebagai 500
     e.Use(echoMiddleware.Recover())
+    e.Use(i18n.Middleware()) // Bahasa response dari header Accept-Language, default en
+    // Batas request per IP, rute yang butuh login juga dibatasi per user atau API key lewat jwtMiddleware
+    rateLimitConfig := utils.NewRateLimitConfigFromEnv(db)
+    // Resource server memeriksa token semua user-nya dari satu IP, jadi introspection dihitung per client
+    rateLimitConfig.Keys = map[string]ratelimit.KeyFunc{"POST /oauth/introspect": oauthController.IntrospectionClient}
+    limiter := ratelimit.New(rateLimitConfig)
+    e.Use(limiter.Middleware())
+    // Retry POST dengan Idempotency-Key yang sama mendapat response pertama, tidak diproses ulang
+    e.Use(idempotency.Middleware(utils.NewIdempotencyConfigFromEnv(db)))
 
     // Validator
     e.Validator = utils.NewValidator()
 
+    // Error dari echo sendiri (rute tidak ada, method salah, panic) memakai format response yang sama
+    e.HTTPErrorHandler = envelope.HTTPErrorHandler
+
+    jwtMiddleware := limiter.WithAuth(jwtauth.Middleware(jwtauth.Config{
+        Token:         jwtConfig,
+        Cookies:       cookieConfig,
+        Resolve:       middleware.NewPrincipalResolver(tokenService),
+        ResolveAPIKey: middleware.NewAPIKeyResolver(apiKeyService),
+        RenderError:   middleware.RenderAuthError,
+    }))
+
     // Routes
-    e.POST("/register", userController.RegisterUser)
-    e.POST("/login", userController.LoginUser)
-    e.GET("/users", userController.GetAllUsers)
-    e.PUT("/update/:id", userController.UpdateUser)
-    e.DELETE("/delete", userController.DeleteUser)
-    
-    jwtMiddleware := middleware.NewJWTMiddleware(userService)
-
-    // Rute dengan middleware JWT
-    e.GET("/protected/hello", userController.HelloProtected, jwtMiddleware.JWTMiddleware)
-
-    // Start Server
-    port := "8080"
-    fmt.Printf("Server running on port %s\n", port)
-    if err := e.Start(":" + port); err != nil {
-        log.Fatalf("Failed to start server: %v", err)
+    registerRoutes(e, routeDeps{
+        users:      userController,
+        sessions:   sessionController,
+        oauth:      oauthController,
+        oidc:       oidcController,
+        apiKeys:    apiKeyController,
+        audit:      auditController,
+        health:     checker,
+        auth:       jwtMiddleware,
+        magicLinks: magicLinkConfig.Enabled,
+    })
+
+    // Inisialisasi selesai, request diteruskan ke e sampai SIGINT atau SIGTERM
+    server.Serve(e)
+    if err := server.Wait(); err != nil {
+        slog.Error("Server stopped", "error", err)
+    }
+}
+
+func closeDB(db *gorm.DB) {
+    sqlDB, err := db.DB()
+    if err != nil {
+        return
+    }
+    if err := sqlDB.Close(); err != nil {
+        slog.Error("Failed to close database", "error", err)
+    }
+}
+
+// cacheStats mengubah statistik services.Cache ke format paket metrics
+func cacheStats(stats func() services.CacheStats) func() metrics.CacheStats {
+    return func() metrics.CacheStats {
+        s := stats()
+        return metrics.CacheStats{Hits: s.Hits, Misses: s.Misses, Evictions: s.Evictions, Size: s.Size}
     }
 }
@@ -0,0 +1,230 @@
+// cmd/oidc-client/main.go
+//
+// Client OIDC lokal untuk memverifikasi provider auth-user-api dari ujung ke ujung:
+// discovery, authorization code + PKCE, verifikasi id_token lewat JWKS, nonce, at_hash dan /userinfo.
+//
+//   go run ./cmd/oidc-client -client-id <id> -redirect http://127.0.0.1:9999/callback
+package main
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "math/big"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "auth-user-api/domains"
+    "auth-user-api/utils"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+func main() {
+    issuer := flag.String("issuer", "http://localhost:8080", "issuer URL of auth-user-api")
+    clientID := flag.String("client-id", "", "registered OAuth client ID")
+    clientSecret := flag.String("client-secret", "", "client secret, empty for public clients")
+    redirectURI := flag.String("redirect", "http://127.0.0.1:9999/callback", "registered redirect URI served by this tool")
+    scope := flag.String("scope", "openid profile email", "requested scopes")
+    flag.Parse()
+
+    if *clientID == "" {
+        log.Fatal("-client-id is required")
+    }
+
+    discovery := domains.DiscoveryResponse{}
+    must(getJSON(strings.TrimRight(*issuer, "/")+"/.well-known/openid-configuration", "", &discovery))
+    check(discovery.Issuer == *issuer, "discovery issuer matches %q", *issuer)
+
+    verifier := randomString()
+    challenge := sha256.Sum256([]byte(verifier))
+    state := randomString()
+    nonce := randomString()
+
+    params := url.Values{}
+    params.Set("response_type", "code")
+    params.Set("client_id", *clientID)
+    params.Set("redirect_uri", *redirectURI)
+    params.Set("scope", *scope)
+    params.Set("state", state)
+    params.Set("nonce", nonce)
+    params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+    params.Set("code_challenge_method", "S256")
+
+    fmt.Println("Open this URL in a browser and sign in:")
+    fmt.Println(discovery.AuthorizationEndpoint + "?" + params.Encode())
+
+    code := waitForCallback(*redirectURI, state)
+    check(true, "received authorization code with matching state")
+
+    form := url.Values{}
+    form.Set("grant_type", "authorization_code")
+    form.Set("code", code)
+    form.Set("redirect_uri", *redirectURI)
+    form.Set("code_verifier", verifier)
+    form.Set("client_id", *clientID)
+    if *clientSecret != "" {
+        form.Set("client_secret", *clientSecret)
+    }
+
+    tokens := domains.OAuthTokenResponse{}
+    must(postForm(discovery.TokenEndpoint, form, &tokens))
+    check(tokens.AccessToken != "", "token endpoint returned an access_token")
+    check(tokens.IDToken != "", "token endpoint returned an id_token")
+
+    jwks := struct {
+        Keys []utils.JWK `json:"keys"`
+    }{}
+    must(getJSON(discovery.JWKSURI, "", &jwks))
+
+    claims := &domains.IDTokenClaims{}
+    _, err := jwt.ParseWithClaims(tokens.IDToken, claims, func(token *jwt.Token) (interface{}, error) {
+        if token.Method != jwt.SigningMethodRS256 {
+            return nil, fmt.Errorf("unexpected alg %v", token.Header["alg"])
+        }
+        kid, _ := token.Header["kid"].(string)
+        for _, key := range jwks.Keys {
+            if key.Kid == kid {
+                return publicKey(key)
+            }
+        }
+        return nil, fmt.Errorf("no JWK with kid %q", kid)
+    })
+    must(err)
+    check(true, "id_token signature verified with JWKS")
+    check(claims.VerifyIssuer(discovery.Issuer, true), "id_token iss matches discovery issuer")
+    check(claims.VerifyAudience(*clientID, true), "id_token aud contains client_id")
+    check(claims.Nonce == nonce, "id_token nonce matches")
+    check(claims.AtHash == utils.AccessTokenHash(tokens.AccessToken), "id_token at_hash matches access_token")
+
+    userInfo := domains.UserInfoResponse{}
+    must(getJSON(discovery.UserInfoEndpoint, tokens.AccessToken, &userInfo))
+    check(userInfo.Subject == claims.Subject, "userinfo sub matches id_token sub")
+
+    fmt.Printf("\nAll checks passed for subject %s\n", claims.Subject)
+}
+
+// waitForCallback menjalankan server pada redirect URI sampai authorization code diterima
+func waitForCallback(redirectURI, state string) string {
+    target, err := url.Parse(redirectURI)
+    must(err)
+
+    result := make(chan string, 1)
+    failure := make(chan error, 1)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc(target.Path, func(w http.ResponseWriter, r *http.Request) {
+        query := r.URL.Query()
+        if errCode := query.Get("error"); errCode != "" {
+            failure <- fmt.Errorf("authorization failed: %s: %s", errCode, query.Get("error_description"))
+            fmt.Fprintln(w, "Authorization failed, check the terminal.")
+            return
+        }
+        if query.Get("state") != state {
+            failure <- errors.New("state mismatch")
+            fmt.Fprintln(w, "State mismatch, check the terminal.")
+            return
+        }
+        result <- query.Get("code")
+        fmt.Fprintln(w, "Done, you can close this window.")
+    })
+
+    server := &http.Server{Addr: target.Host, Handler: mux}
+    go func() {
+        if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            failure <- err
+        }
+    }()
+    defer func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+        defer cancel()
+        server.Shutdown(ctx)
+    }()
+
+    select {
+    case code := <-result:
+        return code
+    case err := <-failure:
+        log.Fatal(err)
+    case <-time.After(5 * time.Minute):
+        log.Fatal("timed out waiting for the authorization callback")
+    }
+    return ""
+}
+
+func getJSON(endpoint, bearer string, out interface{}) error {
+    req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+    if err != nil {
+        return err
+    }
+    if bearer != "" {
+        req.Header.Set("Authorization", "Bearer "+bearer)
+    }
+    return doJSON(req, out)
+}
+
+func postForm(endpoint string, form url.Values, out interface{}) error {
+    req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    return doJSON(req, out)
+}
+
+func doJSON(req *http.Request, out interface{}) error {
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        var oauthErr domains.OAuthErrorResponse
+        json.NewDecoder(resp.Body).Decode(&oauthErr)
+        return fmt.Errorf("%s %s: %d %s %s", req.Method, req.URL, resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func publicKey(key utils.JWK) (*rsa.PublicKey, error) {
+    n, err := base64.RawURLEncoding.DecodeString(key.N)
+    if err != nil {
+        return nil, err
+    }
+    e, err := base64.RawURLEncoding.DecodeString(key.E)
+    if err != nil {
+        return nil, err
+    }
+    return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func randomString() string {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        log.Fatal(err)
+    }
+    return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func check(ok bool, format string, args ...interface{}) {
+    if !ok {
+        log.Fatalf("FAIL: "+format, args...)
+    }
+    fmt.Printf("ok: "+format+"\n", args...)
+}
+
+func must(err error) {
+    if err != nil {
+        log.Fatal(err)
+    }
+}
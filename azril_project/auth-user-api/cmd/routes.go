@@ -0,0 +1,108 @@
+package main
+
+import (
+    "apidocs"
+    "auth-user-api/controllers"
+    "auth-user-api/docs"
+    "auth-user-api/middleware"
+    "auth-user-api/models"
+    "health"
+    "jwtauth"
+    "metrics"
+
+    "github.com/labstack/echo/v4"
+)
+
+// routeDeps berisi controller dan middleware yang dibutuhkan registerRoutes
+type routeDeps struct {
+    users      *controllers.UserController
+    sessions   *controllers.SessionController
+    oauth      *controllers.OAuthController
+    oidc       *controllers.OIDCController
+    apiKeys    *controllers.APIKeyController
+    audit      *controllers.AuditController
+    health     *health.Checker
+    auth       echo.MiddlewareFunc // Middleware JWT / API key
+    magicLinks bool                // Login passwordless lewat email, MAGIC_LINK_ENABLED=true
+}
+
+// registerRoutes mendaftarkan semua rute API beserta dokumentasinya di /openapi.json dan /docs.
+// Setiap rute baru juga harus ditambahkan ke docs/openapi.json, dicek oleh routes_test.go.
+func registerRoutes(e *echo.Echo, d routeDeps) {
+    // Token OAuth dan API key untuk rute admin juga harus punya scope admin, bukan hanya role admin
+    adminRole := jwtauth.RequireRole(models.RoleAdmin, middleware.RenderAuthError)
+    adminScope := jwtauth.RequireScope("admin", middleware.RenderAuthError)
+    adminOnly := func(next echo.HandlerFunc) echo.HandlerFunc {
+        return adminRole(adminScope(next))
+    }
+    profileScope := jwtauth.RequireScope("profile", middleware.RenderAuthError)
+    sessionsScope := jwtauth.RequireScope("sessions", middleware.RenderAuthError)
+    writeScope := jwtauth.RequireScope("users:write", middleware.RenderAuthError)
+
+    apidocs.Register(e, docs.OpenAPI)
+    d.health.Register(e)
+    metrics.Register(e)
+
+    e.POST("/register", d.users.RegisterUser)
+    e.POST("/login", d.users.LoginUser)
+    if d.magicLinks {
+        e.POST("/login/magic", d.users.RequestMagicLink)
+        e.GET("/login/magic/verify", d.users.MagicLinkPage)
+        e.POST("/login/magic/verify", d.users.VerifyMagicLink)
+    }
+    e.GET("/users", d.users.GetAllUsers)
+    e.PUT("/update/:id", d.users.UpdateUser)
+    e.DELETE("/delete", d.users.DeleteUser)
+
+    // Rute dengan middleware JWT
+    e.GET("/protected/hello", d.users.HelloProtected, d.auth)
+
+    // Rute profil milik user yang sedang login
+    e.GET("/me", d.users.GetMe, d.auth, profileScope)
+    e.PATCH("/me", d.users.UpdateMe, d.auth, writeScope)
+    e.DELETE("/me", d.users.DeleteMe, d.auth, writeScope)
+    e.POST("/me/logout", d.users.LogoutMe, d.auth, writeScope)
+    e.GET("/me/sessions", d.sessions.ListMySessions, d.auth, sessionsScope)
+    e.DELETE("/me/sessions/:id", d.sessions.RevokeMySession, d.auth, sessionsScope)
+    e.GET("/me/consents", d.oauth.ListMyConsents, d.auth, writeScope)
+    e.DELETE("/me/consents/:client_id", d.oauth.RevokeMyConsent, d.auth, writeScope)
+    e.POST("/me/api-keys", d.apiKeys.CreateMyAPIKey, d.auth)
+    e.GET("/me/api-keys", d.apiKeys.ListMyAPIKeys, d.auth, writeScope)
+    e.POST("/me/api-keys/:id/rotate", d.apiKeys.RotateMyAPIKey, d.auth)
+    e.DELETE("/me/api-keys/:id", d.apiKeys.RevokeMyAPIKey, d.auth, writeScope)
+
+    // Rute OAuth 2.0
+    e.GET("/oauth/authorize", d.oauth.AuthorizePage)
+    e.POST("/oauth/authorize", d.oauth.Authorize)
+    e.POST("/oauth/token", d.oauth.Token)
+    e.POST("/oauth/introspect", d.oauth.Introspect)
+    e.POST("/oauth/revoke", d.oauth.Revoke)
+
+    // Rute OpenID Connect
+    e.GET("/.well-known/openid-configuration", d.oidc.Discovery)
+    e.GET("/.well-known/jwks.json", d.oidc.JWKS)
+    e.GET("/userinfo", d.oidc.UserInfo, d.auth)
+    e.POST("/userinfo", d.oidc.UserInfo, d.auth)
+
+    // Rute khusus admin
+    e.GET("/users/:id", d.users.GetUser, d.auth, adminOnly)
+    e.PATCH("/users/:id", d.users.PatchUser, d.auth, adminOnly)
+    e.POST("/users/:id/logout", d.users.ForceLogout, d.auth, adminOnly)
+    e.GET("/admin/stats/user-cache", d.users.UserCacheStats, d.auth, adminOnly)
+    e.GET("/users/:id/sessions", d.sessions.ListUserSessions, d.auth, adminOnly)
+    e.DELETE("/users/:id/sessions/:session_id", d.sessions.RevokeUserSession, d.auth, adminOnly)
+    e.POST("/oauth/clients", d.oauth.RegisterClient, d.auth, adminOnly)
+    e.GET("/oauth/clients", d.oauth.ListClients, d.auth, adminOnly)
+    e.POST("/admin/service-accounts", d.apiKeys.CreateServiceAccount, d.auth, adminOnly)
+    e.POST("/users/:id/api-keys", d.apiKeys.CreateUserAPIKey, d.auth, adminOnly)
+    e.GET("/users/:id/api-keys", d.apiKeys.ListUserAPIKeys, d.auth, adminOnly)
+    e.POST("/users/:id/api-keys/:key_id/rotate", d.apiKeys.RotateUserAPIKey, d.auth, adminOnly)
+    e.DELETE("/users/:id/api-keys/:key_id", d.apiKeys.RevokeUserAPIKey, d.auth, adminOnly)
+    e.GET("/admin/audit-events", d.audit.ListEvents, d.auth, adminOnly)
+    e.GET("/admin/users/deleted", d.users.ListDeletedUsers, d.auth, adminOnly)
+    e.POST("/admin/users/:id/restore", d.users.RestoreUser, d.auth, adminOnly)
+    e.POST("/admin/users/:id/purge", d.users.PurgeUser, d.auth, adminOnly)
+    e.PUT("/admin/users/:id/status", d.users.ChangeUserStatus, d.auth, adminOnly)
+    e.GET("/admin/users/:id/status-history", d.users.UserStatusHistory, d.auth, adminOnly)
+    e.GET("/admin/stats/audit", d.audit.Stats, d.auth, adminOnly)
+}
@@ -0,0 +1,290 @@
+// controllers/api_key_controller.go
+
+package controllers
+
+import (
+    "net/http"
+    "strings"
+    "time"
+    "auth-user-api/services"
+    "auth-user-api/domains"
+    "auth-user-api/models"
+    "binding"
+    "envelope"
+    "i18n"
+    "github.com/labstack/echo/v4"
+)
+
+type APIKeyController struct {
+    service     services.APIKeyService
+    userService services.UserService
+}
+
+func NewAPIKeyController(service services.APIKeyService, userService services.UserService) *APIKeyController {
+    return &APIKeyController{service: service, userService: userService}
+}
+
+type createAPIKeyRequest struct {
+    Name          string   `json:"name" validate:"required,max=255"`
+    Scopes        []string `json:"scopes" validate:"required,min=1"`
+    ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"` // Kosong berarti tidak kedaluwarsa
+}
+
+type rotateAPIKeyRequest struct {
+    OverlapHours *int `json:"overlap_hours" validate:"omitempty,min=0,max=168"` // Default 24 jam
+}
+
+// Create My API Key godoc
+func (c *APIKeyController) CreateMyAPIKey(ctx echo.Context) error {
+    principal, ok := c.interactivePrincipal(ctx)
+    if !ok {
+        return nil
+    }
+    return c.createAPIKey(ctx, principal.ID)
+}
+
+// List My API Keys godoc
+func (c *APIKeyController) ListMyAPIKeys(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+    return c.listAPIKeys(ctx, principal.ID)
+}
+
+// Rotate My API Key godoc
+func (c *APIKeyController) RotateMyAPIKey(ctx echo.Context) error {
+    principal, ok := c.interactivePrincipal(ctx)
+    if !ok {
+        return nil
+    }
+    return c.rotateAPIKey(ctx, principal.ID, ctx.Param("id"))
+}
+
+// Revoke My API Key godoc
+func (c *APIKeyController) RevokeMyAPIKey(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+    return c.revokeAPIKey(ctx, principal.ID, ctx.Param("id"))
+}
+
+// Create Service Account godoc (admin)
+func (c *APIKeyController) CreateServiceAccount(ctx echo.Context) error {
+    type CreateServiceAccountRequest struct {
+        Username string `json:"username" validate:"required"`
+        Email    string `json:"email" validate:"required,email"`
+    }
+
+    var req CreateServiceAccountRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    user, err := c.userService.CreateServiceAccount(ctx.Request().Context(), req.Username, req.Email, auditActor(ctx))
+    if err != nil {
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+        response := envelope.Response{
+            Message: i18n.T(ctx, "service_account.create_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "service_account.created", user.ID),
+        Data:    newProfileResponse(user),
+    }
+    return envelope.JSON(ctx, http.StatusCreated, response)
+}
+
+// Create User API Key godoc (admin)
+func (c *APIKeyController) CreateUserAPIKey(ctx echo.Context) error {
+    if _, ok := c.interactivePrincipal(ctx); !ok {
+        return nil
+    }
+    return c.createAPIKey(ctx, ctx.Param("id"))
+}
+
+// List User API Keys godoc (admin)
+func (c *APIKeyController) ListUserAPIKeys(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    if _, err := c.userService.GetUserByID(ctx.Request().Context(), userID); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+    return c.listAPIKeys(ctx, userID)
+}
+
+// Rotate User API Key godoc (admin)
+func (c *APIKeyController) RotateUserAPIKey(ctx echo.Context) error {
+    if _, ok := c.interactivePrincipal(ctx); !ok {
+        return nil
+    }
+    return c.rotateAPIKey(ctx, ctx.Param("id"), ctx.Param("key_id"))
+}
+
+// Revoke User API Key godoc (admin)
+func (c *APIKeyController) RevokeUserAPIKey(ctx echo.Context) error {
+    return c.revokeAPIKey(ctx, ctx.Param("id"), ctx.Param("key_id"))
+}
+
+// interactivePrincipal menolak pembuatan key memakai API key atau token OAuth, agar key tidak bisa memperbanyak dirinya sendiri
+func (c *APIKeyController) interactivePrincipal(ctx echo.Context) (*domains.Principal, bool) {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        envelope.JSON(ctx, http.StatusUnauthorized, response)
+        return nil, false
+    }
+
+    if principal.APIKeyID != "" || principal.ClientID != "" {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "api_key.login_token_required"),
+            Errors:  []envelope.ErrorDetail{{Message: "ForbiddenError"}},
+        }
+        envelope.JSON(ctx, http.StatusForbidden, response)
+        return nil, false
+    }
+    return principal, true
+}
+
+func (c *APIKeyController) createAPIKey(ctx echo.Context, userID string) error {
+    var req createAPIKeyRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+    key, secret, err := c.service.Create(ctx.Request().Context(), userID, req.Name, req.Scopes, ttl, auditActor(ctx))
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "api_key.create_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    data := newAPIKeyResponse(key)
+    data.Key = secret
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "api_key.created"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusCreated, response)
+}
+
+func (c *APIKeyController) listAPIKeys(ctx echo.Context, userID string) error {
+    keys, err := c.service.List(ctx.Request().Context(), userID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "api_key.list_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.APIKeyResponse, 0, len(keys))
+    for _, key := range keys {
+        data = append(data, newAPIKeyResponse(key))
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "api_key.list"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+func (c *APIKeyController) rotateAPIKey(ctx echo.Context, userID, keyID string) error {
+    var req rotateAPIKeyRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    overlap := services.DefaultAPIKeyOverlap
+    if req.OverlapHours != nil {
+        overlap = time.Duration(*req.OverlapHours) * time.Hour
+    }
+
+    key, secret, err := c.service.Rotate(ctx.Request().Context(), userID, keyID, overlap, auditActor(ctx))
+    if err != nil {
+        if err == services.ErrAPIKeyNotFound {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "api_key.not_found_id", keyID),
+                Errors:  []envelope.ErrorDetail{{Message: "APIKeyNotFoundError", Parameter: "id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "api_key.rotate_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    data := newAPIKeyResponse(key)
+    data.Key = secret
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "api_key.rotated", overlap.String()),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusCreated, response)
+}
+
+func (c *APIKeyController) revokeAPIKey(ctx echo.Context, userID, keyID string) error {
+    if err := c.service.Revoke(ctx.Request().Context(), userID, keyID, auditActor(ctx)); err != nil {
+        if err == services.ErrAPIKeyNotFound {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "api_key.not_found_id", keyID),
+                Errors:  []envelope.ErrorDetail{{Message: "APIKeyNotFoundError", Parameter: "id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "api_key.revoke_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "api_key.revoked", keyID),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// newAPIKeyResponse menyusun data API key tanpa secret
+func newAPIKeyResponse(key *models.APIKey) domains.APIKeyResponse {
+    return domains.APIKeyResponse{
+        ID:            key.ID,
+        Name:          key.Name,
+        Prefix:        key.Prefix,
+        Scopes:        strings.Fields(key.Scopes),
+        ExpiresAt:     key.ExpiresAt,
+        LastUsedAt:    key.LastUsedAt,
+        RotatedFromID: key.RotatedFromID,
+        CreatedAt:     key.CreatedAt,
+    }
+}
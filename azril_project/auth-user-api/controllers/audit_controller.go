@@ -0,0 +1,84 @@
+// controllers/audit_controller.go
+
+package controllers
+
+import (
+    "net/http"
+    "time"
+    "auth-user-api/domains"
+    "auditlog"
+    "envelope"
+    "i18n"
+    "github.com/labstack/echo/v4"
+)
+
+type AuditController struct {
+    store  *auditlog.Store
+    logger *auditlog.Logger
+}
+
+func NewAuditController(store *auditlog.Store, logger *auditlog.Logger) *AuditController {
+    return &AuditController{store: store, logger: logger}
+}
+
+// List Audit Events godoc (admin)
+// Filter: actor_id, subject_id, action, outcome, from, to (RFC 3339), limit, offset. ?format=csv untuk export.
+func (c *AuditController) ListEvents(ctx echo.Context) error {
+    filter, err := auditlog.ParseFilter(ctx.QueryParams())
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "audit.invalid_filter", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.validation_detail", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    csvExport := ctx.QueryParam("format") == "csv"
+    if csvExport && ctx.QueryParam("limit") == "" {
+        filter.Limit = auditlog.MaxLimit
+    }
+
+    events, err := c.store.Find(ctx.Request().Context(), filter)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "audit.events_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    if csvExport {
+        filename := "audit-events-" + time.Now().UTC().Format("20060102-150405") + ".csv"
+        ctx.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+        ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+        ctx.Response().WriteHeader(http.StatusOK)
+        return auditlog.WriteCSV(ctx.Response(), events)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "audit.events"),
+        Data:    events,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Audit Stats godoc (admin)
+func (c *AuditController) Stats(ctx echo.Context) error {
+    response := envelope.Response{
+        Message: i18n.T(ctx, "audit.stats"),
+        Data:    c.logger.Stats(),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// auditActor mengambil user yang sedang login, IP dan User-Agent dari request untuk audit log
+func auditActor(ctx echo.Context) auditlog.Actor {
+    actor := auditlog.Actor{
+        IPAddress: ctx.RealIP(),
+        UserAgent: ctx.Request().UserAgent(),
+    }
+    if principal, ok := domains.PrincipalFromContext(ctx); ok {
+        actor.ID = principal.ID
+    }
+    return actor
+}
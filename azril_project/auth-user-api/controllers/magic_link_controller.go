@@ -0,0 +1,134 @@
+// controllers/magic_link_controller.go
+
+package controllers
+
+import (
+    "bytes"
+    "net/http"
+    "strconv"
+    "time"
+    "auth-user-api/services"
+    "auth-user-api/utils"
+    "binding"
+    "envelope"
+    "i18n"
+    "github.com/labstack/echo/v4"
+)
+
+// magicLinkNonceCookie mengikat link login ke browser yang memintanya
+const magicLinkNonceCookie = "magic_link_nonce"
+
+// Request Magic Link godoc
+func (c *UserController) RequestMagicLink(ctx echo.Context) error {
+    type MagicLinkRequest struct {
+        Email string `json:"email" validate:"required,email"`
+    }
+
+    var req MagicLinkRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    // Nonce yang sudah ada dipakai ulang agar beberapa link dari browser yang sama tetap berlaku
+    nonce := ""
+    if cookie, err := ctx.Cookie(magicLinkNonceCookie); err == nil && cookie.Value != "" {
+        nonce = cookie.Value
+    } else {
+        generated, err := utils.GenerateRandomToken()
+        if err != nil {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "error.internal"),
+                Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
+            }
+            return envelope.JSON(ctx, http.StatusInternalServerError, response)
+        }
+        nonce = generated
+    }
+
+    if err := c.magicLinks.Request(ctx.Request().Context(), req.Email, nonce, auditActor(ctx)); err != nil {
+        if err == services.ErrMagicLinkRateLimited {
+            ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(time.Hour.Seconds())))
+            response := envelope.Response{
+                Message: i18n.Err(ctx, err),
+                Errors:  []envelope.ErrorDetail{{Message: "RateLimitError", Parameter: "email"}},
+            }
+            return envelope.JSON(ctx, http.StatusTooManyRequests, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "error.internal"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    ctx.SetCookie(&http.Cookie{
+        Name:     magicLinkNonceCookie,
+        Value:    nonce,
+        Path:     "/login/magic",
+        HttpOnly: true,
+        Secure:   ctx.Scheme() == "https",
+        SameSite: http.SameSiteLaxMode, // Lax agar cookie ikut terkirim saat link dibuka dari email
+    })
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "magic_link.sent"),
+    }
+    return envelope.JSON(ctx, http.StatusAccepted, response)
+}
+
+// Magic Link Confirm Page godoc
+func (c *UserController) MagicLinkPage(ctx echo.Context) error {
+    // GET tidak memakai link, hanya menampilkan form konfirmasi yang mengirim POST
+    data := magicLinkPageData{Token: ctx.QueryParam("token")}
+    if ctx.QueryParam("mode") == "cookie" {
+        data.Mode = "cookie"
+    }
+
+    var buf bytes.Buffer
+    if err := magicLinkPage.Execute(&buf, data); err != nil {
+        return err
+    }
+    ctx.Response().Header().Set("X-Frame-Options", "DENY")
+    ctx.Response().Header().Set("Referrer-Policy", "no-referrer")
+    return ctx.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+// Verify Magic Link godoc
+func (c *UserController) VerifyMagicLink(ctx echo.Context) error {
+    nonce := ""
+    if cookie, err := ctx.Cookie(magicLinkNonceCookie); err == nil {
+        nonce = cookie.Value
+    }
+
+    user, err := c.magicLinks.Consume(ctx.Request().Context(), ctx.FormValue("token"), nonce, auditActor(ctx))
+    if err != nil {
+        if response, ok := accountStatusResponse(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusForbidden, response)
+        }
+        switch err {
+        case services.ErrMagicLinkInvalid, services.ErrMagicLinkUsed, services.ErrMagicLinkNonce:
+            response := envelope.Response{
+                Message: i18n.Err(ctx, err),
+                Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError", Parameter: "token"}},
+            }
+            return envelope.JSON(ctx, http.StatusUnauthorized, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "error.internal"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    ctx.SetCookie(&http.Cookie{
+        Name:     magicLinkNonceCookie,
+        Path:     "/login/magic",
+        MaxAge:   -1,
+        HttpOnly: true,
+        Secure:   ctx.Scheme() == "https",
+        SameSite: http.SameSiteLaxMode,
+    })
+    return c.completeLogin(ctx, user)
+}
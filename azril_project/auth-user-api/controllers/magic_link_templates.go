@@ -0,0 +1,32 @@
+// controllers/magic_link_templates.go
+
+package controllers
+
+import "html/template"
+
+// magicLinkPageData adalah data untuk halaman konfirmasi GET /login/magic/verify
+type magicLinkPageData struct {
+    Token string
+    Mode  string
+}
+
+// Link baru dipakai saat form ini dikirim, agar scanner email yang membuka link lebih dulu tidak menghanguskannya
+var magicLinkPage = template.Must(template.New("magic_link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>Log in</title>
+    <style>
+        body { font-family: sans-serif; max-width: 420px; margin: 48px auto; padding: 0 16px; }
+    </style>
+</head>
+<body>
+    <h1>Log in</h1>
+    <p>Continue to log in with the link from your email.</p>
+    <form method="post" action="/login/magic/verify{{if .Mode}}?mode={{.Mode}}{{end}}">
+        <input type="hidden" name="token" value="{{.Token}}">
+        <button type="submit">Log in</button>
+    </form>
+</body>
+</html>
+`))
@@ -0,0 +1,476 @@
+// controllers/oauth_controller.go
+
+package controllers
+
+import (
+    "bytes"
+    "errors"
+    "net/http"
+    "net/url"
+    "strings"
+    "auth-user-api/services"
+    "auth-user-api/domains"
+    "auth-user-api/models"
+    "binding"
+    "envelope"
+    "i18n"
+    "github.com/labstack/echo/v4"
+)
+
+type OAuthController struct {
+    service      services.OAuthService
+    userService  services.UserService
+    tokenService services.TokenService
+}
+
+func NewOAuthController(service services.OAuthService, userService services.UserService, tokenService services.TokenService) *OAuthController {
+    return &OAuthController{service: service, userService: userService, tokenService: tokenService}
+}
+
+// Authorize Page godoc (GET /oauth/authorize)
+func (c *OAuthController) AuthorizePage(ctx echo.Context) error {
+    req := authorizeRequestFrom(ctx)
+
+    client, redirectURI, err := c.service.ValidateClientRedirect(ctx.Request().Context(), req.ClientID, req.RedirectURI)
+    if err != nil {
+        return renderAuthorizePage(ctx, http.StatusBadRequest, authorizePageData{Error: oauthErrorDescription(err)})
+    }
+
+    scope, err := c.service.ValidateAuthorizeRequest(client, req)
+    if err != nil {
+        return redirectWithError(ctx, redirectURI, req.State, err)
+    }
+
+    return renderAuthorizePage(ctx, http.StatusOK, newAuthorizePageData(client, req, scope, "", ""))
+}
+
+// Authorize godoc (POST /oauth/authorize)
+func (c *OAuthController) Authorize(ctx echo.Context) error {
+    req := authorizeRequestFrom(ctx)
+
+    client, redirectURI, err := c.service.ValidateClientRedirect(ctx.Request().Context(), req.ClientID, req.RedirectURI)
+    if err != nil {
+        return renderAuthorizePage(ctx, http.StatusBadRequest, authorizePageData{Error: oauthErrorDescription(err)})
+    }
+
+    scope, err := c.service.ValidateAuthorizeRequest(client, req)
+    if err != nil {
+        return redirectWithError(ctx, redirectURI, req.State, err)
+    }
+
+    if ctx.FormValue("decision") != "approve" {
+        return redirectWithError(ctx, redirectURI, req.State, &services.OAuthError{
+            Code:        "access_denied",
+            Description: "the user denied the request",
+        })
+    }
+
+    // Login memakai alur yang sama dengan /login
+    username := ctx.FormValue("username")
+    user, err := c.userService.Authenticate(ctx.Request().Context(), username, ctx.FormValue("password"), auditActor(ctx))
+    if err != nil {
+        if services.IsAccountStatusError(err) {
+            page := newAuthorizePageData(client, req, scope, username, "Your account cannot sign in: "+err.Error())
+            return renderAuthorizePage(ctx, http.StatusForbidden, page)
+        }
+        page := newAuthorizePageData(client, req, scope, username, "Invalid username or password")
+        return renderAuthorizePage(ctx, http.StatusUnauthorized, page)
+    }
+
+    code, err := c.service.Authorize(ctx.Request().Context(), user.ID, client, req, scope)
+    if err != nil {
+        return redirectWithError(ctx, redirectURI, req.State, &services.OAuthError{
+            Code:        "server_error",
+            Description: "failed to issue authorization code",
+        })
+    }
+
+    params := url.Values{}
+    params.Set("code", code)
+    if req.State != "" {
+        params.Set("state", req.State)
+    }
+    return ctx.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
+
+// Token godoc (POST /oauth/token)
+func (c *OAuthController) Token(ctx echo.Context) error {
+    ctx.Response().Header().Set("Cache-Control", "no-store")
+    ctx.Response().Header().Set("Pragma", "no-cache")
+
+    clientID, clientSecret, usedBasic := clientCredentialsFrom(ctx)
+    client, err := c.service.AuthenticateClient(ctx.Request().Context(), clientID, clientSecret)
+    if err != nil {
+        if usedBasic {
+            ctx.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+        }
+        return oauthErrorJSON(ctx, err)
+    }
+
+    var response *domains.OAuthTokenResponse
+    switch ctx.FormValue("grant_type") {
+    case models.GrantAuthorizationCode:
+        response, err = c.service.ExchangeCode(ctx.Request().Context(),
+            client,
+            ctx.FormValue("code"),
+            ctx.FormValue("redirect_uri"),
+            ctx.FormValue("code_verifier"),
+            ctx.Request().UserAgent(),
+            ctx.RealIP(),
+        )
+    case models.GrantRefreshToken:
+        response, err = c.service.Refresh(ctx.Request().Context(), client, ctx.FormValue("refresh_token"), ctx.FormValue("scope"))
+    case models.GrantClientCredentials:
+        response, err = c.service.ClientCredentials(ctx.Request().Context(), client, ctx.FormValue("scope"))
+    case "":
+        err = &services.OAuthError{Code: "invalid_request", Description: "grant_type is required"}
+    default:
+        err = &services.OAuthError{Code: "unsupported_grant_type", Description: "unsupported grant_type"}
+    }
+    if err != nil {
+        return oauthErrorJSON(ctx, err)
+    }
+
+    return ctx.JSON(http.StatusOK, response)
+}
+
+// Introspect godoc (POST /oauth/introspect, RFC 7662)
+func (c *OAuthController) Introspect(ctx echo.Context) error {
+    ctx.Response().Header().Set("Cache-Control", "no-store")
+
+    client, err := c.resourceClient(ctx)
+    if err != nil {
+        return oauthErrorJSON(ctx, err)
+    }
+
+    response, err := c.tokenService.Introspect(ctx.Request().Context(), client, ctx.FormValue("token"), ctx.FormValue("token_type_hint"))
+    if err != nil {
+        return oauthErrorJSON(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc (POST /oauth/revoke, RFC 7009)
+func (c *OAuthController) Revoke(ctx echo.Context) error {
+    ctx.Response().Header().Set("Cache-Control", "no-store")
+
+    clientID, clientSecret, usedBasic := clientCredentialsFrom(ctx)
+    client, err := c.service.AuthenticateClient(ctx.Request().Context(), clientID, clientSecret)
+    if err != nil {
+        if usedBasic {
+            ctx.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+        }
+        return oauthErrorJSON(ctx, err)
+    }
+
+    if err := c.tokenService.Revoke(ctx.Request().Context(), client, ctx.FormValue("token"), ctx.FormValue("token_type_hint")); err != nil {
+        return oauthErrorJSON(ctx, err)
+    }
+    return ctx.NoContent(http.StatusOK)
+}
+
+// IntrospectionClient - ratelimit.KeyFunc untuk POST /oauth/introspect, request dihitung per client
+// yang terautentikasi. Request tanpa credential yang valid dihitung per IP.
+func (c *OAuthController) IntrospectionClient(ctx echo.Context) (string, string) {
+    client, err := c.resourceClient(ctx)
+    if err != nil {
+        return "", ""
+    }
+    return "client", client.ID
+}
+
+// resourceClientKey adalah key echo.Context untuk hasil resourceClient
+const resourceClientKey = "oauth.resource_client"
+
+// resourceClient - authenticateResourceClient yang hasilnya disimpan di context, agar rate limiter
+// dan handler tidak memeriksa credential dua kali
+func (c *OAuthController) resourceClient(ctx echo.Context) (*models.OAuthClient, error) {
+    type result struct {
+        client *models.OAuthClient
+        err    error
+    }
+    if cached, ok := ctx.Get(resourceClientKey).(result); ok {
+        return cached.client, cached.err
+    }
+    client, err := c.authenticateResourceClient(ctx)
+    ctx.Set(resourceClientKey, result{client: client, err: err})
+    return client, err
+}
+
+// authenticateResourceClient hanya menerima confidential client, public client tidak boleh melakukan introspection
+func (c *OAuthController) authenticateResourceClient(ctx echo.Context) (*models.OAuthClient, error) {
+    clientID, clientSecret, usedBasic := clientCredentialsFrom(ctx)
+    client, err := c.service.AuthenticateClient(ctx.Request().Context(), clientID, clientSecret)
+    if err == nil && client.Public {
+        err = &services.OAuthError{Code: "invalid_client", Description: "public clients may not introspect tokens"}
+    }
+    if err != nil && usedBasic {
+        ctx.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+    }
+    return client, err
+}
+
+// Register Client godoc (admin)
+func (c *OAuthController) RegisterClient(ctx echo.Context) error {
+    type RegisterClientRequest struct {
+        Name         string   `json:"name" validate:"required"`
+        RedirectURIs []string `json:"redirect_uris" validate:"dive,url"`
+        GrantTypes   []string `json:"grant_types" validate:"required,min=1"`
+        Scopes       []string `json:"scopes"`
+        Public       bool     `json:"public"`
+    }
+
+    var req RegisterClientRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    client, secret, err := c.service.RegisterClient(ctx.Request().Context(), req.Name, req.RedirectURIs, req.GrantTypes, req.Scopes, req.Public, auditActor(ctx))
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "oauth.client_register_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    clientResponse := newOAuthClientResponse(client)
+    clientResponse.ClientSecret = secret
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "oauth.client_registered"),
+        Data:    clientResponse,
+    }
+    return envelope.JSON(ctx, http.StatusCreated, response)
+}
+
+// List Clients godoc (admin)
+func (c *OAuthController) ListClients(ctx echo.Context) error {
+    clients, err := c.service.GetAllClients(ctx.Request().Context())
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "oauth.clients_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.OAuthClientResponse, 0, len(clients))
+    for _, client := range clients {
+        data = append(data, newOAuthClientResponse(client))
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "oauth.clients"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// List My Consents godoc
+func (c *OAuthController) ListMyConsents(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    consents, err := c.service.GetConsents(ctx.Request().Context(), principal.ID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "consent.list_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.ConsentResponse, 0, len(consents))
+    for _, consent := range consents {
+        clientName := ""
+        if client, err := c.service.GetClient(ctx.Request().Context(), consent.ClientID); err == nil {
+            clientName = client.Name
+        }
+        data = append(data, domains.ConsentResponse{
+            ClientID:   consent.ClientID,
+            ClientName: clientName,
+            Scopes:     strings.Fields(consent.Scope),
+            UpdatedAt:  consent.UpdatedAt,
+        })
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "consent.list"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Revoke My Consent godoc
+func (c *OAuthController) RevokeMyConsent(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    clientID := ctx.Param("client_id")
+    if err := c.service.RevokeConsent(ctx.Request().Context(), principal.ID, clientID); err != nil {
+        if errors.Is(err, services.ErrConsentNotFound) {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "consent.not_found_id", clientID),
+                Errors:  []envelope.ErrorDetail{{Message: "ConsentNotFoundError", Parameter: "client_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "consent.revoke_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "consent.revoked", clientID),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+func authorizeRequestFrom(ctx echo.Context) services.AuthorizeRequest {
+    return services.AuthorizeRequest{
+        ResponseType:        ctx.FormValue("response_type"),
+        ClientID:            ctx.FormValue("client_id"),
+        RedirectURI:         ctx.FormValue("redirect_uri"),
+        Scope:               ctx.FormValue("scope"),
+        State:               ctx.FormValue("state"),
+        CodeChallenge:       ctx.FormValue("code_challenge"),
+        CodeChallengeMethod: ctx.FormValue("code_challenge_method"),
+        Nonce:               ctx.FormValue("nonce"),
+    }
+}
+
+func newAuthorizePageData(client *models.OAuthClient, req services.AuthorizeRequest, scope, username, errMessage string) authorizePageData {
+    scopes := make([]authorizeScope, 0)
+    for _, name := range strings.Fields(scope) {
+        scopes = append(scopes, authorizeScope{Name: name, Description: services.SupportedScopes[name]})
+    }
+
+    return authorizePageData{
+        ClientName: client.Name,
+        Scopes:     scopes,
+        Error:      errMessage,
+        Username:   username,
+        Params: map[string]string{
+            "response_type":         req.ResponseType,
+            "client_id":             req.ClientID,
+            "redirect_uri":          req.RedirectURI,
+            "scope":                 scope,
+            "state":                 req.State,
+            "code_challenge":        req.CodeChallenge,
+            "code_challenge_method": req.CodeChallengeMethod,
+            "nonce":                 req.Nonce,
+        },
+    }
+}
+
+func renderAuthorizePage(ctx echo.Context, status int, data authorizePageData) error {
+    var buf bytes.Buffer
+    if err := authorizePage.Execute(&buf, data); err != nil {
+        return err
+    }
+    ctx.Response().Header().Set("X-Frame-Options", "DENY")
+    return ctx.HTMLBlob(status, buf.Bytes())
+}
+
+// redirectWithError mengirim error ke redirect_uri client (RFC 6749 section 4.1.2.1)
+func redirectWithError(ctx echo.Context, redirectURI, state string, err error) error {
+    params := url.Values{}
+    params.Set("error", oauthErrorCode(err))
+    params.Set("error_description", oauthErrorDescription(err))
+    if state != "" {
+        params.Set("state", state)
+    }
+    return ctx.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
+
+// oauthErrorJSON menulis error token endpoint (RFC 6749 section 5.2)
+func oauthErrorJSON(ctx echo.Context, err error) error {
+    code := oauthErrorCode(err)
+
+    status := http.StatusBadRequest
+    switch code {
+    case "invalid_client":
+        status = http.StatusUnauthorized
+    case "server_error":
+        status = http.StatusInternalServerError
+    }
+
+    return ctx.JSON(status, domains.OAuthErrorResponse{
+        Error:            code,
+        ErrorDescription: oauthErrorDescription(err),
+    })
+}
+
+func oauthErrorCode(err error) string {
+    var oauthErr *services.OAuthError
+    if errors.As(err, &oauthErr) {
+        return oauthErr.Code
+    }
+    return "server_error"
+}
+
+func oauthErrorDescription(err error) string {
+    var oauthErr *services.OAuthError
+    if errors.As(err, &oauthErr) {
+        return oauthErr.Description
+    }
+    return "internal server error"
+}
+
+// clientCredentialsFrom membaca kredensial client dari HTTP Basic atau dari form
+func clientCredentialsFrom(ctx echo.Context) (string, string, bool) {
+    if clientID, clientSecret, ok := ctx.Request().BasicAuth(); ok {
+        // RFC 6749 section 2.3.1: client_id dan secret di-encode form-urlencoded
+        if decoded, err := url.QueryUnescape(clientID); err == nil {
+            clientID = decoded
+        }
+        if decoded, err := url.QueryUnescape(clientSecret); err == nil {
+            clientSecret = decoded
+        }
+        return clientID, clientSecret, true
+    }
+    return ctx.FormValue("client_id"), ctx.FormValue("client_secret"), false
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return rawURL
+    }
+    query := u.Query()
+    for key, values := range params {
+        for _, value := range values {
+            query.Add(key, value)
+        }
+    }
+    u.RawQuery = query.Encode()
+    return u.String()
+}
+
+// newOAuthClientResponse menyusun data client dari model client
+func newOAuthClientResponse(client *models.OAuthClient) domains.OAuthClientResponse {
+    return domains.OAuthClientResponse{
+        ClientID:     client.ID,
+        Name:         client.Name,
+        RedirectURIs: strings.Fields(client.RedirectURIs),
+        GrantTypes:   strings.Fields(client.GrantTypes),
+        Scopes:       strings.Fields(client.Scopes),
+        Public:       client.Public,
+    }
+}
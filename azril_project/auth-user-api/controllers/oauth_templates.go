@@ -0,0 +1,57 @@
+// controllers/oauth_templates.go
+
+package controllers
+
+import "html/template"
+
+// authorizePageData adalah data untuk halaman login dan consent /oauth/authorize
+type authorizePageData struct {
+    ClientName string
+    Scopes     []authorizeScope
+    Error      string
+    Params     map[string]string // Parameter authorize yang dikirim ulang sebagai hidden input
+    Username   string
+}
+
+type authorizeScope struct {
+    Name        string
+    Description string
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>Sign in{{if .ClientName}} to {{.ClientName}}{{end}}</title>
+    <style>
+        body { font-family: sans-serif; max-width: 420px; margin: 48px auto; padding: 0 16px; }
+        .error { color: #b00020; }
+        label { display: block; margin-top: 12px; }
+        input[type=text], input[type=password] { width: 100%; padding: 8px; box-sizing: border-box; }
+        .actions { margin-top: 20px; display: flex; gap: 8px; }
+    </style>
+</head>
+<body>
+{{if .ClientName}}
+    <h1>{{.ClientName}}</h1>
+    <p>This application would like to:</p>
+    <ul>
+    {{range .Scopes}}<li><strong>{{.Name}}</strong>: {{.Description}}</li>{{else}}<li>Verify your identity</li>{{end}}
+    </ul>
+{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Params}}
+    <form method="post" action="/oauth/authorize">
+        {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+        {{end}}
+        <label>Username <input type="text" name="username" value="{{.Username}}" autocomplete="username" required></label>
+        <label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+        <div class="actions">
+            <button type="submit" name="decision" value="approve">Allow</button>
+            <button type="submit" name="decision" value="deny" formnovalidate>Deny</button>
+        </div>
+    </form>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,95 @@
+// controllers/oidc_controller.go
+
+package controllers
+
+import (
+    "net/http"
+    "sort"
+    "auth-user-api/services"
+    "auth-user-api/domains"
+    "auth-user-api/models"
+    "auth-user-api/utils"
+    "github.com/labstack/echo/v4"
+)
+
+type OIDCController struct {
+    userService services.UserService
+    config      utils.OIDCConfig
+}
+
+func NewOIDCController(userService services.UserService, config utils.OIDCConfig) *OIDCController {
+    return &OIDCController{userService: userService, config: config}
+}
+
+// Discovery godoc (GET /.well-known/openid-configuration)
+func (c *OIDCController) Discovery(ctx echo.Context) error {
+    scopes := make([]string, 0, len(services.SupportedScopes))
+    for scope := range services.SupportedScopes {
+        scopes = append(scopes, scope)
+    }
+    sort.Strings(scopes)
+
+    issuer := c.config.Issuer
+    return ctx.JSON(http.StatusOK, domains.DiscoveryResponse{
+        Issuer:                            issuer,
+        AuthorizationEndpoint:             issuer + "/oauth/authorize",
+        TokenEndpoint:                     issuer + "/oauth/token",
+        UserInfoEndpoint:                  issuer + "/userinfo",
+        JWKSURI:                           issuer + "/.well-known/jwks.json",
+        IntrospectionEndpoint:             issuer + "/oauth/introspect",
+        RevocationEndpoint:                issuer + "/oauth/revoke",
+        ScopesSupported:                   scopes,
+        ResponseTypesSupported:            []string{"code"},
+        GrantTypesSupported:               []string{models.GrantAuthorizationCode, models.GrantRefreshToken, models.GrantClientCredentials},
+        SubjectTypesSupported:             []string{"public"},
+        IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+        TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+        CodeChallengeMethodsSupported:     []string{"S256"},
+        ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "email", "email_verified", "preferred_username"},
+    })
+}
+
+// JWKS godoc (GET /.well-known/jwks.json)
+func (c *OIDCController) JWKS(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "keys": c.config.JWKS(),
+    })
+}
+
+// User Info godoc (GET/POST /userinfo)
+func (c *OIDCController) UserInfo(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok || principal.HasRole(models.RoleClient) {
+        ctx.Response().Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+        return ctx.JSON(http.StatusUnauthorized, domains.OAuthErrorResponse{
+            Error:            "invalid_token",
+            ErrorDescription: "a user access token is required",
+        })
+    }
+    if !principal.HasScope("openid") {
+        ctx.Response().Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+        return ctx.JSON(http.StatusForbidden, domains.OAuthErrorResponse{
+            Error:            "insufficient_scope",
+            ErrorDescription: "the openid scope is required",
+        })
+    }
+
+    user, err := c.userService.GetUserByID(ctx.Request().Context(), principal.ID)
+    if err != nil {
+        return ctx.JSON(http.StatusUnauthorized, domains.OAuthErrorResponse{
+            Error:            "invalid_token",
+            ErrorDescription: "user not found",
+        })
+    }
+
+    response := domains.UserInfoResponse{Subject: user.ID}
+    if principal.HasScope("email") {
+        emailVerified := user.EmailVerified
+        response.Email = user.Email
+        response.EmailVerified = &emailVerified
+    }
+    if principal.HasScope("profile") {
+        response.PreferredUsername = user.Username
+    }
+    return ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,130 @@
+// controllers/session_controller.go
+
+package controllers
+
+import (
+    "net/http"
+    "auth-user-api/services"
+    "auth-user-api/domains"
+    "auth-user-api/models"
+    "envelope"
+    "i18n"
+    "github.com/labstack/echo/v4"
+)
+
+type SessionController struct {
+    service     services.SessionService
+    userService services.UserService
+}
+
+func NewSessionController(service services.SessionService, userService services.UserService) *SessionController {
+    return &SessionController{service: service, userService: userService}
+}
+
+// List My Sessions godoc
+func (c *SessionController) ListMySessions(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    return c.listSessions(ctx, principal.ID, principal.SessionID)
+}
+
+// Revoke My Session godoc
+func (c *SessionController) RevokeMySession(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    return c.revokeSession(ctx, principal.ID, ctx.Param("id"))
+}
+
+// List User Sessions godoc (admin)
+func (c *SessionController) ListUserSessions(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    if _, err := c.userService.GetUserByID(ctx.Request().Context(), userID); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    currentSessionID := ""
+    if principal, ok := domains.PrincipalFromContext(ctx); ok {
+        currentSessionID = principal.SessionID
+    }
+    return c.listSessions(ctx, userID, currentSessionID)
+}
+
+// Revoke User Session godoc (admin)
+func (c *SessionController) RevokeUserSession(ctx echo.Context) error {
+    return c.revokeSession(ctx, ctx.Param("id"), ctx.Param("session_id"))
+}
+
+func (c *SessionController) listSessions(ctx echo.Context, userID, currentSessionID string) error {
+    sessions, err := c.service.ListActive(ctx.Request().Context(), userID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "session.list_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.SessionResponse, 0, len(sessions))
+    for _, session := range sessions {
+        data = append(data, newSessionResponse(session, currentSessionID))
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "session.list"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+func (c *SessionController) revokeSession(ctx echo.Context, userID, sessionID string) error {
+    if err := c.service.Revoke(ctx.Request().Context(), userID, sessionID); err != nil {
+        if err == services.ErrSessionNotFound {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "session.not_found_id", sessionID),
+                Errors:  []envelope.ErrorDetail{{Message: "SessionNotFoundError", Parameter: "session_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "session.revoke_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "session.revoked", sessionID),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// newSessionResponse menyusun data session dari model session
+func newSessionResponse(session *models.Session, currentSessionID string) domains.SessionResponse {
+    return domains.SessionResponse{
+        SessionID:   session.ID,
+        DeviceLabel: session.DeviceLabel,
+        IPAddress:   session.IPAddress,
+        CreatedAt:   session.CreatedAt,
+        LastSeenAt:  session.LastSeenAt,
+        Current:     session.ID == currentSessionID,
+    }
+}
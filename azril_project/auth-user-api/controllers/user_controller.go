@@ -3,20 +3,59 @@
 package controllers
 
 import (
+    "errors"
     "net/http"
-    "time"
-    "github.com/golang-jwt/jwt/v4"
     "auth-user-api/services"
     "auth-user-api/domains"
+    "auth-user-api/models"
+    "binding"
+    "envelope"
+    "i18n"
+    "github.com/golang-jwt/jwt/v4"
     "github.com/labstack/echo/v4"
+    "jwtauth"
+    "metrics"
+    "precondition"
 )
 
 type UserController struct {
-    service services.UserService
+    service        services.UserService
+    sessionService services.SessionService
+    magicLinks     services.MagicLinkService
+    jwtConfig      jwtauth.TokenConfig
+    cookies        *jwtauth.CookieConfig // nil jika mode cookie tidak aktif
+}
+
+// userConflict membuat response 409 jika err berarti username atau email sudah dipakai user aktif lain
+func userConflict(ctx echo.Context, err error) (envelope.Response, bool) {
+    var parameter string
+    switch {
+    case errors.Is(err, services.ErrUsernameTaken):
+        parameter = "username"
+    case errors.Is(err, services.ErrEmailTaken):
+        parameter = "email"
+    default:
+        return envelope.Response{}, false
+    }
+    return envelope.Response{
+        Message: i18n.T(ctx, "error.conflict", err),
+        Errors:  []envelope.ErrorDetail{{Message: "DuplicateError", Parameter: parameter}},
+    }, true
+}
+
+// accountStatusResponse membuat response 403 jika login ditolak karena status akun tidak aktif
+func accountStatusResponse(ctx echo.Context, err error) (envelope.Response, bool) {
+    if !services.IsAccountStatusError(err) {
+        return envelope.Response{}, false
+    }
+    return envelope.Response{
+        Message: i18n.T(ctx, "auth.login_rejected", err),
+        Errors:  []envelope.ErrorDetail{{Message: "AccountStatusError"}},
+    }, true
 }
 
-func NewUserController(service services.UserService) *UserController {
-    return &UserController{service}
+func NewUserController(service services.UserService, sessionService services.SessionService, magicLinks services.MagicLinkService, jwtConfig jwtauth.TokenConfig, cookies *jwtauth.CookieConfig) *UserController {
+    return &UserController{service: service, sessionService: sessionService, magicLinks: magicLinks, jwtConfig: jwtConfig, cookies: cookies}
 }
 
 // Register User godoc
@@ -29,103 +68,49 @@ func (c *UserController) RegisterUser(ctx echo.Context) error {
     }
 
     var req RegisterRequest
-    if err := ctx.Bind(&req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Failed processing input, try again. Error: " + err.Error(),
-            Error:   "Binding error: " + err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if req.Username == "" {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Username cannot be empty. Field: username",
-            Error:   "Validation error",
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if req.Email == "" {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Email cannot be empty. Field: email",
-            Error:   "Validation error",
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
     }
 
-    if req.Password1 == "" {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Password 1 cannot be empty. Field: password_1",
-            Error:   "Validation error",
+    if err := c.service.Register(ctx.Request().Context(), req.Username, req.Email, req.Password1, req.Password2, auditActor(ctx)); err != nil {
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
         }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if req.Password2 == "" {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Password 2 cannot be empty. Field: password_2",
-            Error:   "Validation error",
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if err := ctx.Validate(req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Validation error. Field: " + err.Error(),
-            Error:   "Validation error: " + err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if err := c.service.Register(req.Username, req.Email, req.Password1, req.Password2); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Registration failed. Error: " + err.Error(),
-            Error:   "Service error: " + err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.register_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
         }
-        return ctx.JSON(http.StatusBadRequest, response)
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
     }
 
     userResponse := domains.RegisterResponse{
-        Username:  req.Username,
-        Email:     req.Email,
-        Password1: req.Password1,
-        Password2: req.Password2,
+        Username: req.Username,
+        Email:    req.Email,
     }
 
-    response := domains.BaseResponse{
-        Code:      "200",
-        Message:   "User successfully registered",
-        Data:      userResponse,
-        Parameter: "username", 
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.registered"),
+        Data:    userResponse,
     }    
-    return ctx.JSON(http.StatusOK, response)
+    return envelope.JSON(ctx, http.StatusOK, response)
 }
 
 // Get All Users godoc
 func (c *UserController) GetAllUsers(ctx echo.Context) error {
-    users, err := c.service.GetAllUsers()
+    users, err := c.service.GetAllUsers(ctx.Request().Context())
     if err != nil {
-        response := domains.BaseResponse{
-            Code:    "500",
-            Message: "Failed to retrieve users. Error: " + err.Error(),
-            Error:   "Service error: " + err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.list_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
         }
-        return ctx.JSON(http.StatusInternalServerError, response)
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
     }
 
-    response := domains.BaseResponse{
-        Code:    "200",
-        Message: "Users retrieved successfully",
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.list"),
         Data:    users,
     }
-    return ctx.JSON(http.StatusOK, response)
+    return envelope.JSON(ctx, http.StatusOK, response)
 }
 
 // Update User godoc
@@ -139,58 +124,66 @@ func (c *UserController) UpdateUser(ctx echo.Context) error {
 
     userID := ctx.Param("id")
     if userID == "" {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "User ID is required. Field: id",
-            Error:   "Validation error",
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.id_required"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.validation"), Parameter: "id"}},
         }
-        return ctx.JSON(http.StatusBadRequest, response)
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
     }
 
-    existingUser, err := c.service.GetUserByID(userID)
+    existingUser, err := c.service.GetUserByID(ctx.Request().Context(), userID)
     if err != nil {
-        response := domains.BaseResponse{
-            Code:    "404",
-            Message: "User not found. UserID: " + userID,
-            Error:   "User retrieval error: " + err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
         }
-        return ctx.JSON(http.StatusNotFound, response)
+        return envelope.JSON(ctx, http.StatusNotFound, response)
     }
 
-    var req UpdateRequest
-    if err := ctx.Bind(&req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Failed processing input. Error: " + err.Error(),
-            Error:   "Binding error: " + err.Error(),
+    // If-Match opsional pada PUT agar client lama tetap jalan, tetapi jika dikirim harus cocok
+    version := 0
+    if ctx.Request().Header.Get(precondition.HeaderIfMatch) != "" {
+        if err := precondition.Check(ctx, existingUser.Version, false); err != nil {
+            return precondition.Write(ctx, err)
         }
-        return ctx.JSON(http.StatusBadRequest, response)
+        version = existingUser.Version
     }
 
-    err = c.service.Update(userID, req.Username, req.Email, req.Password1, req.Password2)
+    var req UpdateRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    err = c.service.Update(ctx.Request().Context(), userID, version, req.Username, req.Email, req.Password1, req.Password2, auditActor(ctx))
     if err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Failed to update user. Error: " + err.Error(),
-            Error:   "Service error: " + err.Error(),
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+        if errors.Is(err, services.ErrVersionConflict) {
+            return precondition.Write(ctx, err)
         }
-        return ctx.JSON(http.StatusBadRequest, response)
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.update_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    if updated, err := c.service.GetUserByID(ctx.Request().Context(), userID); err == nil {
+        precondition.SetETag(ctx, updated.Version)
     }
 
     userResponse := domains.UserResponse{
         UserID:   existingUser.ID,
         Username: req.Username,
         Email:    req.Email,
-        Password: existingUser.Password,
     }
 
-    response := domains.BaseResponse{
-        Code:      "200",
-        Message:   "User successfully updated. UserID: " + userID,
-        Data:      userResponse,
-        Parameter: "username", 
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.updated", userID),
+        Data:    userResponse,
     }    
-    return ctx.JSON(http.StatusOK, response)
+    return envelope.JSON(ctx, http.StatusOK, response)
 }
 
 // Delete User godoc
@@ -200,65 +193,39 @@ func (c *UserController) DeleteUser(ctx echo.Context) error {
     }
 
     var req DeleteRequest
-    if err := ctx.Bind(&req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Delete failed. Error: " + err.Error(),
-            Error:   "Binding error: " + err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
     }
 
-    if err := ctx.Validate(req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Delete failed. Validation error: " + err.Error(),
-            Error:   "Validation error: " + err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    user, err := c.service.GetUserByID(req.UserID)
+    user, err := c.service.GetUserByID(ctx.Request().Context(), req.UserID)
     if err != nil {
-        response := domains.BaseResponse{
-            Code:    "404",
-            Message: "User not found. UserID: " + req.UserID,
-            Error:   "User retrieval error: " + err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", req.UserID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
         }
-        return ctx.JSON(http.StatusNotFound, response)
+        return envelope.JSON(ctx, http.StatusNotFound, response)
     }
 
     if user.DeletedAt.Valid {
-        response := domains.BaseResponse{
-            Code:    "404",
-            Message: "User not found. UserID: " + req.UserID,
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", req.UserID),
         }
-        return ctx.JSON(http.StatusNotFound, response)
+        return envelope.JSON(ctx, http.StatusNotFound, response)
     }
 
-    if err := c.service.Delete(req.UserID); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Failed to delete user. UserID: " + req.UserID + ", Error: " + err.Error(),
-            Error:   "Service error: " + err.Error(),
+    if err := c.service.Delete(ctx.Request().Context(), req.UserID, auditActor(ctx)); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.delete_failed", req.UserID, err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
         }
-        return ctx.JSON(http.StatusBadRequest, response)
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
     }
 
-    response := domains.BaseResponse{
-        Code:      "200",
-        Message:   "User deleted successfully. UserID: " + req.UserID,
-        Data:      domains.DeleteResponse{UserID: req.UserID},
-        Parameter: "user_id", 
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.deleted", req.UserID),
+        Data:    domains.DeleteResponse{UserID: req.UserID},
     }    
-    return ctx.JSON(http.StatusOK, response)
-}
-
-var jwtKey = []byte("my_secret_key")  // Pastikan menggunakan secret key yang sama
-
-type JWTClaims struct {
-    Username string `json:"username"`
-    jwt.RegisteredClaims
+    return envelope.JSON(ctx, http.StatusOK, response)
 }
 
 // Login User
@@ -269,99 +236,598 @@ func (c *UserController) LoginUser(ctx echo.Context) error {
     }
 
     var req LoginRequest
-    if err := ctx.Bind(&req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Invalid input",
-            Error:  err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
-    }
-
-    if err := ctx.Validate(req); err != nil {
-        response := domains.BaseResponse{
-            Code:    "400",
-            Message: "Validation error",
-            Error:  err.Error(),
-        }
-        return ctx.JSON(http.StatusBadRequest, response)
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
     }
 
     // Authenticate the user
-    err := c.service.Authenticate(req.Username, req.Password)
+    user, err := c.service.Authenticate(ctx.Request().Context(), req.Username, req.Password, auditActor(ctx))
     if err != nil {
-        if err.Error() == "user not found" {
-            response := domains.BaseResponse{
-                Code:    "404",
-                Message: "User not found.",
-                Error:   "UserNotFoundError",
-            }
-            return ctx.JSON(http.StatusNotFound, response)
-        } else if err.Error() == "invalid username or password" {
-            response := domains.BaseResponse{
-                Code:    "401",
-                Message: "Invalid username or password",
-                Error:   "AuthenticationError",
+        if response, ok := accountStatusResponse(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusForbidden, response)
+        }
+        // Username yang tidak ada dan password yang salah mendapat response yang sama
+        if errors.Is(err, services.ErrInvalidCredentials) {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "auth.login_invalid"),
+                Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
             }
-            return ctx.JSON(http.StatusUnauthorized, response)
+            return envelope.JSON(ctx, http.StatusUnauthorized, response)
         }
     
-        response := domains.BaseResponse{
-            Code:    "500",
-            Message: "Internal server error",
-            Error:   err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "error.internal"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
         }
-        return ctx.JSON(http.StatusInternalServerError, response)
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
     }    
 
-    // Membuat token JWT dengan durasi 24 jam
-    expirationTime := time.Now().Add(24 * time.Hour)
-    claims := &JWTClaims{
-        Username: req.Username,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(expirationTime),
-        },
+    return c.completeLogin(ctx, user)
+}
+
+// completeLogin membuat session dan token untuk user yang sudah terautentikasi, dipakai login password dan magic link
+func (c *UserController) completeLogin(ctx echo.Context, user *models.User) error {
+    // ?mode=cookie dipakai frontend browser agar token tidak perlu disimpan di localStorage
+    cookieMode := ctx.QueryParam("mode") == "cookie"
+    if cookieMode && c.cookies == nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.cookie_mode_disabled"),
+            Errors:  []envelope.ErrorDetail{{Message: "InvalidModeError", Parameter: "mode"}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
     }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    tokenString, err := token.SignedString(jwtKey)
+    // Setiap login membuat session baru untuk device yang digunakan
+    session, err := c.sessionService.Create(ctx.Request().Context(), user.ID, ctx.Request().UserAgent(), ctx.RealIP())
     if err != nil {
-        response := domains.BaseResponse{
-            Code:    "500",
-            Message: "Failed to generate token",
-            Error:  err.Error(),
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.session_create_failed"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
         }
-        return ctx.JSON(http.StatusInternalServerError, response)
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
     }
 
-    response := domains.BaseResponse{
-        Code:    "200",
-        Message: "Successful login",
-        Data: map[string]interface{}{
-            "token": tokenString,
+    // Membuat token JWT dengan subject berupa ID user
+    tokenString, err := c.jwtConfig.Issue(&jwtauth.Claims{
+        Username:     user.Username,
+        Roles:        []string{user.Role},
+        TokenVersion: user.TokenVersion,
+        SessionID:    session.ID,
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject: user.ID,
         },
-        Error: "",
+    })
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.token_generate_failed"),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
     }
-    
-    // Panggil helper function untuk memformat error jika kosong
-    response.FormatError()
-    
-    return ctx.JSON(http.StatusOK, response)
+    metrics.TokensIssued.WithLabelValues("access").Inc()
+
+    data := map[string]interface{}{
+        "token": tokenString,
+    }
+    if cookieMode {
+        // Token hanya dikirim sebagai cookie HttpOnly, body berisi CSRF token untuk header X-CSRF-Token
+        csrfToken, err := c.cookies.SetSession(ctx, tokenString, c.jwtConfig.TTL)
+        if err != nil {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "auth.cookie_set_failed"),
+                Errors:  []envelope.ErrorDetail{{Message: i18n.Err(ctx, err)}},
+            }
+            return envelope.JSON(ctx, http.StatusInternalServerError, response)
+        }
+        data = map[string]interface{}{
+            "csrf_token": csrfToken,
+        }
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "auth.login_success"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
 }
 
 // Route yang diproteksi
 func (c *UserController) HelloProtected(ctx echo.Context) error {
-    username := ctx.Get("username")
-    if username == nil {
-        response := map[string]string{
-            "Message": "Unauthorized access. Missing or invalid token.",
+    if _, ok := domains.PrincipalFromContext(ctx); !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "auth.hello"),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Get Me godoc
+func (c *UserController) GetMe(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    user, err := c.service.GetUserByID(ctx.Request().Context(), principal.ID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", principal.ID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "profile.retrieved"),
+        Data:    newProfileResponse(user),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Update Me godoc
+func (c *UserController) UpdateMe(ctx echo.Context) error {
+    type UpdateMeRequest struct {
+        Username  string `json:"username"`
+        Email     string `json:"email" validate:"omitempty,email"`
+        Password1 string `json:"password_1"`
+        Password2 string `json:"password_2"`
+        // Wajib diisi jika password_1 atau password_2 diisi
+        CurrentPassword string `json:"current_password"`
+    }
+
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    var req UpdateMeRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    // Token yang dicuri tidak boleh cukup untuk mengganti password
+    if req.Password1 != "" || req.Password2 != "" {
+        if req.CurrentPassword == "" {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "password.current_required"),
+                Errors:  []envelope.ErrorDetail{{Message: "ValidationError", Parameter: "current_password"}},
+            }
+            return envelope.JSON(ctx, http.StatusBadRequest, response)
+        }
+        if err := c.service.VerifyPassword(ctx.Request().Context(), principal.ID, req.CurrentPassword); err != nil {
+            status := http.StatusInternalServerError
+            if errors.Is(err, services.ErrCurrentPasswordInvalid) {
+                status = http.StatusForbidden
+            }
+            response := envelope.Response{
+                Message: i18n.T(ctx, "profile.update_failed", err),
+                Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err), Parameter: "current_password"}},
+            }
+            return envelope.JSON(ctx, status, response)
+        }
+    }
+
+    // Field yang kosong tidak diubah
+    if err := c.service.Update(ctx.Request().Context(), principal.ID, 0, req.Username, req.Email, req.Password1, req.Password2, auditActor(ctx)); err != nil {
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+        if errors.Is(err, services.ErrVersionConflict) {
+            return precondition.Write(ctx, err)
+        }
+        response := envelope.Response{
+            Message: i18n.T(ctx, "profile.update_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    user, err := c.service.GetUserByID(ctx.Request().Context(), principal.ID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", principal.ID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "profile.updated"),
+        Data:    newProfileResponse(user),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Delete Me godoc
+func (c *UserController) DeleteMe(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
         }
-        return ctx.JSON(http.StatusUnauthorized, response)
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
     }
 
-    response := map[string]string{
-        "Message": "Hello, you have accessed a protected route!",
+    if err := c.service.Delete(ctx.Request().Context(), principal.ID, auditActor(ctx)); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "account.delete_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "account.deleted"),
+        Data:    domains.DeleteResponse{UserID: principal.ID},
     }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
 
-    return ctx.JSON(http.StatusOK, response)
+// Logout Me godoc
+func (c *UserController) LogoutMe(ctx echo.Context) error {
+    principal, ok := domains.PrincipalFromContext(ctx)
+    if !ok {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.unauthorized"),
+            Errors:  []envelope.ErrorDetail{{Message: "AuthenticationError"}},
+        }
+        return envelope.JSON(ctx, http.StatusUnauthorized, response)
+    }
+
+    if err := c.service.RevokeTokens(ctx.Request().Context(), principal.ID, auditActor(ctx)); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "auth.logout_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    if err := c.sessionService.RevokeAll(ctx.Request().Context(), principal.ID); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "session.revoke_all_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    if c.cookies != nil {
+        c.cookies.ClearSession(ctx)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "auth.logged_out_all"),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Force Logout godoc
+func (c *UserController) ForceLogout(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    if _, err := c.service.GetUserByID(ctx.Request().Context(), userID); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    if err := c.service.RevokeTokens(ctx.Request().Context(), userID, auditActor(ctx)); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.logout_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    if err := c.sessionService.RevokeAll(ctx.Request().Context(), userID); err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "session.revoke_all_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.logged_out_all", userID),
+        Data:    domains.DeleteResponse{UserID: userID},
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// List Deleted Users godoc (admin)
+func (c *UserController) ListDeletedUsers(ctx echo.Context) error {
+    users, err := c.service.ListDeleted(ctx.Request().Context())
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "admin.deleted_users_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.DeletedUserResponse, 0, len(users))
+    for _, user := range users {
+        data = append(data, domains.DeletedUserResponse{
+            UserID:    user.ID,
+            Username:  user.Username,
+            Email:     user.Email,
+            Role:      user.Role,
+            CreatedAt: user.CreatedAt,
+            DeletedAt: user.DeletedAt.Time,
+        })
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "admin.deleted_users"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Restore User godoc (admin)
+func (c *UserController) RestoreUser(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    if err := c.service.Restore(ctx.Request().Context(), userID, auditActor(ctx)); err != nil {
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+        if err == services.ErrUserNotDeleted {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "admin.no_restorable_user", userID),
+                Errors:  []envelope.ErrorDetail{{Message: "UserNotDeletedError", Parameter: "user_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "admin.restore_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "admin.restored", userID),
+        Data:    domains.DeleteResponse{UserID: userID},
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Purge User godoc (admin)
+func (c *UserController) PurgeUser(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    if err := c.service.Purge(ctx.Request().Context(), userID, auditActor(ctx)); err != nil {
+        if err == services.ErrUserNotDeleted {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "admin.purge_not_deleted", userID),
+                Errors:  []envelope.ErrorDetail{{Message: "UserNotDeletedError", Parameter: "user_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "admin.purge_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "admin.purged", userID),
+        Data:    domains.DeleteResponse{UserID: userID},
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Change User Status godoc (admin)
+func (c *UserController) ChangeUserStatus(ctx echo.Context) error {
+    type ChangeStatusRequest struct {
+        Status string `json:"status" validate:"required,oneof=pending active suspended locked"`
+        Reason string `json:"reason" validate:"required"`
+    }
+
+    var req ChangeStatusRequest
+    if err := binding.Bind(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    userID := ctx.Param("id")
+    change, err := c.service.ChangeStatus(ctx.Request().Context(), userID, req.Status, req.Reason, auditActor(ctx))
+    if err != nil {
+        switch err {
+        case services.ErrUserNotFound:
+            response := envelope.Response{
+                Message: i18n.T(ctx, "user.not_found_id", userID),
+                Errors:  []envelope.ErrorDetail{{Message: "UserNotFoundError", Parameter: "user_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        case services.ErrInvalidStatus:
+            response := envelope.Response{
+                Message: i18n.Err(ctx, err),
+                Errors:  []envelope.ErrorDetail{{Message: "ValidationError", Parameter: "status"}},
+            }
+            return envelope.JSON(ctx, http.StatusBadRequest, response)
+        case services.ErrStatusReasonRequired:
+            response := envelope.Response{
+                Message: i18n.Err(ctx, err),
+                Errors:  []envelope.ErrorDetail{{Message: "ValidationError", Parameter: "reason"}},
+            }
+            return envelope.JSON(ctx, http.StatusBadRequest, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "admin.status_change_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "admin.status_changed", change.ToStatus, userID),
+        Data:    newUserStatusChangeResponse(change),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Get User godoc (admin)
+// Versi user dikirim di header ETag, dipakai sebagai If-Match pada PatchUser
+func (c *UserController) GetUser(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    user, err := c.service.GetUserByID(ctx.Request().Context(), userID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: "UserNotFoundError", Parameter: "id"}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    precondition.SetETag(ctx, user.Version)
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.retrieved", userID),
+        Data:    newProfileResponse(user),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// Patch User godoc (admin)
+// Body berupa JSON merge patch, If-Match wajib berisi ETag dari GetUser. Jika user sudah diubah
+// request lain sejak itu response-nya 412 dan tidak ada yang disimpan.
+func (c *UserController) PatchUser(ctx echo.Context) error {
+    type UserPatch struct {
+        Username string `json:"username" validate:"required"`
+        Email    string `json:"email" validate:"required,email"`
+        Password string `json:"password,omitempty"` // Tidak pernah dikirim balik, hanya diubah jika ada di patch
+    }
+
+    userID := ctx.Param("id")
+    user, err := c.service.GetUserByID(ctx.Request().Context(), userID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: "UserNotFoundError", Parameter: "id"}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+    if err := precondition.Check(ctx, user.Version, true); err != nil {
+        return precondition.Write(ctx, err)
+    }
+
+    req := UserPatch{Username: user.Username, Email: user.Email}
+    if err := binding.BindMergePatch(ctx, &req); err != nil {
+        return binding.Write(ctx, err)
+    }
+
+    err = c.service.Update(ctx.Request().Context(), userID, user.Version, req.Username, req.Email, req.Password, req.Password, auditActor(ctx))
+    if err != nil {
+        if response, ok := userConflict(ctx, err); ok {
+            return envelope.JSON(ctx, http.StatusConflict, response)
+        }
+        if errors.Is(err, services.ErrVersionConflict) {
+            return precondition.Write(ctx, err)
+        }
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.update_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusBadRequest, response)
+    }
+
+    updated, err := c.service.GetUserByID(ctx.Request().Context(), userID)
+    if err != nil {
+        response := envelope.Response{
+            Message: i18n.T(ctx, "user.not_found_id", userID),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "user.retrieval_error", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusNotFound, response)
+    }
+
+    precondition.SetETag(ctx, updated.Version)
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.updated", userID),
+        Data:    newProfileResponse(updated),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// User Status History godoc (admin)
+func (c *UserController) UserStatusHistory(ctx echo.Context) error {
+    userID := ctx.Param("id")
+    changes, err := c.service.StatusHistory(ctx.Request().Context(), userID)
+    if err != nil {
+        if err == services.ErrUserNotFound {
+            response := envelope.Response{
+                Message: i18n.T(ctx, "user.not_found_id", userID),
+                Errors:  []envelope.ErrorDetail{{Message: "UserNotFoundError", Parameter: "user_id"}},
+            }
+            return envelope.JSON(ctx, http.StatusNotFound, response)
+        }
+
+        response := envelope.Response{
+            Message: i18n.T(ctx, "admin.status_history_failed", err),
+            Errors:  []envelope.ErrorDetail{{Message: i18n.T(ctx, "error.service", err)}},
+        }
+        return envelope.JSON(ctx, http.StatusInternalServerError, response)
+    }
+
+    data := make([]domains.UserStatusChangeResponse, 0, len(changes))
+    for _, change := range changes {
+        data = append(data, newUserStatusChangeResponse(change))
+    }
+
+    response := envelope.Response{
+        Message: i18n.T(ctx, "admin.status_history"),
+        Data:    data,
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// User Cache Stats godoc (admin)
+func (c *UserController) UserCacheStats(ctx echo.Context) error {
+    response := envelope.Response{
+        Message: i18n.T(ctx, "user.cache_stats"),
+        Data:    c.service.CacheStats(),
+    }
+    return envelope.JSON(ctx, http.StatusOK, response)
+}
+
+// newProfileResponse menyusun data profil dari model user
+func newProfileResponse(user *models.User) domains.ProfileResponse {
+    return domains.ProfileResponse{
+        UserID:    user.ID,
+        Username:  user.Username,
+        Email:     user.Email,
+        Role:      user.Role,
+        Status:    user.Status,
+        CreatedAt: user.CreatedAt,
+        UpdatedAt: user.UpdatedAt,
+    }
+}
+
+// newUserStatusChangeResponse menyusun satu entri riwayat status dari model
+func newUserStatusChangeResponse(change *models.UserStatusChange) domains.UserStatusChangeResponse {
+    return domains.UserStatusChangeResponse{
+        ChangeID:   change.ID,
+        UserID:     change.UserID,
+        FromStatus: change.FromStatus,
+        ToStatus:   change.ToStatus,
+        Reason:     change.Reason,
+        ChangedBy:  change.ChangedBy,
+        ChangedAt:  change.CreatedAt,
+    }
 }
@@ -0,0 +1,10 @@
+// docs/docs.go
+
+package docs
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of this service, served at /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
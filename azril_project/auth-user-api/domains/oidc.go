@@ -0,0 +1,42 @@
+// domains/oidc.go
+package domains
+
+import "github.com/golang-jwt/jwt/v4"
+
+// IDTokenClaims is the payload of OpenID Connect id_tokens
+type IDTokenClaims struct {
+    Nonce             string `json:"nonce,omitempty"`              // Echoed from the authorization request
+    AuthTime          int64  `json:"auth_time,omitempty"`          // Time the user authenticated
+    AtHash            string `json:"at_hash,omitempty"`            // Hash of the access token issued alongside
+    Email             string `json:"email,omitempty"`              // Present with the email scope
+    EmailVerified     *bool  `json:"email_verified,omitempty"`     // Present with the email scope
+    PreferredUsername string `json:"preferred_username,omitempty"` // Present with the profile scope
+    jwt.RegisteredClaims
+}
+
+// UserInfoResponse is the /userinfo response (OIDC Core section 5.3)
+type UserInfoResponse struct {
+    Subject           string `json:"sub"`                          // Unique user ID
+    Email             string `json:"email,omitempty"`              // Present with the email scope
+    EmailVerified     *bool  `json:"email_verified,omitempty"`     // Present with the email scope
+    PreferredUsername string `json:"preferred_username,omitempty"` // Present with the profile scope
+}
+
+// DiscoveryResponse is the /.well-known/openid-configuration document
+type DiscoveryResponse struct {
+    Issuer                            string   `json:"issuer"`
+    AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+    TokenEndpoint                     string   `json:"token_endpoint"`
+    UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+    JWKSURI                           string   `json:"jwks_uri"`
+    IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+    RevocationEndpoint                string   `json:"revocation_endpoint"`
+    ScopesSupported                   []string `json:"scopes_supported"`
+    ResponseTypesSupported            []string `json:"response_types_supported"`
+    GrantTypesSupported               []string `json:"grant_types_supported"`
+    SubjectTypesSupported             []string `json:"subject_types_supported"`
+    IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+    TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+    CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+    ClaimsSupported                   []string `json:"claims_supported"`
+}
@@ -0,0 +1,15 @@
+// domains/principal.go
+package domains
+
+import (
+    "github.com/labstack/echo/v4"
+    "jwtauth"
+)
+
+// Principal represents the authenticated user behind a request
+type Principal = jwtauth.Principal
+
+// PrincipalFromContext returns the principal set by the JWT middleware, if any
+func PrincipalFromContext(ctx echo.Context) (*Principal, bool) {
+    return jwtauth.PrincipalFromContext(ctx)
+}
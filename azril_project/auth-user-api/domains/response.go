@@ -1,21 +1,7 @@
 // domains/response.go
 package domains
 
-// BaseResponse is the general structure for all API responses
-type BaseResponse struct {
-    Code      string      `json:"code"`                 // HTTP response code
-    Message   string      `json:"message"`              // Response message
-    Data      interface{} `json:"data,omitempty"`       // Data payload (optional)
-    Error     string      `json:"error,omitempty"`      // Error details (optional)
-    Parameter string      `json:"parameter,omitempty"`  // Related parameter (optional)
-}
-
-// FormatError sets Error to "null" if it's an empty string
-func (r *BaseResponse) FormatError() {
-    if r.Error == "" {
-        r.Error = "null"
-    }
-}
+import "time"
 
 // TokenResponse represents a response with a token
 type TokenResponse struct {
@@ -27,7 +13,27 @@ type UserResponse struct {
     UserID   string `json:"user_id"`      // Unique user ID
     Username string `json:"username"`     // User's username
     Email    string `json:"email"`        // User's email
-    Password string `json:"-"`            // Password is omitted in the response
+}
+
+// ProfileResponse represents the authenticated user's own profile
+type ProfileResponse struct {
+    UserID    string    `json:"user_id"`    // Unique user ID
+    Username  string    `json:"username"`   // User's username
+    Email     string    `json:"email"`      // User's email
+    Role      string    `json:"role"`       // User's role
+    Status    string    `json:"status"`     // Account status: pending, active, suspended or locked
+    CreatedAt time.Time `json:"created_at"` // Time the user registered
+    UpdatedAt time.Time `json:"updated_at"` // Time the user was last updated
+}
+
+// SessionResponse represents an active login session of a user
+type SessionResponse struct {
+    SessionID   string    `json:"session_id"`   // Unique session ID
+    DeviceLabel string    `json:"device_label"` // Device derived from the User-Agent
+    IPAddress   string    `json:"ip_address"`   // IP address used at login
+    CreatedAt   time.Time `json:"created_at"`   // Time of login
+    LastSeenAt  time.Time `json:"last_seen_at"` // Last time the session was used
+    Current     bool      `json:"current"`      // Whether this is the session of the calling token
 }
 
 // DeleteResponse represents the response after a user is deleted
@@ -37,16 +43,75 @@ type DeleteResponse struct {
 
 // RegisterResponse represents the response after user registration
 type RegisterResponse struct {
-    Username  string `json:"username"`   // Registered username
-    Email     string `json:"email"`      // Registered email
-    Password1 string `json:"-"`          // Password is hidden in the response
-    Password2 string `json:"-"`          // Password is hidden in the response
-}
-
-// ErrorResponse is used to format error messages with extra details
-type ErrorResponse struct {
-    Code      string            `json:"code"`                 // HTTP response code
-    Message   string            `json:"message"`              // Error message
-    Errors    map[string]string `json:"errors,omitempty"`     // Map of field errors (optional)
-    Parameter string            `json:"parameter,omitempty"`  // Related parameter (optional)
+    Username string `json:"username"`   // Registered username
+    Email    string `json:"email"`      // Registered email
+}
+
+// OAuthTokenResponse is the successful /oauth/token response (RFC 6749 section 5.1)
+type OAuthTokenResponse struct {
+    AccessToken  string `json:"access_token"`            // Signed JWT access token
+    TokenType    string `json:"token_type"`              // Always "Bearer"
+    ExpiresIn    int    `json:"expires_in"`              // Access token lifetime in seconds
+    RefreshToken string `json:"refresh_token,omitempty"` // Present when the client may refresh
+    Scope        string `json:"scope,omitempty"`         // Granted scopes, space separated
+    IDToken      string `json:"id_token,omitempty"`      // Present when the openid scope was granted
+}
+
+// OAuthErrorResponse is the error format of the OAuth endpoints (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+    Error            string `json:"error"`                       // OAuth error code
+    ErrorDescription string `json:"error_description,omitempty"` // Human readable description
+}
+
+// OAuthClientResponse represents a registered OAuth client
+type OAuthClientResponse struct {
+    ClientID     string   `json:"client_id"`               // Unique client ID
+    ClientSecret string   `json:"client_secret,omitempty"` // Only returned once at registration
+    Name         string   `json:"name"`                    // Client display name
+    RedirectURIs []string `json:"redirect_uris"`           // Allowed redirect URIs
+    GrantTypes   []string `json:"grant_types"`             // Allowed grant types
+    Scopes       []string `json:"scopes"`                  // Allowed scopes
+    Public       bool     `json:"public"`                  // Public clients must use PKCE
+}
+
+// ConsentResponse represents scopes a user granted to an OAuth client
+type ConsentResponse struct {
+    ClientID   string    `json:"client_id"`   // OAuth client ID
+    ClientName string    `json:"client_name"` // OAuth client name
+    Scopes     []string  `json:"scopes"`      // Granted scopes
+    UpdatedAt  time.Time `json:"updated_at"`  // Last time consent was given
+}
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+    ID            string     `json:"id"`                        // Unique API key ID
+    Name          string     `json:"name"`                      // Label given at creation
+    Prefix        string     `json:"prefix"`                    // Visible part of the key, e.g. ak_1a2b3c4d
+    Key           string     `json:"key,omitempty"`             // Full key, only returned once at creation or rotation
+    Scopes        []string   `json:"scopes"`                    // Granted scopes
+    ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // Empty if the key never expires
+    LastUsedAt    *time.Time `json:"last_used_at,omitempty"`    // Last time the key authenticated a request
+    RotatedFromID *string    `json:"rotated_from_id,omitempty"` // Key this one replaced
+    CreatedAt     time.Time  `json:"created_at"`                // Time of creation
+}
+
+// DeletedUserResponse represents a soft-deleted user that can still be restored
+type DeletedUserResponse struct {
+    UserID    string    `json:"user_id"`    // Unique user ID
+    Username  string    `json:"username"`   // User's username
+    Email     string    `json:"email"`      // User's email
+    Role      string    `json:"role"`       // User's role
+    CreatedAt time.Time `json:"created_at"` // Time the user registered
+    DeletedAt time.Time `json:"deleted_at"` // Time the user was deleted
+}
+
+// UserStatusChangeResponse represents one entry of a user's account status history
+type UserStatusChangeResponse struct {
+    ChangeID   string    `json:"change_id"`            // Unique history entry ID
+    UserID     string    `json:"user_id"`              // User whose status changed
+    FromStatus string    `json:"from_status"`          // Status before the change
+    ToStatus   string    `json:"to_status"`            // Status after the change
+    Reason     string    `json:"reason"`               // Reason given by the admin
+    ChangedBy  string    `json:"changed_by,omitempty"` // Admin who made the change
+    ChangedAt  time.Time `json:"changed_at"`           // Time of the change
 }
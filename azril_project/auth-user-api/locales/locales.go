@@ -0,0 +1,10 @@
+// locales/locales.go
+
+package locales
+
+import "embed"
+
+// Files berisi katalog pesan service ini per bahasa (en.json, id.json), dimuat dengan i18n.MustLoad
+//
+//go:embed *.json
+var Files embed.FS
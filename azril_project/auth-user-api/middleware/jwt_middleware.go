@@ -2,77 +2,43 @@
 package middleware
 
 import (
-    "auth-user-api/controllers"
-    "auth-user-api/domains"
     "auth-user-api/services"
-    "net/http"
     "strings"
 
-    "github.com/golang-jwt/jwt/v4"
+    "envelope"
+    "i18n"
     "github.com/labstack/echo/v4"
+    "jwtauth"
 )
 
-type JWTMiddlewareConfig struct {
-    UserService services.UserService // Inject UserService
+// NewPrincipalResolver memeriksa user, token version dan session dari token yang valid
+func NewPrincipalResolver(tokenService services.TokenService) jwtauth.PrincipalResolver {
+    return func(ctx echo.Context, claims *jwtauth.Claims) (*jwtauth.Principal, error) {
+        return tokenService.ResolvePrincipal(ctx.Request().Context(), claims)
+    }
 }
 
-func NewJWTMiddleware(userService services.UserService) *JWTMiddlewareConfig {
-    return &JWTMiddlewareConfig{UserService: userService}
+// NewAPIKeyResolver memeriksa API key dari header X-API-Key
+func NewAPIKeyResolver(apiKeyService services.APIKeyService) jwtauth.APIKeyResolver {
+    return func(ctx echo.Context, key string) (*jwtauth.Principal, error) {
+        return apiKeyService.Authenticate(ctx.Request().Context(), key)
+    }
 }
 
-func (mw *JWTMiddlewareConfig) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-    return func(ctx echo.Context) error {
-        tokenString := ctx.Request().Header.Get("Authorization")
-
-        if tokenString == "" {
-            response := domains.BaseResponse{
-                Code:    "401",
-                Message: "Missing Authorization header",
-                Error:   "No Authorization header provided",
-            }
-            return ctx.JSON(http.StatusUnauthorized, response)
-        }
-
-        if !strings.HasPrefix(tokenString, "Bearer ") {
-            response := domains.BaseResponse{
-                Code:    "401",
-                Message: "Invalid Authorization header format",
-                Error:   "Bearer token format error",
-            }
-            return ctx.JSON(http.StatusUnauthorized, response)
-        }
-
-        tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-        claims := &controllers.JWTClaims{}
-
-        token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-            return []byte("my_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            response := domains.BaseResponse{
-                Code:    "401",
-                Message: "Invalid token",
-                Error:   "Token validation error",
-            }
-            return ctx.JSON(http.StatusUnauthorized, response)
-        }
-
-        // Cek apakah user ada di database
-        user, err := mw.UserService.GetUserByUsername(claims.Username)
-        if err != nil || user == nil {
-            response := domains.BaseResponse{
-                Code:    "401",
-                Message: "Invalid token - user not found",
-                Error:   "User not found or deleted",
-            }
-            return ctx.JSON(http.StatusUnauthorized, response)
-        }
-
-        // Set username ke dalam context jika valid
-        ctx.Set("username", claims.Username)
+// RenderAuthError menulis error autentikasi dalam format envelope.Response
+func RenderAuthError(ctx echo.Context, err *jwtauth.Error) error {
+    response := envelope.Response{
+        Message: authMessage(ctx, err),
+        Errors:  []envelope.ErrorDetail{{Message: err.Reason}},
+    }
+    return envelope.JSON(ctx, err.Status, response)
+}
 
-        // Lanjutkan ke handler berikutnya
-        return next(ctx)
+// authMessage menerjemahkan pesan error dengan kode "auth.<reason>", pesan aslinya dipakai jika kodenya tidak ada di katalog
+func authMessage(ctx echo.Context, err *jwtauth.Error) string {
+    code := "auth." + strings.ReplaceAll(strings.ToLower(err.Reason), " ", "_")
+    if message, ok := i18n.Lookup(i18n.LocaleOf(ctx), code, err.Args...); ok {
+        return message
     }
+    return err.Message
 }
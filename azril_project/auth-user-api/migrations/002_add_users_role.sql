@@ -0,0 +1,3 @@
+-- migrations/002_add_users_role.sql
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
@@ -0,0 +1,3 @@
+-- migrations/003_add_users_token_version.sql
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- migrations/010_add_users_purged_at.sql
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at_purged_at ON users (deleted_at) WHERE purged_at IS NULL;
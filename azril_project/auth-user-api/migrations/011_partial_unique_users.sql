@@ -0,0 +1,16 @@
+-- migrations/011_partial_unique_users.sql
+
+-- Username dan email hanya wajib unik di antara user yang belum dihapus, sehingga nama dari
+-- akun yang sudah di-soft delete bisa dipakai lagi. Perbandingan tidak membedakan huruf besar/kecil.
+--
+-- Index gagal dibuat jika sudah ada user aktif yang hanya berbeda huruf besar/kecil, cek dengan:
+--   SELECT LOWER(username), COUNT(*) FROM users WHERE deleted_at IS NULL GROUP BY 1 HAVING COUNT(*) > 1;
+--   SELECT LOWER(email), COUNT(*) FROM users WHERE deleted_at IS NULL GROUP BY 1 HAVING COUNT(*) > 1;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_username;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_active ON users (LOWER(username)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users (LOWER(email)) WHERE deleted_at IS NULL;
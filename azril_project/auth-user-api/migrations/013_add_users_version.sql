@@ -0,0 +1,4 @@
+-- migrations/013_add_users_version.sql
+
+-- Versi row user untuk ETag dan If-Match, naik setiap kali user diubah
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,14 @@
+-- migrations/014_create_idempotency_keys_table.sql
+
+-- Response pertama dari request POST dengan header Idempotency-Key, diputar ulang untuk retry
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY, -- Hash dari Idempotency-Key dan kredensial client
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL, -- 0 selama request pertama masih diproses
+    header JSONB NOT NULL DEFAULT '{}',
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL -- Akhir lease selama request berjalan, lalu akhir masa replay
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
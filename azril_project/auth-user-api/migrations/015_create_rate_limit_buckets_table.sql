@@ -0,0 +1,11 @@
+-- migrations/015_create_rate_limit_buckets_table.sql
+
+-- Token bucket rate limit per rute dan per IP, user atau API key (RATE_LIMIT_STORE=postgres)
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(512) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    full_at TIMESTAMP WITH TIME ZONE NOT NULL -- Setelah ini bucket penuh lagi dan boleh dihapus
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_full_at ON rate_limit_buckets (full_at);
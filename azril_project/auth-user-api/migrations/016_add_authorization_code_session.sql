@@ -0,0 +1,4 @@
+-- migrations/016_add_authorization_code_session.sql
+
+-- Session yang dibuat saat code ditukar, dicabut jika code yang sama dipakai ulang
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS session_id UUID;
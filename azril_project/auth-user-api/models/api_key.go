@@ -0,0 +1,20 @@
+// models/api_key.go
+
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for machine-to-machine access. Only the hash of the key is stored.
+type APIKey struct {
+    ID            string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    UserID        string     `gorm:"type:uuid;not null;index" json:"user_id"`
+    Name          string     `gorm:"not null" json:"name"`
+    Prefix        string     `gorm:"not null;index" json:"prefix"` // Visible part of the key, e.g. ak_1a2b3c4d
+    KeyHash       string     `gorm:"uniqueIndex;not null" json:"-"`
+    Scopes        string     `gorm:"not null" json:"scopes"` // Space separated
+    ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+    LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+    RotatedFromID *string    `gorm:"type:uuid" json:"rotated_from_id,omitempty"`
+    RevokedAt     *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
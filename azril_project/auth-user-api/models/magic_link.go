@@ -0,0 +1,17 @@
+// models/magic_link.go
+
+package models
+
+import "time"
+
+// MagicLink is a single-use passwordless login link. The link itself is a signed token
+// carrying this ID, NonceHash binds it to the browser that requested it.
+type MagicLink struct {
+    ID        string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    UserID    string     `gorm:"type:uuid;not null;index" json:"user_id"`
+    NonceHash string     `gorm:"not null" json:"-"`
+    RequestIP string     `json:"request_ip"`
+    ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+    UsedAt    *time.Time `json:"used_at,omitempty"`
+    CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,65 @@
+// models/oauth.go
+
+package models
+
+import "time"
+
+// OAuthClient is an application registered to use the OAuth 2.0 endpoints
+type OAuthClient struct {
+    ID           string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"client_id"`
+    Name         string    `gorm:"not null" json:"name"`
+    SecretHash   string    `json:"-"`                                        // Kosong untuk public client
+    RedirectURIs string    `gorm:"not null;default:''" json:"redirect_uris"` // Dipisahkan spasi
+    GrantTypes   string    `gorm:"not null" json:"grant_types"`              // Dipisahkan spasi
+    Scopes       string    `gorm:"not null;default:''" json:"scopes"`        // Dipisahkan spasi
+    Public       bool      `gorm:"not null;default:false" json:"public"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OAuthAuthorizationCode is a one-time code issued by /oauth/authorize
+type OAuthAuthorizationCode struct {
+    CodeHash            string     `gorm:"primaryKey" json:"-"`
+    ClientID            string     `gorm:"type:uuid;not null" json:"client_id"`
+    UserID              string     `gorm:"type:uuid;not null" json:"user_id"`
+    RedirectURI         string     `gorm:"not null" json:"redirect_uri"` // Kosong jika client tidak mengirimnya saat authorize
+    Scope               string     `gorm:"not null;default:''" json:"scope"`
+    CodeChallenge       string     `json:"-"`
+    CodeChallengeMethod string     `json:"-"`
+    Nonce               string     `json:"-"` // OpenID Connect nonce, returned in the id_token
+    ExpiresAt           time.Time  `gorm:"not null" json:"expires_at"`
+    UsedAt              *time.Time `json:"used_at,omitempty"`
+    SessionID           *string    `gorm:"type:uuid" json:"session_id,omitempty"` // Diisi saat code ditukar
+    CreatedAt           time.Time  `json:"created_at"`
+}
+
+// OAuthRefreshToken is rotated on every refresh_token grant
+type OAuthRefreshToken struct {
+    ID           string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    TokenHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+    ClientID     string     `gorm:"type:uuid;not null;index" json:"client_id"`
+    UserID       string     `gorm:"type:uuid;not null;index" json:"user_id"`
+    SessionID    string     `gorm:"type:uuid;not null" json:"session_id"`
+    Scope        string     `gorm:"not null;default:''" json:"scope"`
+    TokenVersion int        `gorm:"not null" json:"-"`
+    ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+    RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+    CreatedAt    time.Time  `json:"created_at"`
+}
+
+// OAuthConsent records the scopes a user has granted to a client
+type OAuthConsent struct {
+    ID        string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    UserID    string    `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consents_user_client" json:"user_id"`
+    ClientID  string    `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consents_user_client" json:"client_id"`
+    Scope     string    `gorm:"not null;default:''" json:"scope"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuth grant types supported by /oauth/token
+const (
+    GrantAuthorizationCode = "authorization_code"
+    GrantRefreshToken      = "refresh_token"
+    GrantClientCredentials = "client_credentials"
+)
@@ -0,0 +1,17 @@
+// models/session.go
+
+package models
+
+import "time"
+
+// Session is created on every successful login and bound to the issued token
+type Session struct {
+    ID          string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    UserID      string     `gorm:"type:uuid;not null;index" json:"user_id"`
+    DeviceLabel string     `gorm:"not null" json:"device_label"`
+    UserAgent   string     `json:"user_agent"`
+    IPAddress   string     `json:"ip_address"`
+    CreatedAt   time.Time  `json:"created_at"`
+    LastSeenAt  time.Time  `json:"last_seen_at"`
+    RevokedAt   *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
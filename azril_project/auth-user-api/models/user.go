@@ -9,11 +9,44 @@ import (
 )
 
 type User struct {
-    ID        string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-    Username  string         `gorm:"unique;not null" json:"username"`
-    Email     string         `gorm:"unique;not null" json:"email"`
-    Password  string         `gorm:"not null" json:"-"`
-    CreatedAt time.Time      `json:"created_at"`
-    UpdatedAt time.Time      `json:"updated_at"`
-    DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+    ID              string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    Username        string         `gorm:"not null" json:"username"` // Unik tanpa membedakan huruf besar/kecil di antara user aktif, lihat migrations/011
+    Email           string         `gorm:"not null" json:"email"`
+    EmailVerified   bool           `gorm:"not null;default:false" json:"email_verified"`
+    Password        string         `gorm:"not null" json:"-"`
+    Role            string         `gorm:"not null;default:user" json:"role"`
+    TokenVersion    int            `gorm:"not null;default:1" json:"-"`
+    Status          string         `gorm:"not null;default:active" json:"status"`
+    StatusReason    string         `json:"status_reason,omitempty"`
+    StatusChangedAt *time.Time     `json:"status_changed_at,omitempty"`
+    CreatedAt       time.Time      `json:"created_at"`
+    UpdatedAt       time.Time      `json:"updated_at"`
+    DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+    PurgedAt        *time.Time     `json:"purged_at,omitempty"` // Data pribadi sudah dianonimkan, user tidak bisa di-restore
+    Version         int            `gorm:"not null;default:1" json:"-"` // Naik setiap kali row diubah, dikirim ke client sebagai ETag
+}
+
+// Role values stored in User.Role
+const (
+    RoleUser    = "user"
+    RoleAdmin   = "admin"
+    RoleClient  = "client" // OAuth client authenticated with client_credentials
+    RoleService = "service" // Service account, authenticates with API keys only
+)
+
+// Status values stored in User.Status. User yang dihapus tetap ditandai lewat DeletedAt.
+const (
+    StatusPending   = "pending"   // Menunggu verifikasi, belum boleh login
+    StatusActive    = "active"
+    StatusSuspended = "suspended" // Dibekukan admin, misalnya karena denda belum dibayar
+    StatusLocked    = "locked"    // Dikunci karena alasan keamanan
+)
+
+// ValidStatus memeriksa apakah status bisa di-set lewat endpoint admin
+func ValidStatus(status string) bool {
+    switch status {
+    case StatusPending, StatusActive, StatusSuspended, StatusLocked:
+        return true
+    }
+    return false
 }
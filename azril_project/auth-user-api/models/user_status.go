@@ -0,0 +1,21 @@
+// models/user_status.go
+
+package models
+
+import "time"
+
+// UserStatusChange records one change of User.Status made by an admin. Rows are never
+// updated, so the table is the full history of why an account was suspended or locked.
+type UserStatusChange struct {
+    ID         string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+    UserID     string    `gorm:"type:uuid;not null;index" json:"user_id"`
+    FromStatus string    `gorm:"not null" json:"from_status"`
+    ToStatus   string    `gorm:"not null" json:"to_status"`
+    Reason     string    `gorm:"not null" json:"reason"`
+    ChangedBy  string    `json:"changed_by,omitempty"`
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+func (UserStatusChange) TableName() string {
+    return "user_status_history"
+}
@@ -0,0 +1,75 @@
+// repository/api_key_repository.go
+
+package repository
+
+import (
+    "context"
+    "auth-user-api/models"
+    "time"
+
+    "gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+    CreateAPIKey(ctx context.Context, key *models.APIKey) error
+    GetAPIKeyByID(ctx context.Context, id string) (*models.APIKey, error)
+    GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+    GetAPIKeysByUserID(ctx context.Context, userID string) ([]*models.APIKey, error)
+    RevokeAPIKey(ctx context.Context, id string) error
+    RotateAPIKey(ctx context.Context, oldID string, oldExpiresAt time.Time, newKey *models.APIKey) error
+    UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+    db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+    return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+    return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) GetAPIKeyByID(ctx context.Context, id string) (*models.APIKey, error) {
+    var key models.APIKey
+    if err := r.db.WithContext(ctx).Where("id = ?", id).First(&key).Error; err != nil {
+        return nil, err
+    }
+    return &key, nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+    var key models.APIKey
+    if err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+        return nil, err
+    }
+    return &key, nil
+}
+
+func (r *apiKeyRepository) GetAPIKeysByUserID(ctx context.Context, userID string) ([]*models.APIKey, error) {
+    var keys []*models.APIKey
+    if err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+        return nil, err
+    }
+    return keys, nil
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", gorm.Expr("NOW()")).Error
+}
+
+// RotateAPIKey membuat key baru dan memperpendek masa berlaku key lama dalam satu transaksi
+func (r *apiKeyRepository) RotateAPIKey(ctx context.Context, oldID string, oldExpiresAt time.Time, newKey *models.APIKey) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(newKey).Error; err != nil {
+            return err
+        }
+        return tx.Model(&models.APIKey{}).Where("id = ?", oldID).Update("expires_at", oldExpiresAt).Error
+    })
+}
+
+func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+    return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
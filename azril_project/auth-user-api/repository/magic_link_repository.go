@@ -0,0 +1,47 @@
+// repository/magic_link_repository.go
+
+package repository
+
+import (
+    "context"
+    "auth-user-api/models"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+type MagicLinkRepository interface {
+    CreateMagicLink(ctx context.Context, link *models.MagicLink) error
+    ConsumeMagicLink(ctx context.Context, id string) (*models.MagicLink, error)
+}
+
+type magicLinkRepository struct {
+    db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+    return &magicLinkRepository{db}
+}
+
+func (r *magicLinkRepository) CreateMagicLink(ctx context.Context, link *models.MagicLink) error {
+    return r.db.WithContext(ctx).Create(link).Error
+}
+
+// ConsumeMagicLink menandai link sebagai terpakai dan mengembalikan UsedAt sebelumnya,
+// row dikunci agar link yang sama tidak bisa dipakai dua kali secara bersamaan
+func (r *magicLinkRepository) ConsumeMagicLink(ctx context.Context, id string) (*models.MagicLink, error) {
+    var link models.MagicLink
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&link).Error; err != nil {
+            return err
+        }
+        if link.UsedAt != nil {
+            return nil
+        }
+        return tx.Model(&link).Update("used_at", gorm.Expr("NOW()")).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &link, nil
+}
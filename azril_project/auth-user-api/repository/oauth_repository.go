@@ -0,0 +1,145 @@
+// repository/oauth_repository.go
+
+package repository
+
+import (
+    "context"
+    "auth-user-api/models"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+type OAuthRepository interface {
+    CreateClient(ctx context.Context, client *models.OAuthClient) error
+    GetClientByID(ctx context.Context, id string) (*models.OAuthClient, error)
+    GetAllClients(ctx context.Context) ([]*models.OAuthClient, error)
+
+    CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+    ConsumeAuthorizationCode(ctx context.Context, codeHash, clientID string) (*models.OAuthAuthorizationCode, error)
+    SetAuthorizationCodeSession(ctx context.Context, codeHash, sessionID string) error
+
+    CreateRefreshToken(ctx context.Context, token *models.OAuthRefreshToken) error
+    GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.OAuthRefreshToken, error)
+    RevokeRefreshToken(ctx context.Context, id string) (bool, error)
+    RevokeRefreshTokensBySessionID(ctx context.Context, sessionID string) error
+    RevokeRefreshTokensByUserAndClient(ctx context.Context, userID, clientID string) error
+
+    UpsertConsent(ctx context.Context, consent *models.OAuthConsent) error
+    GetConsent(ctx context.Context, userID, clientID string) (*models.OAuthConsent, error)
+    GetConsentsByUserID(ctx context.Context, userID string) ([]*models.OAuthConsent, error)
+    DeleteConsent(ctx context.Context, userID, clientID string) error
+}
+
+type oauthRepository struct {
+    db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) OAuthRepository {
+    return &oauthRepository{db}
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+    return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *oauthRepository) GetClientByID(ctx context.Context, id string) (*models.OAuthClient, error) {
+    var client models.OAuthClient
+    if err := r.db.WithContext(ctx).Where("id = ?", id).First(&client).Error; err != nil {
+        return nil, err
+    }
+    return &client, nil
+}
+
+func (r *oauthRepository) GetAllClients(ctx context.Context) ([]*models.OAuthClient, error) {
+    var clients []*models.OAuthClient
+    if err := r.db.WithContext(ctx).Order("created_at").Find(&clients).Error; err != nil {
+        return nil, err
+    }
+    return clients, nil
+}
+
+func (r *oauthRepository) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+    return r.db.WithContext(ctx).Create(code).Error
+}
+
+// ConsumeAuthorizationCode menandai code sudah dipakai secara atomik, sehingga code hanya bisa ditukar sekali.
+// Code milik client lain dikembalikan tanpa ditandai, agar client yang salah tidak bisa menghanguskannya.
+func (r *oauthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash, clientID string) (*models.OAuthAuthorizationCode, error) {
+    var code models.OAuthAuthorizationCode
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+            return err
+        }
+        if code.UsedAt != nil || code.ClientID != clientID {
+            return nil
+        }
+        return tx.Model(&code).Update("used_at", gorm.Expr("NOW()")).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &code, nil
+}
+
+// SetAuthorizationCodeSession mencatat session hasil penukaran code, dipakai untuk mencabutnya jika code dipakai ulang
+func (r *oauthRepository) SetAuthorizationCodeSession(ctx context.Context, codeHash, sessionID string) error {
+    return r.db.WithContext(ctx).Model(&models.OAuthAuthorizationCode{}).Where("code_hash = ?", codeHash).Update("session_id", sessionID).Error
+}
+
+func (r *oauthRepository) CreateRefreshToken(ctx context.Context, token *models.OAuthRefreshToken) error {
+    return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *oauthRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.OAuthRefreshToken, error) {
+    var token models.OAuthRefreshToken
+    if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+        return nil, err
+    }
+    return &token, nil
+}
+
+// RevokeRefreshToken mengembalikan false jika token sudah dicabut sebelumnya, sehingga dari dua
+// refresh bersamaan dengan token yang sama hanya satu yang berhasil
+func (r *oauthRepository) RevokeRefreshToken(ctx context.Context, id string) (bool, error) {
+    result := r.db.WithContext(ctx).Model(&models.OAuthRefreshToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", gorm.Expr("NOW()"))
+    if result.Error != nil {
+        return false, result.Error
+    }
+    return result.RowsAffected == 1, nil
+}
+
+func (r *oauthRepository) RevokeRefreshTokensBySessionID(ctx context.Context, sessionID string) error {
+    return r.db.WithContext(ctx).Model(&models.OAuthRefreshToken{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", gorm.Expr("NOW()")).Error
+}
+
+func (r *oauthRepository) RevokeRefreshTokensByUserAndClient(ctx context.Context, userID, clientID string) error {
+    return r.db.WithContext(ctx).Model(&models.OAuthRefreshToken{}).Where("user_id = ? AND client_id = ? AND revoked_at IS NULL", userID, clientID).Update("revoked_at", gorm.Expr("NOW()")).Error
+}
+
+func (r *oauthRepository) UpsertConsent(ctx context.Context, consent *models.OAuthConsent) error {
+    return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+        DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+    }).Create(consent).Error
+}
+
+func (r *oauthRepository) GetConsent(ctx context.Context, userID, clientID string) (*models.OAuthConsent, error) {
+    var consent models.OAuthConsent
+    if err := r.db.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+        return nil, err
+    }
+    return &consent, nil
+}
+
+func (r *oauthRepository) GetConsentsByUserID(ctx context.Context, userID string) ([]*models.OAuthConsent, error) {
+    var consents []*models.OAuthConsent
+    if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC").Find(&consents).Error; err != nil {
+        return nil, err
+    }
+    return consents, nil
+}
+
+func (r *oauthRepository) DeleteConsent(ctx context.Context, userID, clientID string) error {
+    return r.db.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{}).Error
+}
@@ -0,0 +1,71 @@
+// repository/session_repository.go
+
+package repository
+
+import (
+    "context"
+    "auth-user-api/models"
+    "time"
+
+    "gorm.io/gorm"
+)
+
+type SessionRepository interface {
+    CreateSession(ctx context.Context, session *models.Session) error
+    GetSessionByID(ctx context.Context, id string) (*models.Session, error)
+    GetActiveSessionsByUserID(ctx context.Context, userID string) ([]*models.Session, error)
+    RevokeSession(ctx context.Context, id string) error
+    RevokeSessionsByUserID(ctx context.Context, userID string) error
+    UpdateLastSeen(ctx context.Context, lastSeen map[string]time.Time) error
+}
+
+type sessionRepository struct {
+    db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+    return &sessionRepository{db}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+    return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) GetSessionByID(ctx context.Context, id string) (*models.Session, error) {
+    var session models.Session
+    if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+        return nil, err
+    }
+    return &session, nil
+}
+
+func (r *sessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID string) ([]*models.Session, error) {
+    var sessions []*models.Session
+    if err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+        return nil, err
+    }
+    return sessions, nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", gorm.Expr("NOW()")).Error
+}
+
+func (r *sessionRepository) RevokeSessionsByUserID(ctx context.Context, userID string) error {
+    return r.db.WithContext(ctx).Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", gorm.Expr("NOW()")).Error
+}
+
+// UpdateLastSeen menulis last_seen_at beberapa session sekaligus dalam satu transaksi
+func (r *sessionRepository) UpdateLastSeen(ctx context.Context, lastSeen map[string]time.Time) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        for id, seenAt := range lastSeen {
+            err := tx.Model(&models.Session{}).
+                Where("id = ? AND last_seen_at < ?", id, seenAt).
+                Update("last_seen_at", seenAt).Error
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
@@ -3,18 +3,90 @@
 package repository
 
 import (
+    "context"
     "auth-user-api/models"
+    "errors"
+    "time"
+    "i18n"
+    "precondition"
 
+    "github.com/jackc/pgx/v5/pgconn"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
-    CreateUser(user *models.User) error
-    GetUserByUsername(username string) (*models.User, error)
-    GetUserByID(id string) (*models.User, error)
-    UpdateUser(user *models.User) error
-    DeleteUser(id string) error
-    GetAllUsers() ([]*models.User, error)
+    CreateUser(ctx context.Context, user *models.User) error
+    GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+    GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+    GetUserByID(ctx context.Context, id string) (*models.User, error)
+    UpdateUser(ctx context.Context, user *models.User) error
+    DeleteUser(ctx context.Context, id string) error
+    GetAllUsers(ctx context.Context) ([]*models.User, error)
+    IncrementTokenVersion(ctx context.Context, id string) error
+    GetDeletedUsers(ctx context.Context) ([]*models.User, error)
+    GetUsersDeletedBefore(ctx context.Context, cutoff time.Time) ([]*models.User, error)
+    RestoreUser(ctx context.Context, id string) error
+    PurgeUser(ctx context.Context, id string) error
+    ChangeUserStatus(ctx context.Context, id, status, reason, changedBy string) (*models.UserStatusChange, error)
+    GetUserStatusHistory(ctx context.Context, id string) ([]*models.UserStatusChange, error)
+}
+
+// Dikembalikan saat username atau email sudah dipakai user lain yang belum dihapus
+var (
+    ErrDuplicateUsername = i18n.NewError("user.username_taken")
+    ErrDuplicateEmail    = i18n.NewError("user.email_taken")
+)
+
+// ErrVersionConflict dikembalikan UpdateUser jika user sudah diubah request lain sejak dibaca
+var ErrVersionConflict = precondition.ErrFailed
+
+// Nama partial unique index dari migrations/011_partial_unique_users.sql
+const (
+    usersUsernameIndex = "idx_users_username_active"
+    usersEmailIndex    = "idx_users_email_active"
+)
+
+// DropUserUniqueConstraints menghapus constraint UNIQUE lama pada username dan email (migrations/011).
+// Harus dijalankan sebelum AutoMigrate, karena AutoMigrate mencoba menghapus constraint yang
+// tidak lagi ada di tag model dengan nama uni_users_* dan gagal jika constraint dibuat oleh migrations/001.
+func DropUserUniqueConstraints(db *gorm.DB) error {
+    for _, name := range []string{"users_username_key", "users_email_key", "uni_users_username", "uni_users_email"} {
+        if err := db.Exec("ALTER TABLE IF EXISTS users DROP CONSTRAINT IF EXISTS " + name).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// CreateUserUniqueIndexes membuat partial unique index dari migrations/011, AutoMigrate tidak bisa
+// membuat index ekspresi dengan kondisi WHERE
+func CreateUserUniqueIndexes(db *gorm.DB) error {
+    statements := []string{
+        "CREATE UNIQUE INDEX IF NOT EXISTS " + usersUsernameIndex + " ON users (LOWER(username)) WHERE deleted_at IS NULL",
+        "CREATE UNIQUE INDEX IF NOT EXISTS " + usersEmailIndex + " ON users (LOWER(email)) WHERE deleted_at IS NULL",
+    }
+    for _, statement := range statements {
+        if err := db.Exec(statement).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// translateUniqueViolation mengubah unique violation dari Postgres menjadi ErrDuplicateUsername atau ErrDuplicateEmail
+func translateUniqueViolation(err error) error {
+    var pgErr *pgconn.PgError
+    if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+        return err
+    }
+    switch pgErr.ConstraintName {
+    case usersUsernameIndex:
+        return ErrDuplicateUsername
+    case usersEmailIndex:
+        return ErrDuplicateEmail
+    }
+    return err
 }
 
 type userRepository struct {
@@ -25,38 +97,195 @@ func NewUserRepository(db *gorm.DB) UserRepository {
     return &userRepository{db}
 }
 
-func (r *userRepository) CreateUser(user *models.User) error {
-    return r.db.Create(user).Error
+func (r *userRepository) CreateUser(ctx context.Context, user *models.User) error {
+    return translateUniqueViolation(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+    var user models.User
+    if err := r.db.WithContext(ctx).Where("LOWER(username) = LOWER(?) AND deleted_at IS NULL", username).First(&user).Error; err != nil {
+        return nil, err
+    }
+    return &user, nil
 }
 
-func (r *userRepository) GetUserByUsername(username string) (*models.User, error) {
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
     var user models.User
-    if err := r.db.Where("username = ? AND deleted_at IS NULL", username).First(&user).Error; err != nil {
+    if err := r.db.WithContext(ctx).Where("LOWER(email) = LOWER(?) AND deleted_at IS NULL", email).First(&user).Error; err != nil {
         return nil, err
     }
     return &user, nil
 }
 
-func (r *userRepository) GetAllUsers() ([]*models.User, error) {
+func (r *userRepository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
     var users []*models.User
-    if err := r.db.Find(&users).Error; err != nil {
+    if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
         return nil, err
     }
     return users, nil
 }
 
-func (r *userRepository) GetUserByID(id string) (*models.User, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
     var user models.User
-    if err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&user).Error; err != nil {
+    if err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error; err != nil {
         return nil, err
     }
     return &user, nil
 }
 
-func (r *userRepository) UpdateUser(user *models.User) error {
-    return r.db.Save(user).Error
+// UpdateUser menyimpan user hanya jika versinya belum diubah request lain sejak user dibaca, lalu
+// menaikkan versinya. Mengembalikan ErrVersionConflict jika versinya sudah berubah.
+func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
+    // Save tidak bisa dipakai, saat tidak ada row yang cocok Save malah melakukan insert
+    version := user.Version
+    user.Version++
+    result := r.db.WithContext(ctx).Model(user).Where("version = ?", version).Select("*").Updates(user)
+    if result.Error == nil && result.RowsAffected == 0 {
+        result.Error = ErrVersionConflict
+    }
+    if result.Error != nil {
+        user.Version = version
+        return translateUniqueViolation(result.Error)
+    }
+    return nil
+}
+
+// IncrementTokenVersion juga menaikkan version, agar UpdateUser yang membaca user sebelumnya gagal
+// dan tidak menulis ulang token_version yang lama
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ? AND deleted_at IS NULL", id).Updates(map[string]interface{}{
+        "token_version": gorm.Expr("token_version + 1"),
+        "version":       gorm.Expr("version + 1"),
+    }).Error
+}
+
+func (r *userRepository) DeleteUser(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+        "deleted_at": gorm.Expr("NOW()"),
+        "version":    gorm.Expr("version + 1"),
+    }).Error
 }
 
-func (r *userRepository) DeleteUser(id string) error {
-    return r.db.Model(&models.User{}).Where("id = ?", id).Update("deleted_at", gorm.Expr("NOW()")).Error
+// GetDeletedUsers mengambil user yang sudah dihapus tetapi belum di-purge
+func (r *userRepository) GetDeletedUsers(ctx context.Context) ([]*models.User, error) {
+    var users []*models.User
+    err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL AND purged_at IS NULL").Order("deleted_at DESC").Find(&users).Error
+    if err != nil {
+        return nil, err
+    }
+    return users, nil
+}
+
+func (r *userRepository) GetUsersDeletedBefore(ctx context.Context, cutoff time.Time) ([]*models.User, error) {
+    var users []*models.User
+    err := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ? AND purged_at IS NULL", cutoff).Find(&users).Error
+    if err != nil {
+        return nil, err
+    }
+    return users, nil
+}
+
+// RestoreUser mengembalikan user yang dihapus, gorm.ErrRecordNotFound jika user tidak dalam status terhapus
+func (r *userRepository) RestoreUser(ctx context.Context, id string) error {
+    result := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+        Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+        Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+    if result.Error != nil {
+        // Username atau email sudah dipakai user baru selama akun ini terhapus
+        return translateUniqueViolation(result.Error)
+    }
+    if result.RowsAffected == 0 {
+        return gorm.ErrRecordNotFound
+    }
+    return nil
+}
+
+// PurgeUser menganonimkan data pribadi user yang sudah dihapus. Row user tetap ada agar
+// riwayat yang mereferensikan users(id) tetap utuh, data turunan yang berisi data pribadi dihapus.
+// audit_events tidak diubah karena append-only: metadata-nya hanya berisi ID user, sedangkan IP
+// dan user agent actor sengaja disimpan sebagai catatan keamanan.
+func (r *userRepository) PurgeUser(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        result := tx.Unscoped().Model(&models.User{}).
+            Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+            Updates(map[string]interface{}{
+                "username":       "purged-" + id,
+                "email":          id + "@purged.invalid",
+                "password":       "",
+                "email_verified": false,
+                "status_reason":  "",
+                "token_version":  gorm.Expr("token_version + 1"),
+                "purged_at":      gorm.Expr("NOW()"),
+                "version":        gorm.Expr("version + 1"),
+            })
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return gorm.ErrRecordNotFound
+        }
+
+        // Urutan mengikuti foreign key: refresh token mereferensikan session
+        dependents := []interface{}{
+            &models.OAuthRefreshToken{},
+            &models.OAuthAuthorizationCode{},
+            &models.OAuthConsent{},
+            &models.MagicLink{},
+            &models.APIKey{},
+            &models.Session{},
+        }
+        for _, model := range dependents {
+            if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+                return err
+            }
+        }
+
+        // Riwayat status tetap ada, alasan yang ditulis admin bisa berisi data pribadi
+        return tx.Model(&models.UserStatusChange{}).Where("user_id = ?", id).Update("reason", "").Error
+    })
+}
+
+// ChangeUserStatus mengubah status user yang belum dihapus dan mencatatnya ke user_status_history
+// dalam satu transaksi, row user dikunci agar from_status di riwayat selalu benar
+func (r *userRepository) ChangeUserStatus(ctx context.Context, id, status, reason, changedBy string) (*models.UserStatusChange, error) {
+    var change *models.UserStatusChange
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        var user models.User
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error; err != nil {
+            return err
+        }
+
+        err := tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+            "status":            status,
+            "status_reason":     reason,
+            "status_changed_at": time.Now(),
+            "version":           gorm.Expr("version + 1"),
+        }).Error
+        if err != nil {
+            return err
+        }
+
+        change = &models.UserStatusChange{
+            UserID:     id,
+            FromStatus: user.Status,
+            ToStatus:   status,
+            Reason:     reason,
+            ChangedBy:  changedBy,
+        }
+        return tx.Create(change).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return change, nil
+}
+
+// GetUserStatusHistory mengambil riwayat perubahan status user, yang terbaru lebih dulu
+func (r *userRepository) GetUserStatusHistory(ctx context.Context, id string) ([]*models.UserStatusChange, error) {
+    var changes []*models.UserStatusChange
+    err := r.db.WithContext(ctx).Where("user_id = ?", id).Order("created_at DESC").Find(&changes).Error
+    if err != nil {
+        return nil, err
+    }
+    return changes, nil
 }
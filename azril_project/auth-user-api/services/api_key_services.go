@@ -0,0 +1,239 @@
+package services
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "strings"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+    "auditlog"
+    "i18n"
+
+    "jwtauth"
+)
+
+const (
+    apiKeyPrefix = "ak_"
+
+    // DefaultAPIKeyOverlap adalah lama key lama tetap berlaku setelah rotasi
+    DefaultAPIKeyOverlap = 24 * time.Hour
+    MaxAPIKeyOverlap     = 7 * 24 * time.Hour
+
+    // last_used_at cukup ditulis paling sering sekali per menit untuk setiap key
+    apiKeyLastUsedInterval = time.Minute
+)
+
+var ErrAPIKeyNotFound = i18n.NewError("api_key.not_found")
+
+type APIKeyService interface {
+    Create(ctx context.Context, userID, name string, scopes []string, ttl time.Duration, actor auditlog.Actor) (*models.APIKey, string, error)
+    List(ctx context.Context, userID string) ([]*models.APIKey, error)
+    Revoke(ctx context.Context, userID, keyID string, actor auditlog.Actor) error
+    Rotate(ctx context.Context, userID, keyID string, overlap time.Duration, actor auditlog.Actor) (*models.APIKey, string, error)
+    Authenticate(ctx context.Context, key string) (*jwtauth.Principal, error)
+}
+
+type apiKeyService struct {
+    repo        repository.APIKeyRepository
+    userService UserService
+    audit       *auditlog.Logger
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, userService UserService, audit *auditlog.Logger) APIKeyService {
+    return &apiKeyService{repo: repo, userService: userService, audit: audit}
+}
+
+// Create - Membuat API key baru. Key asli hanya dikembalikan sekali, yang disimpan hanya hash-nya.
+// ttl 0 berarti key tidak pernah kedaluwarsa.
+func (s *apiKeyService) Create(ctx context.Context, userID, name string, scopes []string, ttl time.Duration, actor auditlog.Actor) (created *models.APIKey, secret string, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditAPIKeyCreate, err).With("user_id", userID).With("scopes", scopes)
+        if created != nil {
+            event = event.On("api_key", created.ID).With("prefix", created.Prefix)
+        }
+        s.audit.Record(event)
+    }()
+
+    if len(scopes) == 0 {
+        return nil, "", i18n.NewError("api_key.scope_required")
+    }
+    for _, scope := range scopes {
+        if _, ok := SupportedScopes[scope]; !ok {
+            return nil, "", i18n.NewError("scope.unsupported", scope)
+        }
+    }
+
+    user, err := s.userService.GetUserByID(ctx, userID)
+    if err != nil {
+        return nil, "", err
+    }
+
+    key, secret, err := newAPIKey(user.ID, name, strings.Join(scopes, " "))
+    if err != nil {
+        return nil, "", err
+    }
+    if ttl > 0 {
+        expiresAt := time.Now().Add(ttl)
+        key.ExpiresAt = &expiresAt
+    }
+
+    if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+        return nil, "", err
+    }
+    return key, secret, nil
+}
+
+// List - Mengambil API key user yang belum dicabut
+func (s *apiKeyService) List(ctx context.Context, userID string) ([]*models.APIKey, error) {
+    return s.repo.GetAPIKeysByUserID(ctx, userID)
+}
+
+// Revoke - Mencabut API key milik user
+func (s *apiKeyService) Revoke(ctx context.Context, userID, keyID string, actor auditlog.Actor) (err error) {
+    defer func() {
+        s.audit.Record(auditEvent(actor, AuditAPIKeyRevoke, err).On("api_key", keyID).With("user_id", userID))
+    }()
+
+    key, err := s.ownedKey(ctx, userID, keyID)
+    if err != nil {
+        return err
+    }
+    return s.repo.RevokeAPIKey(ctx, key.ID)
+}
+
+// Rotate - Membuat key pengganti dengan nama dan scope yang sama. Key lama tetap berlaku selama overlap
+// agar job yang memakainya bisa diganti tanpa downtime. overlap 0 langsung mencabut key lama.
+func (s *apiKeyService) Rotate(ctx context.Context, userID, keyID string, overlap time.Duration, actor auditlog.Actor) (rotated *models.APIKey, secret string, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditAPIKeyRotate, err).On("api_key", keyID).With("user_id", userID).With("overlap", overlap.String())
+        if rotated != nil {
+            event = event.With("new_api_key_id", rotated.ID)
+        }
+        s.audit.Record(event)
+    }()
+
+    if overlap < 0 || overlap > MaxAPIKeyOverlap {
+        return nil, "", i18n.NewError("api_key.invalid_overlap", MaxAPIKeyOverlap.String())
+    }
+
+    old, err := s.ownedKey(ctx, userID, keyID)
+    if err != nil {
+        return nil, "", err
+    }
+    now := time.Now()
+    if old.ExpiresAt != nil && !now.Before(*old.ExpiresAt) {
+        return nil, "", i18n.NewError("api_key.expired")
+    }
+
+    key, secret, err := newAPIKey(old.UserID, old.Name, old.Scopes)
+    if err != nil {
+        return nil, "", err
+    }
+    key.RotatedFromID = &old.ID
+    // Key baru mendapat masa berlaku sepanjang key lama
+    if old.ExpiresAt != nil {
+        expiresAt := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+        key.ExpiresAt = &expiresAt
+    }
+
+    if overlap == 0 {
+        if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+            return nil, "", err
+        }
+        if err := s.repo.RevokeAPIKey(ctx, old.ID); err != nil {
+            return nil, "", err
+        }
+        return key, secret, nil
+    }
+
+    oldExpiresAt := now.Add(overlap)
+    if old.ExpiresAt != nil && old.ExpiresAt.Before(oldExpiresAt) {
+        oldExpiresAt = *old.ExpiresAt
+    }
+    if err := s.repo.RotateAPIKey(ctx, old.ID, oldExpiresAt, key); err != nil {
+        return nil, "", err
+    }
+    return key, secret, nil
+}
+
+// Authenticate - Memeriksa API key dari header X-API-Key dan mengembalikan principal pemiliknya
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*jwtauth.Principal, error) {
+    if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+        return nil, jwtauth.ErrInvalidAPIKey
+    }
+
+    key, err := s.repo.GetAPIKeyByHash(ctx, utils.HashToken(rawKey))
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, jwtauth.ErrInvalidAPIKey
+        }
+        return nil, err
+    }
+
+    now := time.Now()
+    if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+        return nil, jwtauth.ErrInvalidAPIKey
+    }
+
+    user, err := s.userService.GetAuthUser(ctx, key.UserID)
+    if err != nil || user == nil {
+        return nil, jwtauth.Unauthorized("User not found or deleted", "Invalid API key - user not found")
+    }
+    if err := accountStatusError(user); err != nil {
+        return nil, accountStatusAuthError(user, err)
+    }
+
+    if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedInterval {
+        // Gagal mencatat last_used_at tidak boleh menggagalkan request
+        _ = s.repo.UpdateLastUsed(ctx, key.ID, now)
+    }
+
+    return &jwtauth.Principal{
+        ID:       user.ID,
+        Username: user.Username,
+        Roles:    []string{user.Role},
+        APIKeyID: key.ID,
+        Scopes:   strings.Fields(key.Scopes),
+    }, nil
+}
+
+// ownedKey mengambil key aktif milik user, key milik user lain dianggap tidak ada
+func (s *apiKeyService) ownedKey(ctx context.Context, userID, keyID string) (*models.APIKey, error) {
+    key, err := s.repo.GetAPIKeyByID(ctx, keyID)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, ErrAPIKeyNotFound
+        }
+        return nil, err
+    }
+    if key.UserID != userID || key.RevokedAt != nil {
+        return nil, ErrAPIKeyNotFound
+    }
+    return key, nil
+}
+
+// newAPIKey membuat key berformat ak_<prefix>_<secret>, prefix disimpan agar key bisa dikenali di daftar
+func newAPIKey(userID, name, scopes string) (*models.APIKey, string, error) {
+    prefixBytes := make([]byte, 4)
+    if _, err := rand.Read(prefixBytes); err != nil {
+        return nil, "", err
+    }
+    secret, err := utils.GenerateRandomToken()
+    if err != nil {
+        return nil, "", err
+    }
+
+    prefix := apiKeyPrefix + hex.EncodeToString(prefixBytes)
+    rawKey := prefix + "_" + secret
+    return &models.APIKey{
+        UserID:  userID,
+        Name:    name,
+        Prefix:  prefix,
+        KeyHash: utils.HashToken(rawKey),
+        Scopes:  scopes,
+    }, rawKey, nil
+}
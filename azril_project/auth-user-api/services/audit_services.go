@@ -0,0 +1,30 @@
+package services
+
+import "auditlog"
+
+// Action yang dicatat ke audit_events
+const (
+    AuditUserRegister       = "user.register"
+    AuditUserLogin          = "user.login"
+    AuditUserUpdate         = "user.update"
+    AuditUserPasswordChange = "user.password_change"
+    AuditUserDelete         = "user.delete"
+    AuditUserRestore        = "user.restore"
+    AuditUserPurge          = "user.purge"
+    AuditUserStatusChange   = "user.status_change"
+    AuditUserTokensRevoke   = "user.tokens_revoke"
+    AuditServiceAccount     = "service_account.create"
+    AuditAPIKeyCreate       = "api_key.create"
+    AuditAPIKeyRotate       = "api_key.rotate"
+    AuditAPIKeyRevoke       = "api_key.revoke"
+    AuditMagicLinkRequest   = "magic_link.request"
+    AuditOAuthClientCreate  = "oauth_client.create"
+)
+
+// auditEvent membuat event dengan outcome success, atau failure beserta alasannya jika err tidak nil
+func auditEvent(actor auditlog.Actor, action string, err error) auditlog.Event {
+    if err != nil {
+        return actor.Event(action, auditlog.Failure).With("reason", err.Error())
+    }
+    return actor.Event(action, auditlog.Success)
+}
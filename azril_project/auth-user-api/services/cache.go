@@ -0,0 +1,148 @@
+package services
+
+import (
+    "container/list"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// CacheStats berisi statistik hit/miss dari Cache
+type CacheStats struct {
+    Hits      uint64 `json:"hits"`
+    Misses    uint64 `json:"misses"`
+    Evictions uint64 `json:"evictions"`
+    Size      int    `json:"size"`
+}
+
+// Cache adalah cache LRU dengan TTL untuk data yang dibaca di setiap request terautentikasi.
+// Aman dipakai dari banyak goroutine dan jumlah entry-nya dibatasi oleh capacity.
+type Cache[T any] struct {
+    mu         sync.Mutex
+    capacity   int
+    ttl        time.Duration
+    items      map[string]*list.Element
+    order      *list.List // Depan = paling baru dipakai
+    generation uint64     // Naik setiap invalidasi, mencegah data lama masuk kembali
+
+    hits      atomic.Uint64
+    misses    atomic.Uint64
+    evictions atomic.Uint64
+}
+
+type cacheEntry[T any] struct {
+    key       string
+    value     T
+    expiresAt time.Time
+}
+
+func NewCache[T any](capacity int, ttl time.Duration) *Cache[T] {
+    if capacity < 1 {
+        capacity = 1
+    }
+    return &Cache[T]{
+        capacity: capacity,
+        ttl:      ttl,
+        items:    make(map[string]*list.Element),
+        order:    list.New(),
+    }
+}
+
+// Get mengembalikan salinan data jika ada dan belum kedaluwarsa
+func (c *Cache[T]) Get(key string) (*T, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    elem, ok := c.items[key]
+    if !ok {
+        c.misses.Add(1)
+        return nil, false
+    }
+
+    entry := elem.Value.(*cacheEntry[T])
+    if time.Now().After(entry.expiresAt) {
+        c.removeElement(elem)
+        c.misses.Add(1)
+        return nil, false
+    }
+
+    c.order.MoveToFront(elem)
+    c.hits.Add(1)
+    value := entry.value
+    return &value, true
+}
+
+// Generation dibaca sebelum mengambil data dari database lalu diberikan ke Set
+func (c *Cache[T]) Generation() uint64 {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.generation
+}
+
+// Set menyimpan salinan data, kecuali ada invalidasi sejak generation dibaca
+func (c *Cache[T]) Set(key string, value *T, generation uint64) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if generation != c.generation {
+        return
+    }
+
+    entry := &cacheEntry[T]{key: key, value: *value, expiresAt: time.Now().Add(c.ttl)}
+    if elem, ok := c.items[key]; ok {
+        elem.Value = entry
+        c.order.MoveToFront(elem)
+        return
+    }
+
+    c.items[key] = c.order.PushFront(entry)
+    for c.order.Len() > c.capacity {
+        c.removeElement(c.order.Back())
+        c.evictions.Add(1)
+    }
+}
+
+// Invalidate menghapus satu key dari cache
+func (c *Cache[T]) Invalidate(key string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.generation++
+    if elem, ok := c.items[key]; ok {
+        c.removeElement(elem)
+    }
+}
+
+// InvalidateFunc menghapus semua entry yang cocok dengan match, misalnya semua session milik satu user
+func (c *Cache[T]) InvalidateFunc(match func(value *T) bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.generation++
+    for elem := c.order.Front(); elem != nil; {
+        next := elem.Next()
+        if match(&elem.Value.(*cacheEntry[T]).value) {
+            c.removeElement(elem)
+        }
+        elem = next
+    }
+}
+
+// Stats mengembalikan statistik cache saat ini
+func (c *Cache[T]) Stats() CacheStats {
+    c.mu.Lock()
+    size := c.order.Len()
+    c.mu.Unlock()
+
+    return CacheStats{
+        Hits:      c.hits.Load(),
+        Misses:    c.misses.Load(),
+        Evictions: c.evictions.Load(),
+        Size:      size,
+    }
+}
+
+func (c *Cache[T]) removeElement(elem *list.Element) {
+    c.order.Remove(elem)
+    delete(c.items, elem.Value.(*cacheEntry[T]).key)
+}
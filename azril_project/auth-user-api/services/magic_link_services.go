@@ -0,0 +1,244 @@
+package services
+
+import (
+    "context"
+    "log/slog"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+    "auditlog"
+    "i18n"
+
+    "github.com/golang-jwt/jwt/v4"
+    "jwtauth"
+    "metrics"
+)
+
+var (
+    ErrMagicLinkInvalid     = i18n.NewError("magic_link.invalid")
+    ErrMagicLinkUsed        = i18n.NewError("magic_link.used")
+    ErrMagicLinkNonce       = i18n.NewError("magic_link.nonce")
+    ErrMagicLinkRateLimited = i18n.NewError("magic_link.rate_limited")
+)
+
+type MagicLinkService interface {
+    Request(ctx context.Context, email, nonce string, actor auditlog.Actor) error
+    Consume(ctx context.Context, token, nonce string, actor auditlog.Actor) (*models.User, error)
+    Wait()
+}
+
+// magicLinkSendTimeout membatasi waktu mencari user, menyimpan link dan mengirim email di background
+const magicLinkSendTimeout = 30 * time.Second
+
+type magicLinkService struct {
+    repo        repository.MagicLinkRepository
+    userService UserService
+    mailer      utils.Mailer
+    config      utils.MagicLinkConfig
+    limiter     *emailRateLimiter
+    audit       *auditlog.Logger
+    pending     sync.WaitGroup // Pengiriman link yang masih berjalan di background
+}
+
+func NewMagicLinkService(repo repository.MagicLinkRepository, userService UserService, mailer utils.Mailer, config utils.MagicLinkConfig, audit *auditlog.Logger) MagicLinkService {
+    return &magicLinkService{
+        repo:        repo,
+        userService: userService,
+        mailer:      mailer,
+        config:      config,
+        limiter:     newEmailRateLimiter(config.MaxPerWindow, config.Window),
+        audit:       audit,
+    }
+}
+
+// Request - Mengirim link login ke email. Pencarian user, penyimpanan link dan pengiriman email
+// berjalan di background, sehingga waktu response dan error mailer tidak membocorkan email mana
+// yang terdaftar. Hanya ErrMagicLinkRateLimited yang dikembalikan.
+func (s *magicLinkService) Request(ctx context.Context, email, nonce string, actor auditlog.Actor) error {
+    // Batas dihitung per email, termasuk email yang tidak terdaftar
+    if !s.limiter.Allow(strings.ToLower(strings.TrimSpace(email))) {
+        event := auditEvent(actor, AuditMagicLinkRequest, ErrMagicLinkRateLimited)
+        event.Outcome = auditlog.Denied
+        s.audit.Record(event)
+        return ErrMagicLinkRateLimited
+    }
+
+    // Request HTTP sudah selesai saat link dikirim, context-nya tidak boleh membatalkan pengiriman
+    sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), magicLinkSendTimeout)
+    s.pending.Add(1)
+    go func() {
+        defer s.pending.Done()
+        defer cancel()
+        s.send(sendCtx, email, nonce, actor)
+    }()
+    return nil
+}
+
+// Wait - Menunggu pengiriman link yang masih berjalan, dipanggil saat shutdown
+func (s *magicLinkService) Wait() {
+    s.pending.Wait()
+}
+
+// send - Membuat dan mengirim link jika email milik akun aktif, hasilnya hanya dicatat ke audit log
+func (s *magicLinkService) send(ctx context.Context, email, nonce string, actor auditlog.Actor) (err error) {
+    var user *models.User
+    defer func() {
+        if err != nil {
+            slog.Error("Failed to send magic link", "error", err)
+        }
+        event := auditEvent(actor, AuditMagicLinkRequest, err)
+        if user != nil {
+            event = event.On("user", user.ID)
+        } else if err == nil {
+            event = event.With("sent", false)
+        }
+        s.audit.Record(event)
+    }()
+
+    found, err := s.userService.GetUserByEmail(ctx, email)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil
+        }
+        return err
+    }
+    // Service account dan akun yang tidak aktif tidak dikirimi link, tanpa memberi tahu peminta
+    if found.Role == models.RoleService || accountStatusError(found) != nil {
+        return nil
+    }
+    user = found
+
+    link := &models.MagicLink{
+        UserID:    user.ID,
+        NonceHash: utils.HashToken(nonce),
+        RequestIP: actor.IPAddress,
+        ExpiresAt: time.Now().Add(s.config.Token.TTL),
+    }
+    if err := s.repo.CreateMagicLink(ctx, link); err != nil {
+        return err
+    }
+
+    token, err := s.config.Token.Issue(&jwtauth.Claims{
+        Username: user.Username,
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject: user.ID,
+            ID:      link.ID,
+        },
+    })
+    if err != nil {
+        return err
+    }
+
+    return s.mailer.Send(utils.Mail{
+        To:      user.Email,
+        Subject: "Your login link",
+        Body: "Hi " + user.Username + ",\n\n" +
+            "Open the link below in the same browser to log in. It expires in " + s.config.Token.TTL.String() + " and can only be used once.\n\n" +
+            appendToken(s.config.VerifyURL, token) + "\n\n" +
+            "If you did not request this, you can ignore this email.\n",
+    })
+}
+
+// Consume - Memeriksa signature, masa berlaku dan nonce browser, lalu menandai link sebagai terpakai
+func (s *magicLinkService) Consume(ctx context.Context, token, nonce string, actor auditlog.Actor) (authenticated *models.User, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditUserLogin, err).With("method", "magic_link")
+        if authenticated != nil {
+            event = event.On("user", authenticated.ID)
+            event.ActorID = authenticated.ID
+        }
+        s.audit.Record(event)
+        metrics.LoginAttempts.WithLabelValues("magic_link", metrics.Outcome(err)).Inc()
+    }()
+
+    claims, err := s.config.Token.Parse(token)
+    if err != nil || claims.ID == "" {
+        return nil, ErrMagicLinkInvalid
+    }
+    if nonce == "" {
+        return nil, ErrMagicLinkNonce
+    }
+
+    link, err := s.repo.ConsumeMagicLink(ctx, claims.ID)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, ErrMagicLinkInvalid
+        }
+        return nil, err
+    }
+    if link.UsedAt != nil {
+        return nil, ErrMagicLinkUsed
+    }
+    if link.UserID != claims.Subject || time.Now().After(link.ExpiresAt) {
+        return nil, ErrMagicLinkInvalid
+    }
+    // Link dari browser lain tetap hangus, agar link yang bocor tidak bisa dicoba ulang
+    if link.NonceHash != utils.HashToken(nonce) {
+        return nil, ErrMagicLinkNonce
+    }
+
+    user, err := s.userService.GetUserByID(ctx, link.UserID)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, ErrMagicLinkInvalid
+        }
+        return nil, err
+    }
+    if err := accountStatusError(user); err != nil {
+        return nil, err
+    }
+    return user, nil
+}
+
+func appendToken(verifyURL, token string) string {
+    separator := "?"
+    if strings.Contains(verifyURL, "?") {
+        separator = "&"
+    }
+    return verifyURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// emailRateLimiter membatasi jumlah request per key dalam sliding window, disimpan di memori
+type emailRateLimiter struct {
+    mu     sync.Mutex
+    max    int
+    window time.Duration
+    hits   map[string][]time.Time
+}
+
+func newEmailRateLimiter(max int, window time.Duration) *emailRateLimiter {
+    return &emailRateLimiter{max: max, window: window, hits: make(map[string][]time.Time)}
+}
+
+func (l *emailRateLimiter) Allow(key string) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    cutoff := now.Add(-l.window)
+
+    // Buang key yang semua request-nya sudah di luar window agar map tidak terus membesar
+    for k, times := range l.hits {
+        if len(times) > 0 && times[len(times)-1].Before(cutoff) {
+            delete(l.hits, k)
+        }
+    }
+
+    recent := l.hits[key][:0]
+    for _, t := range l.hits[key] {
+        if t.After(cutoff) {
+            recent = append(recent, t)
+        }
+    }
+    if len(recent) >= l.max {
+        l.hits[key] = recent
+        return false
+    }
+    l.hits[key] = append(recent, now)
+    return true
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+    "auditlog"
+    "jwtauth"
+
+    "gorm.io/gorm"
+)
+
+type fakeMagicLinkRepository struct {
+    repository.MagicLinkRepository
+
+    mu    sync.Mutex
+    links []*models.MagicLink
+}
+
+func (r *fakeMagicLinkRepository) CreateMagicLink(ctx context.Context, link *models.MagicLink) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    link.ID = "link-1"
+    r.links = append(r.links, link)
+    return nil
+}
+
+// fakeMailer mencatat email yang dikirim, err dikembalikan untuk mensimulasikan mailer yang gagal
+type fakeMailer struct {
+    mu   sync.Mutex
+    sent []utils.Mail
+    err  error
+}
+
+func (m *fakeMailer) Send(mail utils.Mail) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.err != nil {
+        return m.err
+    }
+    m.sent = append(m.sent, mail)
+    return nil
+}
+
+func (s *fakeUserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    for _, user := range s.users {
+        if user.Email == email {
+            found := *user
+            return &found, nil
+        }
+    }
+    return nil, gorm.ErrRecordNotFound
+}
+
+func TestMagicLinkRequest(t *testing.T) {
+    tests := []struct {
+        name      string
+        email     string
+        mailerErr error
+        wantSent  int
+    }{
+        {name: "email terdaftar", email: "budi@example.com", wantSent: 1},
+        {name: "email tidak terdaftar", email: "tidak-ada@example.com"},
+        {name: "akun tidak aktif", email: "suspended@example.com"},
+        {name: "mailer gagal", email: "budi@example.com", mailerErr: errors.New("smtp down")},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            users := &fakeUserService{users: map[string]*models.User{
+                "user-1": {ID: "user-1", Username: "budi", Email: "budi@example.com", Status: models.StatusActive},
+                "user-2": {ID: "user-2", Username: "susi", Email: "suspended@example.com", Status: models.StatusSuspended},
+            }}
+            mailer := &fakeMailer{err: tt.mailerErr}
+            config := utils.MagicLinkConfig{
+                VerifyURL:    "https://app.example.com/login/magic/verify",
+                Token:        jwtauth.TokenConfig{SigningKey: []byte("test-signing-key"), TTL: time.Minute},
+                MaxPerWindow: 1,
+                Window:       time.Hour,
+            }
+            service := NewMagicLinkService(&fakeMagicLinkRepository{}, users, mailer, config, nil)
+
+            // Hasilnya sama untuk semua email, agar email yang terdaftar tidak bisa ditebak
+            ctx, cancel := context.WithCancel(context.Background())
+            if err := service.Request(ctx, tt.email, "nonce", auditlog.Actor{}); err != nil {
+                t.Fatalf("Request error = %v, want nil", err)
+            }
+            cancel() // Request HTTP selesai sebelum link dikirim
+            service.Wait()
+
+            if len(mailer.sent) != tt.wantSent {
+                t.Errorf("mails sent = %d, want %d", len(mailer.sent), tt.wantSent)
+            }
+
+            // Batas per email juga berlaku untuk email yang tidak terdaftar
+            if err := service.Request(context.Background(), tt.email, "nonce", auditlog.Actor{}); err != ErrMagicLinkRateLimited {
+                t.Errorf("second Request error = %v, want %v", err, ErrMagicLinkRateLimited)
+            }
+        })
+    }
+}
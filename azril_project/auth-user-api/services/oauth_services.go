@@ -0,0 +1,583 @@
+package services
+
+import (
+    "context"
+    "crypto/subtle"
+    "errors"
+    "strings"
+    "time"
+
+    "auth-user-api/domains"
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+    "auditlog"
+    "i18n"
+
+    "github.com/golang-jwt/jwt/v4"
+    "gorm.io/gorm"
+    "jwtauth"
+    "metrics"
+)
+
+// SupportedScopes adalah scope yang boleh diberikan ke OAuth client beserta deskripsinya
+var SupportedScopes = map[string]string{
+    "openid":      "Sign you in with your account",
+    "profile":     "Read your username and role",
+    "email":       "Read your email address",
+    "sessions":    "View and revoke your active sessions",
+    "users:read":  "Read the list of users",
+    "users:write": "Create, update and delete users",
+    "admin":       "Manage users, clients and audit events as an administrator",
+}
+
+// ErrConsentNotFound dikembalikan jika user belum pernah memberi consent ke client
+var ErrConsentNotFound = i18n.NewError("consent.not_found")
+
+// OAuthError adalah error OAuth 2.0 dengan kode standar RFC 6749
+type OAuthError struct {
+    Code        string
+    Description string
+}
+
+func (e *OAuthError) Error() string {
+    return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+    return &OAuthError{Code: code, Description: description}
+}
+
+// AuthorizeRequest berisi parameter dari /oauth/authorize
+type AuthorizeRequest struct {
+    ResponseType        string
+    ClientID            string
+    RedirectURI         string // Seperti dikirim client, kosong jika client memakai satu-satunya redirect URI terdaftar
+    Scope               string
+    State               string
+    CodeChallenge       string
+    CodeChallengeMethod string
+    Nonce               string
+}
+
+type OAuthService interface {
+    RegisterClient(ctx context.Context, name string, redirectURIs, grantTypes, scopes []string, public bool, actor auditlog.Actor) (*models.OAuthClient, string, error)
+    GetAllClients(ctx context.Context) ([]*models.OAuthClient, error)
+    GetClient(ctx context.Context, id string) (*models.OAuthClient, error)
+    AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error)
+    ValidateClientRedirect(ctx context.Context, clientID, redirectURI string) (*models.OAuthClient, string, error)
+    ValidateAuthorizeRequest(client *models.OAuthClient, req AuthorizeRequest) (string, error)
+    Authorize(ctx context.Context, userID string, client *models.OAuthClient, req AuthorizeRequest, scope string) (string, error)
+    ExchangeCode(ctx context.Context, client *models.OAuthClient, code, redirectURI, codeVerifier, userAgent, ipAddress string) (*domains.OAuthTokenResponse, error)
+    Refresh(ctx context.Context, client *models.OAuthClient, refreshToken, scope string) (*domains.OAuthTokenResponse, error)
+    ClientCredentials(ctx context.Context, client *models.OAuthClient, scope string) (*domains.OAuthTokenResponse, error)
+    GetConsents(ctx context.Context, userID string) ([]*models.OAuthConsent, error)
+    RevokeConsent(ctx context.Context, userID, clientID string) error
+}
+
+type oauthService struct {
+    repo           repository.OAuthRepository
+    userService    UserService
+    sessionService SessionService
+    tokens         jwtauth.TokenConfig
+    config         utils.OAuthConfig
+    oidc           utils.OIDCConfig
+    audit          *auditlog.Logger
+}
+
+func NewOAuthService(repo repository.OAuthRepository, userService UserService, sessionService SessionService, tokens jwtauth.TokenConfig, config utils.OAuthConfig, oidc utils.OIDCConfig, audit *auditlog.Logger) OAuthService {
+    return &oauthService{
+        repo:           repo,
+        userService:    userService,
+        sessionService: sessionService,
+        tokens:         tokens,
+        config:         config,
+        oidc:           oidc,
+        audit:          audit,
+    }
+}
+
+// RegisterClient - Mendaftarkan client baru, secret hanya dikembalikan sekali
+func (s *oauthService) RegisterClient(ctx context.Context, name string, redirectURIs, grantTypes, scopes []string, public bool, actor auditlog.Actor) (registered *models.OAuthClient, secret string, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditOAuthClientCreate, err).With("name", name).With("grant_types", grantTypes)
+        if registered != nil {
+            event = event.On("oauth_client", registered.ID)
+        }
+        s.audit.Record(event)
+    }()
+
+    for _, grantType := range grantTypes {
+        switch grantType {
+        case models.GrantAuthorizationCode, models.GrantRefreshToken:
+        case models.GrantClientCredentials:
+            if public {
+                return nil, "", i18n.NewError("oauth.public_client_credentials")
+            }
+        default:
+            return nil, "", i18n.NewError("oauth.unsupported_grant_type", grantType)
+        }
+    }
+    if containsString(grantTypes, models.GrantAuthorizationCode) && len(redirectURIs) == 0 {
+        return nil, "", i18n.NewError("oauth.redirect_uri_required")
+    }
+    for _, scope := range scopes {
+        if _, ok := SupportedScopes[scope]; !ok {
+            return nil, "", i18n.NewError("scope.unsupported", scope)
+        }
+    }
+
+    client := &models.OAuthClient{
+        Name:         name,
+        RedirectURIs: strings.Join(redirectURIs, " "),
+        GrantTypes:   strings.Join(grantTypes, " "),
+        Scopes:       strings.Join(scopes, " "),
+        Public:       public,
+    }
+
+    clientSecret := ""
+    if !public {
+        clientSecret, err = utils.GenerateRandomToken()
+        if err != nil {
+            return nil, "", err
+        }
+        client.SecretHash = utils.HashToken(clientSecret)
+    }
+
+    if err := s.repo.CreateClient(ctx, client); err != nil {
+        return nil, "", err
+    }
+    return client, clientSecret, nil
+}
+
+// GetAllClients - Mengambil semua client terdaftar
+func (s *oauthService) GetAllClients(ctx context.Context) ([]*models.OAuthClient, error) {
+    return s.repo.GetAllClients(ctx)
+}
+
+// GetClient - Mengambil client berdasarkan client_id
+func (s *oauthService) GetClient(ctx context.Context, id string) (*models.OAuthClient, error) {
+    return s.repo.GetClientByID(ctx, id)
+}
+
+// AuthenticateClient - Autentikasi client pada token endpoint
+func (s *oauthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+    if clientID == "" {
+        return nil, oauthError("invalid_client", "client_id is required")
+    }
+
+    client, err := s.repo.GetClientByID(ctx, clientID)
+    if err != nil {
+        return nil, oauthError("invalid_client", "unknown client")
+    }
+
+    if client.Public {
+        if clientSecret != "" {
+            return nil, oauthError("invalid_client", "public clients must not send a secret")
+        }
+        return client, nil
+    }
+
+    hash := utils.HashToken(clientSecret)
+    if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+        return nil, oauthError("invalid_client", "invalid client credentials")
+    }
+    return client, nil
+}
+
+// ValidateClientRedirect - Memeriksa client_id dan redirect_uri. Error di sini tidak boleh di-redirect ke client.
+func (s *oauthService) ValidateClientRedirect(ctx context.Context, clientID, redirectURI string) (*models.OAuthClient, string, error) {
+    client, err := s.repo.GetClientByID(ctx, clientID)
+    if err != nil {
+        return nil, "", oauthError("invalid_client", "unknown client")
+    }
+
+    registered := strings.Fields(client.RedirectURIs)
+    if redirectURI == "" {
+        if len(registered) != 1 {
+            return nil, "", oauthError("invalid_request", "redirect_uri is required")
+        }
+        return client, registered[0], nil
+    }
+    if !containsString(registered, redirectURI) {
+        return nil, "", oauthError("invalid_request", "redirect_uri is not registered for this client")
+    }
+    return client, redirectURI, nil
+}
+
+// ValidateAuthorizeRequest - Memeriksa response_type, scope dan PKCE, lalu mengembalikan scope yang diberikan
+func (s *oauthService) ValidateAuthorizeRequest(client *models.OAuthClient, req AuthorizeRequest) (string, error) {
+    if req.ResponseType != "code" {
+        return "", oauthError("unsupported_response_type", "only response_type=code is supported")
+    }
+    if !containsString(strings.Fields(client.GrantTypes), models.GrantAuthorizationCode) {
+        return "", oauthError("unauthorized_client", "client may not use the authorization_code grant")
+    }
+
+    if req.CodeChallenge == "" {
+        if client.Public {
+            return "", oauthError("invalid_request", "code_challenge is required for public clients")
+        }
+    } else if req.CodeChallengeMethod != "S256" {
+        return "", oauthError("invalid_request", "code_challenge_method must be S256")
+    }
+
+    return resolveScope(client, req.Scope)
+}
+
+// Authorize - Menyimpan consent user lalu menerbitkan authorization code
+func (s *oauthService) Authorize(ctx context.Context, userID string, client *models.OAuthClient, req AuthorizeRequest, scope string) (string, error) {
+    granted := strings.Fields(scope)
+    if existing, err := s.repo.GetConsent(ctx, userID, client.ID); err == nil {
+        granted = unionScopes(strings.Fields(existing.Scope), granted)
+    }
+    if err := s.repo.UpsertConsent(ctx, &models.OAuthConsent{
+        UserID:   userID,
+        ClientID: client.ID,
+        Scope:    strings.Join(granted, " "),
+    }); err != nil {
+        return "", err
+    }
+
+    code, err := utils.GenerateRandomToken()
+    if err != nil {
+        return "", err
+    }
+
+    err = s.repo.CreateAuthorizationCode(ctx, &models.OAuthAuthorizationCode{
+        CodeHash:            utils.HashToken(code),
+        ClientID:            client.ID,
+        UserID:              userID,
+        RedirectURI:         req.RedirectURI,
+        Scope:               scope,
+        CodeChallenge:       req.CodeChallenge,
+        CodeChallengeMethod: req.CodeChallengeMethod,
+        Nonce:               req.Nonce,
+        ExpiresAt:           time.Now().Add(s.config.CodeTTL),
+    })
+    if err != nil {
+        return "", err
+    }
+    return code, nil
+}
+
+// ExchangeCode - Grant authorization_code
+func (s *oauthService) ExchangeCode(ctx context.Context, client *models.OAuthClient, code, redirectURI, codeVerifier, userAgent, ipAddress string) (*domains.OAuthTokenResponse, error) {
+    if code == "" {
+        return nil, oauthError("invalid_request", "code is required")
+    }
+    if !containsString(strings.Fields(client.GrantTypes), models.GrantAuthorizationCode) {
+        return nil, oauthError("unauthorized_client", "client may not use the authorization_code grant")
+    }
+
+    codeHash := utils.HashToken(code)
+    authCode, err := s.repo.ConsumeAuthorizationCode(ctx, codeHash, client.ID)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, oauthError("invalid_grant", "invalid authorization code")
+        }
+        return nil, err
+    }
+
+    if authCode.ClientID != client.ID {
+        return nil, oauthError("invalid_grant", "authorization code was issued to another client")
+    }
+    if authCode.UsedAt != nil {
+        // Code dipakai ulang, cabut token yang sudah diterbitkan dari code ini (RFC 6749 section 4.1.2).
+        // Session ikut dicabut agar access token yang sudah diterbitkan juga ditolak.
+        if err := s.repo.RevokeRefreshTokensByUserAndClient(ctx, authCode.UserID, client.ID); err != nil {
+            return nil, err
+        }
+        if authCode.SessionID != nil {
+            if err := revokeGrant(ctx, s.repo, s.sessionService, authCode.UserID, *authCode.SessionID); err != nil {
+                return nil, err
+            }
+        }
+        return nil, oauthError("invalid_grant", "authorization code has already been used")
+    }
+    if time.Now().After(authCode.ExpiresAt) {
+        return nil, oauthError("invalid_grant", "authorization code has expired")
+    }
+    // redirect_uri hanya wajib sama jika dikirim saat authorize (RFC 6749 section 4.1.3)
+    if authCode.RedirectURI != "" && authCode.RedirectURI != redirectURI {
+        return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+    }
+    if authCode.CodeChallenge != "" {
+        if codeVerifier == "" || !utils.VerifyPKCE(codeVerifier, authCode.CodeChallenge) {
+            return nil, oauthError("invalid_grant", "invalid code_verifier")
+        }
+    }
+
+    user, err := s.userService.GetUserByID(ctx, authCode.UserID)
+    if err != nil {
+        return nil, oauthError("invalid_grant", "user no longer exists")
+    }
+    if err := accountStatusError(user); err != nil {
+        return nil, oauthError("invalid_grant", err.Error())
+    }
+
+    session, err := s.sessionService.Create(ctx, user.ID, userAgent, ipAddress)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.repo.SetAuthorizationCodeSession(ctx, codeHash, session.ID); err != nil {
+        return nil, err
+    }
+
+    return s.issueUserTokens(ctx, user, client, session.ID, authCode.Scope, authCode.Scope, authCode.Nonce, authCode.CreatedAt)
+}
+
+// Refresh - Grant refresh_token dengan rotasi refresh token
+func (s *oauthService) Refresh(ctx context.Context, client *models.OAuthClient, refreshToken, scope string) (*domains.OAuthTokenResponse, error) {
+    if refreshToken == "" {
+        return nil, oauthError("invalid_request", "refresh_token is required")
+    }
+    if !containsString(strings.Fields(client.GrantTypes), models.GrantRefreshToken) {
+        return nil, oauthError("unauthorized_client", "client may not use the refresh_token grant")
+    }
+
+    token, err := s.repo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+    if err != nil {
+        if isRecordNotFound(err) {
+            return nil, oauthError("invalid_grant", "invalid refresh token")
+        }
+        return nil, err
+    }
+    if token.ClientID != client.ID {
+        return nil, oauthError("invalid_grant", "refresh token was issued to another client")
+    }
+    if token.RevokedAt != nil {
+        // Refresh token lama dipakai ulang, kemungkinan bocor: cabut seluruh session
+        if err := revokeGrant(ctx, s.repo, s.sessionService, token.UserID, token.SessionID); err != nil {
+            return nil, err
+        }
+        return nil, oauthError("invalid_grant", "refresh token has been revoked")
+    }
+    if time.Now().After(token.ExpiresAt) {
+        return nil, oauthError("invalid_grant", "refresh token has expired")
+    }
+
+    active, err := s.sessionService.IsActive(ctx, token.UserID, token.SessionID)
+    if err != nil {
+        return nil, err
+    }
+    if !active {
+        return nil, oauthError("invalid_grant", "session has been revoked")
+    }
+
+    user, err := s.userService.GetUserByID(ctx, token.UserID)
+    if err != nil || user.TokenVersion != token.TokenVersion {
+        return nil, oauthError("invalid_grant", "refresh token has been revoked")
+    }
+    if err := accountStatusError(user); err != nil {
+        return nil, oauthError("invalid_grant", err.Error())
+    }
+
+    accessScope := token.Scope
+    if scope != "" {
+        for _, requested := range strings.Fields(scope) {
+            if !containsString(strings.Fields(token.Scope), requested) {
+                return nil, oauthError("invalid_scope", "scope exceeds the original grant: "+requested)
+            }
+        }
+        accessScope = scope
+    }
+
+    // Token lama dicabut dulu secara atomik, request lain yang memakai token yang sama diperlakukan
+    // seperti refresh token yang dipakai ulang
+    revoked, err := s.repo.RevokeRefreshToken(ctx, token.ID)
+    if err != nil {
+        return nil, err
+    }
+    if !revoked {
+        if err := revokeGrant(ctx, s.repo, s.sessionService, token.UserID, token.SessionID); err != nil {
+            return nil, err
+        }
+        return nil, oauthError("invalid_grant", "refresh token has been revoked")
+    }
+    return s.issueUserTokens(ctx, user, client, token.SessionID, token.Scope, accessScope, "", time.Time{})
+}
+
+// ClientCredentials - Grant client_credentials, token diterbitkan atas nama client itu sendiri
+func (s *oauthService) ClientCredentials(ctx context.Context, client *models.OAuthClient, scope string) (*domains.OAuthTokenResponse, error) {
+    if client.Public || !containsString(strings.Fields(client.GrantTypes), models.GrantClientCredentials) {
+        return nil, oauthError("unauthorized_client", "client may not use the client_credentials grant")
+    }
+
+    granted, err := resolveScope(client, scope)
+    if err != nil {
+        return nil, err
+    }
+
+    accessToken, err := s.accessTokenConfig().Issue(&jwtauth.Claims{
+        Scope:    granted,
+        ClientID: client.ID,
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject: client.ID,
+        },
+    })
+    if err != nil {
+        return nil, err
+    }
+    metrics.TokensIssued.WithLabelValues("access").Inc()
+
+    return &domains.OAuthTokenResponse{
+        AccessToken: accessToken,
+        TokenType:   "Bearer",
+        ExpiresIn:   int(s.config.AccessTokenTTL.Seconds()),
+        Scope:       granted,
+    }, nil
+}
+
+// GetConsents - Mengambil consent yang pernah diberikan user
+func (s *oauthService) GetConsents(ctx context.Context, userID string) ([]*models.OAuthConsent, error) {
+    return s.repo.GetConsentsByUserID(ctx, userID)
+}
+
+// RevokeConsent - Menghapus consent dan mencabut refresh token client tersebut
+func (s *oauthService) RevokeConsent(ctx context.Context, userID, clientID string) error {
+    if _, err := s.repo.GetConsent(ctx, userID, clientID); err != nil {
+        if isRecordNotFound(err) {
+            return ErrConsentNotFound
+        }
+        return err
+    }
+    if err := s.repo.RevokeRefreshTokensByUserAndClient(ctx, userID, clientID); err != nil {
+        return err
+    }
+    return s.repo.DeleteConsent(ctx, userID, clientID)
+}
+
+func (s *oauthService) issueUserTokens(ctx context.Context, user *models.User, client *models.OAuthClient, sessionID, grantScope, accessScope, nonce string, authTime time.Time) (*domains.OAuthTokenResponse, error) {
+    accessToken, err := s.accessTokenConfig().Issue(&jwtauth.Claims{
+        Username:     user.Username,
+        Roles:        []string{user.Role},
+        TokenVersion: user.TokenVersion,
+        SessionID:    sessionID,
+        Scope:        accessScope,
+        ClientID:     client.ID,
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject: user.ID,
+        },
+    })
+    if err != nil {
+        return nil, err
+    }
+    metrics.TokensIssued.WithLabelValues("access").Inc()
+
+    response := &domains.OAuthTokenResponse{
+        AccessToken: accessToken,
+        TokenType:   "Bearer",
+        ExpiresIn:   int(s.config.AccessTokenTTL.Seconds()),
+        Scope:       accessScope,
+    }
+
+    if containsString(strings.Fields(accessScope), "openid") {
+        idToken, err := s.issueIDToken(user, client, accessScope, accessToken, nonce, authTime)
+        if err != nil {
+            return nil, err
+        }
+        response.IDToken = idToken
+        metrics.TokensIssued.WithLabelValues("id").Inc()
+    }
+
+    if containsString(strings.Fields(client.GrantTypes), models.GrantRefreshToken) {
+        refreshToken, err := utils.GenerateRandomToken()
+        if err != nil {
+            return nil, err
+        }
+        err = s.repo.CreateRefreshToken(ctx, &models.OAuthRefreshToken{
+            TokenHash:    utils.HashToken(refreshToken),
+            ClientID:     client.ID,
+            UserID:       user.ID,
+            SessionID:    sessionID,
+            Scope:        grantScope,
+            TokenVersion: user.TokenVersion,
+            ExpiresAt:    time.Now().Add(s.config.RefreshTokenTTL),
+        })
+        if err != nil {
+            return nil, err
+        }
+        response.RefreshToken = refreshToken
+        metrics.TokensIssued.WithLabelValues("refresh").Inc()
+    }
+
+    return response, nil
+}
+
+// issueIDToken membuat id_token OpenID Connect, claim profil mengikuti scope yang diberikan
+func (s *oauthService) issueIDToken(user *models.User, client *models.OAuthClient, scope, accessToken, nonce string, authTime time.Time) (string, error) {
+    now := time.Now()
+    claims := &domains.IDTokenClaims{
+        Nonce:  nonce,
+        AtHash: utils.AccessTokenHash(accessToken),
+        RegisteredClaims: jwt.RegisteredClaims{
+            Issuer:    s.oidc.Issuer,
+            Subject:   user.ID,
+            Audience:  jwt.ClaimStrings{client.ID},
+            IssuedAt:  jwt.NewNumericDate(now),
+            ExpiresAt: jwt.NewNumericDate(now.Add(s.oidc.IDTokenTTL)),
+        },
+    }
+    if !authTime.IsZero() {
+        claims.AuthTime = authTime.Unix()
+    }
+
+    scopes := strings.Fields(scope)
+    if containsString(scopes, "email") {
+        emailVerified := user.EmailVerified
+        claims.Email = user.Email
+        claims.EmailVerified = &emailVerified
+    }
+    if containsString(scopes, "profile") {
+        claims.PreferredUsername = user.Username
+    }
+
+    return s.oidc.Sign(claims)
+}
+
+// accessTokenConfig memakai konfigurasi JWT yang sama dengan /login, dengan masa berlaku OAuth
+func (s *oauthService) accessTokenConfig() jwtauth.TokenConfig {
+    tokens := s.tokens
+    tokens.TTL = s.config.AccessTokenTTL
+    return tokens
+}
+
+// resolveScope memakai semua scope client jika tidak diminta, atau memastikan scope yang diminta diizinkan
+func resolveScope(client *models.OAuthClient, requested string) (string, error) {
+    allowed := strings.Fields(client.Scopes)
+    if strings.TrimSpace(requested) == "" {
+        return strings.Join(allowed, " "), nil
+    }
+
+    scopes := strings.Fields(requested)
+    for _, scope := range scopes {
+        if !containsString(allowed, scope) {
+            return "", oauthError("invalid_scope", "scope is not allowed for this client: "+scope)
+        }
+    }
+    return strings.Join(scopes, " "), nil
+}
+
+func unionScopes(a, b []string) []string {
+    result := append([]string{}, a...)
+    for _, scope := range b {
+        if !containsString(result, scope) {
+            result = append(result, scope)
+        }
+    }
+    return result
+}
+
+func containsString(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+func isRecordNotFound(err error) bool {
+    return errors.Is(err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,474 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+    "jwtauth"
+
+    "gorm.io/gorm"
+)
+
+// fakeOAuthRepository menyimpan code dan refresh token di memori. Interface di-embed agar hanya
+// method yang dipakai ExchangeCode dan Refresh yang perlu diimplementasikan.
+type fakeOAuthRepository struct {
+    repository.OAuthRepository
+
+    mu            sync.Mutex
+    codes         map[string]*models.OAuthAuthorizationCode
+    refreshTokens map[string]*models.OAuthRefreshToken // Key berupa hash token
+    nextID        int
+    revokeOnRead  bool // Mensimulasikan request lain yang mencabut token tepat setelah token dibaca
+}
+
+func newFakeOAuthRepository() *fakeOAuthRepository {
+    return &fakeOAuthRepository{
+        codes:         make(map[string]*models.OAuthAuthorizationCode),
+        refreshTokens: make(map[string]*models.OAuthRefreshToken),
+    }
+}
+
+func (r *fakeOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash, clientID string) (*models.OAuthAuthorizationCode, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    code, ok := r.codes[codeHash]
+    if !ok {
+        return nil, gorm.ErrRecordNotFound
+    }
+    // Sama dengan repository asli: yang dikembalikan adalah kondisi sebelum code ditandai terpakai
+    consumed := *code
+    if code.UsedAt == nil && code.ClientID == clientID {
+        now := time.Now()
+        code.UsedAt = &now
+    }
+    return &consumed, nil
+}
+
+func (r *fakeOAuthRepository) SetAuthorizationCodeSession(ctx context.Context, codeHash, sessionID string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.codes[codeHash].SessionID = &sessionID
+    return nil
+}
+
+func (r *fakeOAuthRepository) CreateRefreshToken(ctx context.Context, token *models.OAuthRefreshToken) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.nextID++
+    token.ID = strconv.Itoa(r.nextID)
+    stored := *token
+    r.refreshTokens[token.TokenHash] = &stored
+    return nil
+}
+
+func (r *fakeOAuthRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.OAuthRefreshToken, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    token, ok := r.refreshTokens[tokenHash]
+    if !ok {
+        return nil, gorm.ErrRecordNotFound
+    }
+    found := *token
+    if r.revokeOnRead && token.RevokedAt == nil {
+        now := time.Now()
+        token.RevokedAt = &now
+    }
+    return &found, nil
+}
+
+func (r *fakeOAuthRepository) RevokeRefreshToken(ctx context.Context, id string) (bool, error) {
+    return r.revokeWhere(func(token *models.OAuthRefreshToken) bool { return token.ID == id }) == 1, nil
+}
+
+func (r *fakeOAuthRepository) RevokeRefreshTokensBySessionID(ctx context.Context, sessionID string) error {
+    r.revokeWhere(func(token *models.OAuthRefreshToken) bool { return token.SessionID == sessionID })
+    return nil
+}
+
+func (r *fakeOAuthRepository) RevokeRefreshTokensByUserAndClient(ctx context.Context, userID, clientID string) error {
+    r.revokeWhere(func(token *models.OAuthRefreshToken) bool { return token.UserID == userID && token.ClientID == clientID })
+    return nil
+}
+
+// revokeWhere mencabut token aktif yang cocok dan mengembalikan jumlahnya, seperti RowsAffected
+func (r *fakeOAuthRepository) revokeWhere(match func(*models.OAuthRefreshToken) bool) int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    revoked := 0
+    now := time.Now()
+    for _, token := range r.refreshTokens {
+        if token.RevokedAt == nil && match(token) {
+            token.RevokedAt = &now
+            revoked++
+        }
+    }
+    return revoked
+}
+
+func (r *fakeOAuthRepository) activeRefreshTokens() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    active := 0
+    for _, token := range r.refreshTokens {
+        if token.RevokedAt == nil {
+            active++
+        }
+    }
+    return active
+}
+
+type fakeUserService struct {
+    UserService
+    users map[string]*models.User
+}
+
+func (s *fakeUserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+    user, ok := s.users[id]
+    if !ok {
+        return nil, gorm.ErrRecordNotFound
+    }
+    found := *user
+    return &found, nil
+}
+
+type fakeSessionService struct {
+    SessionService
+
+    mu      sync.Mutex
+    active  map[string]bool
+    revoked []string
+}
+
+func (s *fakeSessionService) Create(ctx context.Context, userID, userAgent, ipAddress string) (*models.Session, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    session := &models.Session{ID: "session-" + strconv.Itoa(len(s.active)+1), UserID: userID}
+    s.active[session.ID] = true
+    return session, nil
+}
+
+func (s *fakeSessionService) IsActive(ctx context.Context, userID, sessionID string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.active[sessionID], nil
+}
+
+func (s *fakeSessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if !s.active[sessionID] {
+        return ErrSessionNotFound
+    }
+    s.active[sessionID] = false
+    s.revoked = append(s.revoked, sessionID)
+    return nil
+}
+
+const (
+    testClientID    = "client-1"
+    testUserID      = "user-1"
+    testRedirectURI = "https://app.example.com/callback"
+    testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+    testChallenge   = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+type oauthTestEnv struct {
+    service  OAuthService
+    repo     *fakeOAuthRepository
+    users    *fakeUserService
+    sessions *fakeSessionService
+    client   *models.OAuthClient
+}
+
+func newOAuthTestEnv() *oauthTestEnv {
+    env := &oauthTestEnv{
+        repo: newFakeOAuthRepository(),
+        users: &fakeUserService{users: map[string]*models.User{
+            testUserID: {ID: testUserID, Username: "budi", Role: models.RoleUser, Status: models.StatusActive},
+        }},
+        sessions: &fakeSessionService{active: make(map[string]bool)},
+        client: &models.OAuthClient{
+            ID:           testClientID,
+            RedirectURIs: testRedirectURI,
+            GrantTypes:   models.GrantAuthorizationCode + " " + models.GrantRefreshToken,
+            Scopes:       "profile sessions",
+        },
+    }
+    tokens := jwtauth.TokenConfig{SigningKey: []byte("test-signing-key"), TTL: time.Hour}
+    config := utils.OAuthConfig{AccessTokenTTL: time.Hour, RefreshTokenTTL: 24 * time.Hour, CodeTTL: time.Minute}
+    env.service = NewOAuthService(env.repo, env.users, env.sessions, tokens, config, utils.OIDCConfig{}, nil)
+    return env
+}
+
+// addCode menyimpan authorization code langsung ke repository, modify dipakai untuk mengubah kondisinya
+func (env *oauthTestEnv) addCode(code string, modify func(*models.OAuthAuthorizationCode)) {
+    authCode := &models.OAuthAuthorizationCode{
+        CodeHash:            utils.HashToken(code),
+        ClientID:            testClientID,
+        UserID:              testUserID,
+        RedirectURI:         testRedirectURI,
+        Scope:               "profile",
+        CodeChallenge:       testChallenge,
+        CodeChallengeMethod: "S256",
+        ExpiresAt:           time.Now().Add(time.Minute),
+        CreatedAt:           time.Now(),
+    }
+    if modify != nil {
+        modify(authCode)
+    }
+    env.repo.codes[authCode.CodeHash] = authCode
+}
+
+func oauthErrorCode(err error) string {
+    var oauthErr *OAuthError
+    if errors.As(err, &oauthErr) {
+        return oauthErr.Code
+    }
+    if err != nil {
+        return err.Error()
+    }
+    return ""
+}
+
+func TestExchangeCode(t *testing.T) {
+    tests := []struct {
+        name         string
+        modify       func(*models.OAuthAuthorizationCode)
+        redirectURI  string
+        codeVerifier string
+        wantErr      string
+    }{
+        {name: "code valid dengan PKCE", redirectURI: testRedirectURI, codeVerifier: testVerifier},
+        {name: "code_verifier salah", redirectURI: testRedirectURI, codeVerifier: "salah", wantErr: "invalid_grant"},
+        {name: "code_verifier tidak dikirim", redirectURI: testRedirectURI, wantErr: "invalid_grant"},
+        {name: "redirect_uri berbeda", redirectURI: "https://evil.example.com/callback", codeVerifier: testVerifier, wantErr: "invalid_grant"},
+        {
+            name:         "redirect_uri boleh kosong jika tidak dikirim saat authorize",
+            modify:       func(c *models.OAuthAuthorizationCode) { c.RedirectURI = "" },
+            codeVerifier: testVerifier,
+        },
+        {
+            name:         "redirect_uri wajib jika dikirim saat authorize",
+            codeVerifier: testVerifier,
+            wantErr:      "invalid_grant",
+        },
+        {
+            name:        "tanpa PKCE jika code tidak punya challenge",
+            modify:      func(c *models.OAuthAuthorizationCode) { c.CodeChallenge = ""; c.CodeChallengeMethod = "" },
+            redirectURI: testRedirectURI,
+        },
+        {
+            name:         "code kedaluwarsa",
+            modify:       func(c *models.OAuthAuthorizationCode) { c.ExpiresAt = time.Now().Add(-time.Second) },
+            redirectURI:  testRedirectURI,
+            codeVerifier: testVerifier,
+            wantErr:      "invalid_grant",
+        },
+        {
+            name:         "code milik client lain",
+            modify:       func(c *models.OAuthAuthorizationCode) { c.ClientID = "client-2" },
+            redirectURI:  testRedirectURI,
+            codeVerifier: testVerifier,
+            wantErr:      "invalid_grant",
+        },
+        {
+            name:         "user tidak aktif",
+            modify:       func(c *models.OAuthAuthorizationCode) { c.UserID = "user-suspended" },
+            redirectURI:  testRedirectURI,
+            codeVerifier: testVerifier,
+            wantErr:      "invalid_grant",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            env := newOAuthTestEnv()
+            env.users.users["user-suspended"] = &models.User{ID: "user-suspended", Status: models.StatusSuspended}
+            env.addCode("code-1", tt.modify)
+
+            response, err := env.service.ExchangeCode(context.Background(), env.client, "code-1", tt.redirectURI, tt.codeVerifier, "test", "127.0.0.1")
+            if got := oauthErrorCode(err); got != tt.wantErr {
+                t.Fatalf("ExchangeCode error = %q, want %q", got, tt.wantErr)
+            }
+            if tt.wantErr != "" {
+                return
+            }
+            if response.AccessToken == "" || response.RefreshToken == "" {
+                t.Fatalf("ExchangeCode response missing tokens: %+v", response)
+            }
+            if response.Scope != "profile" {
+                t.Errorf("scope = %q, want %q", response.Scope, "profile")
+            }
+        })
+    }
+}
+
+func TestExchangeCodeReuseRevokesTokens(t *testing.T) {
+    env := newOAuthTestEnv()
+    env.addCode("code-1", nil)
+    ctx := context.Background()
+
+    if _, err := env.service.ExchangeCode(ctx, env.client, "code-1", testRedirectURI, testVerifier, "test", "127.0.0.1"); err != nil {
+        t.Fatalf("first exchange: %v", err)
+    }
+    if active := env.repo.activeRefreshTokens(); active != 1 {
+        t.Fatalf("active refresh tokens = %d, want 1", active)
+    }
+
+    _, err := env.service.ExchangeCode(ctx, env.client, "code-1", testRedirectURI, testVerifier, "test", "127.0.0.1")
+    if got := oauthErrorCode(err); got != "invalid_grant" {
+        t.Fatalf("second exchange error = %q, want invalid_grant", got)
+    }
+    // Token yang diterbitkan dari code yang dipakai ulang ikut dicabut (RFC 6749 section 4.1.2)
+    if active := env.repo.activeRefreshTokens(); active != 0 {
+        t.Errorf("active refresh tokens after reuse = %d, want 0", active)
+    }
+    // Session juga dicabut, sehingga access token yang sudah diterbitkan ikut ditolak
+    if active, _ := env.sessions.IsActive(ctx, testUserID, "session-1"); active {
+        t.Error("session of the first exchange should have been revoked")
+    }
+}
+
+func TestExchangeCodeWrongClientKeepsCode(t *testing.T) {
+    env := newOAuthTestEnv()
+    env.addCode("code-1", nil)
+    ctx := context.Background()
+
+    other := *env.client
+    other.ID = "client-2"
+    _, err := env.service.ExchangeCode(ctx, &other, "code-1", testRedirectURI, testVerifier, "test", "127.0.0.1")
+    if got := oauthErrorCode(err); got != "invalid_grant" {
+        t.Fatalf("exchange by another client error = %q, want invalid_grant", got)
+    }
+
+    // Client yang salah tidak boleh menghanguskan code milik client yang benar
+    if _, err := env.service.ExchangeCode(ctx, env.client, "code-1", testRedirectURI, testVerifier, "test", "127.0.0.1"); err != nil {
+        t.Fatalf("exchange by the right client: %v", err)
+    }
+}
+
+func TestRefreshRotation(t *testing.T) {
+    tests := []struct {
+        name string
+        // run menjalankan skenario dengan refresh token hasil ExchangeCode
+        run         func(t *testing.T, env *oauthTestEnv, refreshToken string) error
+        wantErr     string
+        wantActive  int  // Jumlah refresh token yang masih aktif di akhir skenario
+        wantRevoked bool // Session ikut dicabut
+    }{
+        {
+            name: "refresh menerbitkan token baru dan mencabut token lama",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                response, err := env.service.Refresh(context.Background(), env.client, refreshToken, "")
+                if err == nil && (response.RefreshToken == "" || response.RefreshToken == refreshToken) {
+                    t.Errorf("refresh token was not rotated: %+v", response)
+                }
+                return err
+            },
+            wantActive: 1,
+        },
+        {
+            name: "token lama dipakai ulang mencabut seluruh session",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                if _, err := env.service.Refresh(context.Background(), env.client, refreshToken, ""); err != nil {
+                    t.Fatalf("first refresh: %v", err)
+                }
+                _, err := env.service.Refresh(context.Background(), env.client, refreshToken, "")
+                return err
+            },
+            wantErr:     "invalid_grant",
+            wantActive:  0,
+            wantRevoked: true,
+        },
+        {
+            name: "request bersamaan dengan token yang sama hanya satu yang berhasil",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                // Request lain mencabut token setelah dibaca, RevokeRefreshToken tidak mengubah baris apa pun
+                env.repo.revokeOnRead = true
+                _, err := env.service.Refresh(context.Background(), env.client, refreshToken, "")
+                return err
+            },
+            wantErr:     "invalid_grant",
+            wantActive:  0,
+            wantRevoked: true,
+        },
+        {
+            name: "scope melebihi grant awal",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                _, err := env.service.Refresh(context.Background(), env.client, refreshToken, "profile sessions")
+                return err
+            },
+            wantErr:    "invalid_scope",
+            wantActive: 1,
+        },
+        {
+            name: "session sudah dicabut",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                env.sessions.Revoke(context.Background(), testUserID, "session-1")
+                _, err := env.service.Refresh(context.Background(), env.client, refreshToken, "")
+                return err
+            },
+            wantErr:     "invalid_grant",
+            wantActive:  1,
+            wantRevoked: true,
+        },
+        {
+            name: "token version user berubah",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                env.users.users[testUserID].TokenVersion++
+                _, err := env.service.Refresh(context.Background(), env.client, refreshToken, "")
+                return err
+            },
+            wantErr:    "invalid_grant",
+            wantActive: 1,
+        },
+        {
+            name: "client lain",
+            run: func(t *testing.T, env *oauthTestEnv, refreshToken string) error {
+                other := *env.client
+                other.ID = "client-2"
+                _, err := env.service.Refresh(context.Background(), &other, refreshToken, "")
+                return err
+            },
+            wantErr:    "invalid_grant",
+            wantActive: 1,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            env := newOAuthTestEnv()
+            env.addCode("code-1", nil)
+            response, err := env.service.ExchangeCode(context.Background(), env.client, "code-1", testRedirectURI, testVerifier, "test", "127.0.0.1")
+            if err != nil {
+                t.Fatalf("ExchangeCode: %v", err)
+            }
+
+            err = tt.run(t, env, response.RefreshToken)
+            if got := oauthErrorCode(err); got != tt.wantErr {
+                t.Fatalf("Refresh error = %q, want %q", got, tt.wantErr)
+            }
+            if active := env.repo.activeRefreshTokens(); active != tt.wantActive {
+                t.Errorf("active refresh tokens = %d, want %d", active, tt.wantActive)
+            }
+            if revoked := len(env.sessions.revoked) > 0; revoked != tt.wantRevoked {
+                t.Errorf("session revoked = %v, want %v", revoked, tt.wantRevoked)
+            }
+        })
+    }
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+    "context"
+    "log/slog"
+    "strings"
+    "sync"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "i18n"
+)
+
+// ErrSessionNotFound dikembalikan jika session tidak ada atau milik user lain
+var ErrSessionNotFound = i18n.NewError("session.not_found")
+
+type SessionService interface {
+    Create(ctx context.Context, userID, userAgent, ipAddress string) (*models.Session, error)
+    ListActive(ctx context.Context, userID string) ([]*models.Session, error)
+    Revoke(ctx context.Context, userID, sessionID string) error
+    RevokeAll(ctx context.Context, userID string) error
+    IsActive(ctx context.Context, userID, sessionID string) (bool, error)
+    Touch(sessionID string)
+    Flush(ctx context.Context) error
+    StartLastSeenFlusher(interval time.Duration) (stop func())
+}
+
+type sessionService struct {
+    repo  repository.SessionRepository
+    cache *Cache[models.Session] // Status session untuk IsActive, diinvalidasi oleh Revoke dan RevokeAll
+
+    mu       sync.Mutex
+    lastSeen map[string]time.Time // Last-seen yang belum ditulis ke database
+}
+
+func NewSessionService(repo repository.SessionRepository, cache *Cache[models.Session]) SessionService {
+    return &sessionService{repo: repo, cache: cache, lastSeen: make(map[string]time.Time)}
+}
+
+// Create - Membuat session baru untuk login dari sebuah device
+func (s *sessionService) Create(ctx context.Context, userID, userAgent, ipAddress string) (*models.Session, error) {
+    now := time.Now()
+    session := &models.Session{
+        UserID:      userID,
+        DeviceLabel: deviceLabel(userAgent),
+        UserAgent:   userAgent,
+        IPAddress:   ipAddress,
+        CreatedAt:   now,
+        LastSeenAt:  now,
+    }
+    if err := s.repo.CreateSession(ctx, session); err != nil {
+        return nil, err
+    }
+    return session, nil
+}
+
+// ListActive - Mengambil session aktif milik user, termasuk last-seen yang belum di-flush
+func (s *sessionService) ListActive(ctx context.Context, userID string) ([]*models.Session, error) {
+    sessions, err := s.repo.GetActiveSessionsByUserID(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, session := range sessions {
+        if seenAt, ok := s.lastSeen[session.ID]; ok && seenAt.After(session.LastSeenAt) {
+            session.LastSeenAt = seenAt
+        }
+    }
+    return sessions, nil
+}
+
+// Revoke - Mencabut session milik user tertentu
+func (s *sessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+    session, err := s.repo.GetSessionByID(ctx, sessionID)
+    if err != nil || session.UserID != userID {
+        return ErrSessionNotFound
+    }
+    if session.RevokedAt != nil {
+        return nil
+    }
+    if err := s.repo.RevokeSession(ctx, sessionID); err != nil {
+        return err
+    }
+    // Diinvalidasi setelah update agar pembacaan yang bersamaan tidak menyimpan status lama
+    s.cache.Invalidate(sessionID)
+    return nil
+}
+
+// RevokeAll - Mencabut semua session milik user
+func (s *sessionService) RevokeAll(ctx context.Context, userID string) error {
+    if err := s.repo.RevokeSessionsByUserID(ctx, userID); err != nil {
+        return err
+    }
+    s.cache.InvalidateFunc(func(session *models.Session) bool { return session.UserID == userID })
+    return nil
+}
+
+// IsActive - Mengecek apakah session milik user dan belum dicabut
+func (s *sessionService) IsActive(ctx context.Context, userID, sessionID string) (bool, error) {
+    if session, ok := s.cache.Get(sessionID); ok {
+        return session.UserID == userID && session.RevokedAt == nil, nil
+    }
+
+    generation := s.cache.Generation()
+    session, err := s.repo.GetSessionByID(ctx, sessionID)
+    if err != nil {
+        if isRecordNotFound(err) {
+            return false, nil
+        }
+        return false, err
+    }
+    s.cache.Set(sessionID, session, generation)
+    return session.UserID == userID && session.RevokedAt == nil, nil
+}
+
+// Touch - Mencatat last-seen di memori, ditulis ke database oleh Flush
+func (s *sessionService) Touch(sessionID string) {
+    s.mu.Lock()
+    s.lastSeen[sessionID] = time.Now()
+    s.mu.Unlock()
+}
+
+// Flush - Menulis semua last-seen yang tertunda ke database
+func (s *sessionService) Flush(ctx context.Context) error {
+    s.mu.Lock()
+    pending := s.lastSeen
+    s.lastSeen = make(map[string]time.Time)
+    s.mu.Unlock()
+
+    if len(pending) == 0 {
+        return nil
+    }
+    return s.repo.UpdateLastSeen(ctx, pending)
+}
+
+// StartLastSeenFlusher - Menjalankan Flush secara berkala sampai stop dipanggil
+func (s *sessionService) StartLastSeenFlusher(interval time.Duration) (stop func()) {
+    ticker := time.NewTicker(interval)
+    done := make(chan struct{})
+
+    go func() {
+        for {
+            select {
+            case <-ticker.C:
+                if err := s.Flush(context.Background()); err != nil {
+                    slog.Error("Failed to flush session last-seen", "error", err)
+                }
+            case <-done:
+                ticker.Stop()
+                return
+            }
+        }
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            close(done)
+            if err := s.Flush(context.Background()); err != nil {
+                slog.Error("Failed to flush session last-seen", "error", err)
+            }
+        })
+    }
+}
+
+// deviceLabel membuat label device yang mudah dibaca dari header User-Agent
+func deviceLabel(userAgent string) string {
+    if userAgent == "" {
+        return "Unknown device"
+    }
+
+    browser := "Unknown browser"
+    switch {
+    case strings.Contains(userAgent, "Edg/"):
+        browser = "Edge"
+    case strings.Contains(userAgent, "OPR/"):
+        browser = "Opera"
+    case strings.Contains(userAgent, "Firefox/"):
+        browser = "Firefox"
+    case strings.Contains(userAgent, "Chrome/"):
+        browser = "Chrome"
+    case strings.Contains(userAgent, "Safari/"):
+        browser = "Safari"
+    case strings.HasPrefix(userAgent, "curl/"):
+        browser = "curl"
+    case strings.HasPrefix(userAgent, "PostmanRuntime/"):
+        browser = "Postman"
+    }
+
+    os := ""
+    switch {
+    case strings.Contains(userAgent, "Android"):
+        os = "Android"
+    case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+        os = "iOS"
+    case strings.Contains(userAgent, "Windows"):
+        os = "Windows"
+    case strings.Contains(userAgent, "Mac OS X"):
+        os = "macOS"
+    case strings.Contains(userAgent, "Linux"):
+        os = "Linux"
+    }
+
+    if os == "" {
+        return browser
+    }
+    return browser + " on " + os
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+)
+
+// fakeSessionRepository menyimpan session di memori dan menghitung query ke GetSessionByID
+type fakeSessionRepository struct {
+    repository.SessionRepository
+
+    mu       sync.Mutex
+    sessions map[string]*models.Session
+    reads    int
+}
+
+func (f *fakeSessionRepository) GetSessionByID(ctx context.Context, id string) (*models.Session, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.reads++
+    session, ok := f.sessions[id]
+    if !ok {
+        return nil, errors.New("record not found")
+    }
+    copied := *session
+    return &copied, nil
+}
+
+func (f *fakeSessionRepository) RevokeSession(ctx context.Context, id string) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    now := time.Now()
+    f.sessions[id].RevokedAt = &now
+    return nil
+}
+
+func (f *fakeSessionRepository) RevokeSessionsByUserID(ctx context.Context, userID string) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    now := time.Now()
+    for _, session := range f.sessions {
+        if session.UserID == userID {
+            session.RevokedAt = &now
+        }
+    }
+    return nil
+}
+
+func TestSessionIsActiveCache(t *testing.T) {
+    tests := []struct {
+        name   string
+        revoke func(s SessionService) error
+        // wantActive adalah hasil IsActive untuk session-1 dan session-2 setelah revoke
+        wantActive [2]bool
+    }{
+        {name: "tanpa revoke", revoke: func(s SessionService) error { return nil }, wantActive: [2]bool{true, true}},
+        {
+            name:       "revoke satu session",
+            revoke:     func(s SessionService) error { return s.Revoke(context.Background(), "user-1", "session-1") },
+            wantActive: [2]bool{false, true},
+        },
+        {
+            name:       "revoke semua session user",
+            revoke:     func(s SessionService) error { return s.RevokeAll(context.Background(), "user-1") },
+            wantActive: [2]bool{false, false},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctx := context.Background()
+            repo := &fakeSessionRepository{sessions: map[string]*models.Session{
+                "session-1": {ID: "session-1", UserID: "user-1"},
+                "session-2": {ID: "session-2", UserID: "user-1"},
+            }}
+            service := NewSessionService(repo, NewCache[models.Session](10, time.Minute))
+
+            // Pemanggilan kedua harus dilayani dari cache
+            for i := 0; i < 2; i++ {
+                for _, id := range []string{"session-1", "session-2"} {
+                    if active, err := service.IsActive(ctx, "user-1", id); err != nil || !active {
+                        t.Fatalf("IsActive(%s) = %v, %v, want true", id, active, err)
+                    }
+                }
+            }
+            if repo.reads != 2 {
+                t.Errorf("GetSessionByID called %d times, want 2", repo.reads)
+            }
+
+            // Session milik user lain tidak dianggap aktif walaupun ada di cache
+            if active, _ := service.IsActive(ctx, "user-2", "session-1"); active {
+                t.Error("session-1 should not be active for user-2")
+            }
+
+            if err := tt.revoke(service); err != nil {
+                t.Fatalf("revoke: %v", err)
+            }
+            for i, id := range []string{"session-1", "session-2"} {
+                if active, _ := service.IsActive(ctx, "user-1", id); active != tt.wantActive[i] {
+                    t.Errorf("IsActive(%s) after revoke = %v, want %v", id, active, tt.wantActive[i])
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "auth-user-api/models"
+    "auth-user-api/repository"
+    "auth-user-api/utils"
+
+    "jwtauth"
+)
+
+// TokenService memeriksa apakah token masih berlaku, dipakai oleh middleware JWT,
+// introspection (RFC 7662) dan revocation (RFC 7009)
+type TokenService interface {
+    ResolvePrincipal(ctx context.Context, claims *jwtauth.Claims) (*jwtauth.Principal, error)
+    Introspect(ctx context.Context, client *models.OAuthClient, token, tokenTypeHint string) (jwtauth.IntrospectionResponse, error)
+    Revoke(ctx context.Context, client *models.OAuthClient, token, tokenTypeHint string) error
+}
+
+type tokenService struct {
+    oauthRepo      repository.OAuthRepository
+    userService    UserService
+    sessionService SessionService
+    clients        *Cache[models.OAuthClient] // Client tidak pernah diubah atau dihapus, cukup dibatasi TTL
+    tokens         jwtauth.TokenConfig
+}
+
+func NewTokenService(oauthRepo repository.OAuthRepository, userService UserService, sessionService SessionService, clients *Cache[models.OAuthClient], tokens jwtauth.TokenConfig) TokenService {
+    return &tokenService{
+        oauthRepo:      oauthRepo,
+        userService:    userService,
+        sessionService: sessionService,
+        clients:        clients,
+        tokens:         tokens,
+    }
+}
+
+// ResolvePrincipal - Memeriksa user, token version dan session dari claims yang signature-nya valid
+func (s *tokenService) ResolvePrincipal(ctx context.Context, claims *jwtauth.Claims) (*jwtauth.Principal, error) {
+    // Token client_credentials diterbitkan atas nama client, bukan user
+    if claims.ClientID != "" && claims.Subject == claims.ClientID {
+        client, err := s.getClient(ctx, claims.ClientID)
+        if err != nil {
+            return nil, jwtauth.Unauthorized("Client not found", "Invalid token - client not found")
+        }
+        return &jwtauth.Principal{
+            ID:       client.ID,
+            Username: client.Name,
+            Roles:    []string{models.RoleClient},
+            ClientID: client.ID,
+            Scopes:   strings.Fields(claims.Scope),
+        }, nil
+    }
+
+    // Cek apakah user ada di database
+    user, err := s.userService.GetAuthUser(ctx, claims.Subject)
+    if err != nil || user == nil {
+        return nil, jwtauth.Unauthorized("User not found or deleted", "Invalid token - user not found")
+    }
+
+    // Token milik akun yang dibekukan, dikunci atau belum diverifikasi ditolak
+    if err := accountStatusError(user); err != nil {
+        return nil, accountStatusAuthError(user, err)
+    }
+
+    // Token yang diterbitkan sebelum ganti password atau forced logout ditolak
+    if claims.TokenVersion != user.TokenVersion {
+        return nil, jwtauth.Unauthorized("Token version mismatch", "Invalid token - token has been revoked")
+    }
+
+    // Token yang terikat pada session yang sudah dicabut ditolak
+    active, err := s.sessionService.IsActive(ctx, user.ID, claims.SessionID)
+    if err != nil || !active {
+        return nil, jwtauth.Unauthorized("Session revoked or not found", "Invalid token - session has been revoked")
+    }
+    s.sessionService.Touch(claims.SessionID)
+
+    return &jwtauth.Principal{
+        ID:        user.ID,
+        Username:  user.Username,
+        Roles:     []string{user.Role},
+        SessionID: claims.SessionID,
+        ClientID:  claims.ClientID,
+        Scopes:    strings.Fields(claims.Scope),
+    }, nil
+}
+
+// getClient - Mengambil client dari cache, atau dari database jika belum ada
+func (s *tokenService) getClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+    if client, ok := s.clients.Get(clientID); ok {
+        return client, nil
+    }
+
+    generation := s.clients.Generation()
+    client, err := s.oauthRepo.GetClientByID(ctx, clientID)
+    if err != nil {
+        return nil, err
+    }
+    s.clients.Set(clientID, client, generation)
+    return client, nil
+}
+
+// Introspect - Mengembalikan status access token atau refresh token. Token tidak aktif hanya berisi active=false.
+func (s *tokenService) Introspect(ctx context.Context, client *models.OAuthClient, token, tokenTypeHint string) (jwtauth.IntrospectionResponse, error) {
+    inactive := jwtauth.IntrospectionResponse{Active: false}
+    if token == "" {
+        return inactive, oauthError("invalid_request", "token is required")
+    }
+
+    if tokenTypeHint == "refresh_token" {
+        if response, ok, err := s.introspectRefreshToken(ctx, token); ok || err != nil {
+            return response, err
+        }
+        return s.introspectAccessToken(ctx, token), nil
+    }
+    // Resource server meminta access token, refresh token tidak boleh dianggap aktif sebagai Bearer token
+    if tokenTypeHint == "access_token" {
+        return s.introspectAccessToken(ctx, token), nil
+    }
+
+    if response := s.introspectAccessToken(ctx, token); response.Active {
+        return response, nil
+    }
+    response, _, err := s.introspectRefreshToken(ctx, token)
+    return response, err
+}
+
+// Revoke - Mencabut token milik client. Token yang tidak dikenal atau milik client lain diabaikan (RFC 7009 section 2.2).
+func (s *tokenService) Revoke(ctx context.Context, client *models.OAuthClient, token, tokenTypeHint string) error {
+    if token == "" {
+        return oauthError("invalid_request", "token is required")
+    }
+
+    refreshToken, err := s.oauthRepo.GetRefreshTokenByHash(ctx, utils.HashToken(token))
+    if err == nil {
+        if refreshToken.ClientID != client.ID {
+            return nil
+        }
+        // Access token dari grant yang sama ikut dicabut melalui session-nya
+        return revokeGrant(ctx, s.oauthRepo, s.sessionService, refreshToken.UserID, refreshToken.SessionID)
+    }
+    if !isRecordNotFound(err) {
+        return err
+    }
+
+    claims, err := s.tokens.Parse(token)
+    if err != nil || claims.ClientID != client.ID {
+        return nil
+    }
+    if claims.SessionID == "" {
+        return oauthError("unsupported_token_type", "client_credentials access tokens expire on their own and cannot be revoked")
+    }
+    return revokeGrant(ctx, s.oauthRepo, s.sessionService, claims.Subject, claims.SessionID)
+}
+
+func (s *tokenService) introspectAccessToken(ctx context.Context, token string) jwtauth.IntrospectionResponse {
+    claims, err := s.tokens.Parse(token)
+    if err != nil {
+        return jwtauth.IntrospectionResponse{Active: false}
+    }
+    if _, err := s.ResolvePrincipal(ctx, claims); err != nil {
+        return jwtauth.IntrospectionResponse{Active: false}
+    }
+    return jwtauth.IntrospectionFromClaims(claims)
+}
+
+// introspectRefreshToken mengembalikan ok=false jika token bukan refresh token yang dikenal
+func (s *tokenService) introspectRefreshToken(ctx context.Context, token string) (jwtauth.IntrospectionResponse, bool, error) {
+    inactive := jwtauth.IntrospectionResponse{Active: false}
+
+    refreshToken, err := s.oauthRepo.GetRefreshTokenByHash(ctx, utils.HashToken(token))
+    if err != nil {
+        if isRecordNotFound(err) {
+            return inactive, false, nil
+        }
+        return inactive, false, err
+    }
+
+    if refreshToken.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+        return inactive, true, nil
+    }
+    active, err := s.sessionService.IsActive(ctx, refreshToken.UserID, refreshToken.SessionID)
+    if err != nil {
+        return inactive, true, err
+    }
+    user, userErr := s.userService.GetAuthUser(ctx, refreshToken.UserID)
+    if !active || userErr != nil || user.TokenVersion != refreshToken.TokenVersion || accountStatusError(user) != nil {
+        return inactive, true, nil
+    }
+
+    return jwtauth.IntrospectionResponse{
+        Active:    true,
+        Scope:     refreshToken.Scope,
+        ClientID:  refreshToken.ClientID,
+        Username:  user.Username,
+        TokenType: "refresh_token",
+        Exp:       refreshToken.ExpiresAt.Unix(),
+        Iat:       refreshToken.CreatedAt.Unix(),
+        Sub:       refreshToken.UserID,
+        SessionID: refreshToken.SessionID,
+    }, true, nil
+}
+
+// accountStatusAuthError membuat error 403 untuk middleware dari hasil accountStatusError
+func accountStatusAuthError(user *models.User, err error) *jwtauth.Error {
+    return jwtauth.Forbidden("account_"+user.Status, "Account is "+user.Status+" - "+err.Error()).WithArgs(err)
+}
+
+// revokeGrant mencabut session beserta semua refresh token yang terikat padanya
+func revokeGrant(ctx context.Context, oauthRepo repository.OAuthRepository, sessionService SessionService, userID, sessionID string) error {
+    if err := oauthRepo.RevokeRefreshTokensBySessionID(ctx, sessionID); err != nil {
+        return err
+    }
+    err := sessionService.Revoke(ctx, userID, sessionID)
+    if err != nil && err != ErrSessionNotFound {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+    "time"
+
+    "auth-user-api/models"
+)
+
+// UserCacheStats berisi statistik hit/miss dari UserCache
+type UserCacheStats = CacheStats
+
+// UserCache adalah Cache untuk data user yang dipakai middleware JWT, dengan ID user sebagai key
+type UserCache struct {
+    *Cache[models.User]
+}
+
+func NewUserCache(capacity int, ttl time.Duration) *UserCache {
+    return &UserCache{Cache: NewCache[models.User](capacity, ttl)}
+}
+
+// Set menyimpan salinan user, kecuali ada invalidasi sejak generation dibaca
+func (c *UserCache) Set(user *models.User, generation uint64) {
+    c.Cache.Set(user.ID, user, generation)
+}
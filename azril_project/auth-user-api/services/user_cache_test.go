@@ -0,0 +1,83 @@
+package services
+
+import (
+    "testing"
+    "time"
+
+    "auth-user-api/models"
+)
+
+func TestUserCacheGenerationGuard(t *testing.T) {
+    tests := []struct {
+        name string
+        // between dijalankan setelah generation dibaca dan sebelum Set, seperti query ke database
+        between      func(c *UserCache)
+        wantUsername string // Kosong berarti user tidak boleh ada di cache
+    }{
+        {name: "tanpa invalidasi", between: func(c *UserCache) {}, wantUsername: "lama"},
+        {name: "user yang sama diinvalidasi", between: func(c *UserCache) { c.Invalidate("user-1") }},
+        {name: "user lain diinvalidasi", between: func(c *UserCache) { c.Invalidate("user-2") }},
+        {
+            name: "set dengan generation baru setelah invalidasi",
+            between: func(c *UserCache) {
+                c.Invalidate("user-1")
+                c.Set(&models.User{ID: "user-1", Username: "baru"}, c.Generation())
+            },
+            wantUsername: "baru",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cache := NewUserCache(10, time.Minute)
+            generation := cache.Generation()
+            tt.between(cache)
+            cache.Set(&models.User{ID: "user-1", Username: "lama"}, generation)
+
+            // Data lama yang dibaca sebelum invalidasi tidak boleh masuk atau menimpa data baru
+            username := ""
+            if user, ok := cache.Get("user-1"); ok {
+                username = user.Username
+            }
+            if username != tt.wantUsername {
+                t.Errorf("cached username = %q, want %q", username, tt.wantUsername)
+            }
+        })
+    }
+}
+
+func TestUserCacheLRUAndTTL(t *testing.T) {
+    cache := NewUserCache(2, time.Minute)
+    for _, id := range []string{"user-1", "user-2"} {
+        cache.Set(&models.User{ID: id}, cache.Generation())
+    }
+    cache.Get("user-1") // user-2 sekarang paling lama tidak dipakai
+    cache.Set(&models.User{ID: "user-3"}, cache.Generation())
+
+    if _, ok := cache.Get("user-2"); ok {
+        t.Error("user-2 should have been evicted")
+    }
+    for _, id := range []string{"user-1", "user-3"} {
+        if _, ok := cache.Get(id); !ok {
+            t.Errorf("%s should still be cached", id)
+        }
+    }
+
+    // Salinan yang dikembalikan tidak boleh mengubah isi cache
+    user, _ := cache.Get("user-1")
+    user.Username = "diubah"
+    if cached, _ := cache.Get("user-1"); cached.Username == "diubah" {
+        t.Error("Get returned a reference to the cached user")
+    }
+
+    stats := cache.Stats()
+    if stats.Evictions != 1 || stats.Misses != 1 || stats.Size != 2 {
+        t.Errorf("stats = %+v, want 1 eviction, 1 miss and size 2", stats)
+    }
+
+    expired := NewUserCache(2, -time.Second)
+    expired.Set(&models.User{ID: "user-1"}, expired.Generation())
+    if _, ok := expired.Get("user-1"); ok {
+        t.Error("expired user should not be returned")
+    }
+}
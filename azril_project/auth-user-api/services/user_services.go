@@ -1,36 +1,126 @@
 package services
 
 import (
+    "context"
     "errors"
+    "log/slog"
+    "strings"
+    "sync"
+    "time"
     "auth-user-api/models"
     "auth-user-api/repository"
     "auth-user-api/utils"
+    "auditlog"
+    "i18n"
+    "metrics"
 
     "golang.org/x/crypto/bcrypt"
 )
 
 type UserService interface {
-    Register(username, email, password1, password2 string) error
-    Update(id, username, email, password1, password2 string) error
-    Delete(id string) error
-    Authenticate(username, password string) error
-    GetAllUsers() ([]*models.User, error)
-    GetUserByID(id string) (*models.User, error)
-    GetUserByUsername(username string) (*models.User, error)  // Tambahkan ini untuk mengambil user berdasarkan username
+    Register(ctx context.Context, username, email, password1, password2 string, actor auditlog.Actor) error
+    CreateServiceAccount(ctx context.Context, username, email string, actor auditlog.Actor) (*models.User, error)
+    // version adalah versi user yang terakhir dilihat client, 0 berarti tanpa pengecekan versi
+    Update(ctx context.Context, id string, version int, username, email, password1, password2 string, actor auditlog.Actor) error
+    Delete(ctx context.Context, id string, actor auditlog.Actor) error
+    Authenticate(ctx context.Context, username, password string, actor auditlog.Actor) (*models.User, error)
+    VerifyPassword(ctx context.Context, id, password string) error
+    RevokeTokens(ctx context.Context, id string, actor auditlog.Actor) error
+    GetAllUsers(ctx context.Context) ([]*models.User, error)
+    GetUserByID(ctx context.Context, id string) (*models.User, error)
+    GetUserByUsername(ctx context.Context, username string) (*models.User, error)  // Tambahkan ini untuk mengambil user berdasarkan username
+    GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+    GetAuthUser(ctx context.Context, id string) (*models.User, error)
+    CacheStats() UserCacheStats
+    ListDeleted(ctx context.Context) ([]*models.User, error)
+    Restore(ctx context.Context, id string, actor auditlog.Actor) error
+    Purge(ctx context.Context, id string, actor auditlog.Actor) error
+    PurgeExpired(ctx context.Context, retention time.Duration) (int, error)
+    StartRetentionPurger(interval, retention time.Duration) (stop func())
+    ChangeStatus(ctx context.Context, id, status, reason string, actor auditlog.Actor) (*models.UserStatusChange, error)
+    StatusHistory(ctx context.Context, id string) ([]*models.UserStatusChange, error)
+}
+
+// ErrUserNotDeleted dikembalikan saat restore atau purge user yang tidak dalam status terhapus
+var ErrUserNotDeleted = i18n.NewError("user.not_deleted")
+
+// Dikembalikan saat username atau email sudah dipakai user lain yang belum dihapus,
+// termasuk saat restore user yang namanya sudah didaftarkan ulang
+var (
+    ErrUsernameTaken = repository.ErrDuplicateUsername
+    ErrEmailTaken    = repository.ErrDuplicateEmail
+)
+
+// ErrVersionConflict dikembalikan Update jika user sudah diubah request lain sejak versi yang dilihat client
+var ErrVersionConflict = repository.ErrVersionConflict
+
+// Dikembalikan saat login atau memakai token dengan akun yang statusnya bukan active
+var (
+    ErrAccountPending   = i18n.NewError("account.pending")
+    ErrAccountSuspended = i18n.NewError("account.suspended")
+    ErrAccountLocked    = i18n.NewError("account.locked")
+)
+
+// ErrInvalidCredentials dikembalikan Authenticate jika username tidak ada, password salah atau akun tidak boleh
+// login dengan password. Semua kasus memakai error yang sama agar username yang terdaftar tidak bisa ditebak.
+var ErrInvalidCredentials = i18n.NewError("auth.invalid_credentials")
+
+// ErrCurrentPasswordInvalid dikembalikan VerifyPassword jika password saat ini salah
+var ErrCurrentPasswordInvalid = i18n.NewError("password.current_invalid")
+
+// dummyPasswordHash dibandingkan saat username tidak ada, agar waktu response sama dengan password yang salah
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// Dikembalikan oleh ChangeStatus untuk input yang tidak valid atau user yang tidak ada
+var (
+    ErrUserNotFound         = i18n.NewError("user.not_found")
+    ErrInvalidStatus        = i18n.NewError("user.invalid_status")
+    ErrStatusReasonRequired = i18n.NewError("user.status_reason_required")
+)
+
+// accountStatusError mengembalikan error jika status user tidak mengizinkan login atau memakai token.
+// Status yang tidak dikenal ikut ditolak.
+func accountStatusError(user *models.User) error {
+    switch user.Status {
+    case models.StatusActive:
+        return nil
+    case models.StatusPending:
+        return ErrAccountPending
+    case models.StatusSuspended:
+        return ErrAccountSuspended
+    }
+    return ErrAccountLocked
+}
+
+// IsAccountStatusError memeriksa apakah err berasal dari status akun yang tidak aktif
+func IsAccountStatusError(err error) bool {
+    return errors.Is(err, ErrAccountPending) || errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrAccountLocked)
 }
 
 type userService struct {
-    repo repository.UserRepository
+    repo  repository.UserRepository
+    cache *UserCache
+    audit *auditlog.Logger
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-    return &userService{repo}
+func NewUserService(repo repository.UserRepository, cache *UserCache, audit *auditlog.Logger) UserService {
+    return &userService{repo: repo, cache: cache, audit: audit}
 }
 
 // Register - Untuk mendaftarkan user baru
-func (s *userService) Register(username, email, password1, password2 string) error {
+func (s *userService) Register(ctx context.Context, username, email, password1, password2 string, actor auditlog.Actor) (err error) {
+    var user *models.User
+    defer func() {
+        event := auditEvent(actor, AuditUserRegister, err)
+        if user != nil {
+            event = event.On("user", user.ID)
+        }
+        s.audit.Record(event)
+        metrics.Registrations.WithLabelValues(metrics.Outcome(err)).Inc()
+    }()
+
     if password1 != password2 {
-        return errors.New("password didn't match")
+        return i18n.NewError("password.mismatch")
     }
 
     // Validasi format password
@@ -44,19 +134,55 @@ func (s *userService) Register(username, email, password1, password2 string) err
         return err
     }
 
-    user := &models.User{
+    newUser := &models.User{
         Username: username,
         Email:    email,
         Password: string(hashedPassword), // Simpan password yang sudah di-hash
     }
 
     // Simpan user baru ke database
-    return s.repo.CreateUser(user)
+    if err := s.repo.CreateUser(ctx, newUser); err != nil {
+        return err
+    }
+    user = newUser
+    return nil
+}
+
+// CreateServiceAccount - Membuat akun service untuk job atau perangkat yang login memakai API key.
+// Password-nya acak dan tidak pernah diberikan, sehingga akun ini tidak bisa login lewat /login.
+func (s *userService) CreateServiceAccount(ctx context.Context, username, email string, actor auditlog.Actor) (user *models.User, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditServiceAccount, err)
+        if user != nil {
+            event = event.On("user", user.ID)
+        }
+        s.audit.Record(event)
+    }()
+
+    password, err := utils.GenerateRandomToken()
+    if err != nil {
+        return nil, err
+    }
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return nil, err
+    }
+
+    account := &models.User{
+        Username: username,
+        Email:    email,
+        Password: string(hashedPassword),
+        Role:     models.RoleService,
+    }
+    if err := s.repo.CreateUser(ctx, account); err != nil {
+        return nil, err
+    }
+    return account, nil
 }
 
 // GetAllUsers - Mendapatkan semua user
-func (s *userService) GetAllUsers() ([]*models.User, error) {
-    users, err := s.repo.GetAllUsers()
+func (s *userService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+    users, err := s.repo.GetAllUsers(ctx)
     if err != nil {
         return nil, err
     }
@@ -64,11 +190,22 @@ func (s *userService) GetAllUsers() ([]*models.User, error) {
 }
 
 // Update - Mengupdate data user
-func (s *userService) Update(id, username, email, password1, password2 string) error {
-    user, err := s.repo.GetUserByID(id)
+func (s *userService) Update(ctx context.Context, id string, version int, username, email, password1, password2 string, actor auditlog.Actor) (err error) {
+    passwordChanged := false
+    defer func() {
+        s.audit.Record(auditEvent(actor, AuditUserUpdate, err).On("user", id))
+        if passwordChanged {
+            s.audit.Record(auditEvent(actor, AuditUserPasswordChange, err).On("user", id))
+        }
+    }()
+
+    user, err := s.repo.GetUserByID(ctx, id)
     if err != nil {
         return err
     }
+    if version != 0 && user.Version != version {
+        return ErrVersionConflict
+    }
 
     // Update username jika diberikan
     if username != "" {
@@ -83,7 +220,7 @@ func (s *userService) Update(id, username, email, password1, password2 string) e
     // Update password jika diberikan dan valid
     if password1 != "" || password2 != "" {
         if password1 != password2 {
-            return errors.New("password didn't match")
+            return i18n.NewError("password.mismatch")
         }
 
         if err := utils.ValidatePassword(password1); err != nil {
@@ -96,46 +233,238 @@ func (s *userService) Update(id, username, email, password1, password2 string) e
         }
 
         user.Password = string(hashedPassword) // Simpan password yang sudah di-hash
+        user.TokenVersion++                     // Token lama tidak berlaku setelah password diganti
+        passwordChanged = true
     }
 
     // Update user di database
-    return s.repo.UpdateUser(user)
+    defer s.cache.Invalidate(id)
+    return s.repo.UpdateUser(ctx, user)
 }
 
 // Delete - Menghapus user
-func (s *userService) Delete(id string) error {
-    return s.repo.DeleteUser(id)
+func (s *userService) Delete(ctx context.Context, id string, actor auditlog.Actor) error {
+    defer s.cache.Invalidate(id)
+    err := s.repo.DeleteUser(ctx, id)
+    s.audit.Record(auditEvent(actor, AuditUserDelete, err).On("user", id))
+    return err
 }
 
 // Authenticate - Autentikasi user berdasarkan username dan password
-func (s *userService) Authenticate(username, password string) error {
-    user, err := s.repo.GetUserByUsername(username) // Ambil user berdasarkan username
+func (s *userService) Authenticate(ctx context.Context, username, password string, actor auditlog.Actor) (authenticated *models.User, err error) {
+    defer func() {
+        event := auditEvent(actor, AuditUserLogin, err).With("method", "password")
+        if authenticated != nil {
+            event = event.On("user", authenticated.ID)
+            event.ActorID = authenticated.ID
+        }
+        s.audit.Record(event)
+        metrics.LoginAttempts.WithLabelValues("password", metrics.Outcome(err)).Inc()
+    }()
+
+    user, err := s.repo.GetUserByUsername(ctx, username) // Ambil user berdasarkan username
     if err != nil {
         if err.Error() == "record not found" {
-            return errors.New("user not found")
+            bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+            return nil, ErrInvalidCredentials
         }
-        return err
+        return nil, err
     }
 
-    // Periksa apakah user sudah dihapus
+    // User yang sudah dihapus diperlakukan sama dengan username yang tidak ada
     if user.DeletedAt.Valid {
-        return errors.New("user not found")
+        bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+        return nil, ErrInvalidCredentials
+    }
+
+    // Service account hanya boleh memakai API key
+    if user.Role == models.RoleService {
+        return nil, ErrInvalidCredentials
     }
 
     // Verifikasi password
     if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-        return errors.New("invalid username or password")
+        return nil, ErrInvalidCredentials
     }
 
+    // Status diperiksa setelah password agar status akun tidak bocor ke orang yang tidak tahu password-nya
+    if err := accountStatusError(user); err != nil {
+        return nil, err
+    }
+
+    return user, nil
+}
+
+// VerifyPassword - Memeriksa password saat ini sebelum user mengganti password-nya sendiri
+func (s *userService) VerifyPassword(ctx context.Context, id, password string) error {
+    user, err := s.repo.GetUserByID(ctx, id)
+    if err != nil {
+        return err
+    }
+    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+        return ErrCurrentPasswordInvalid
+    }
     return nil
 }
 
+// RevokeTokens - Menaikkan token version sehingga semua token user yang lama tidak berlaku
+func (s *userService) RevokeTokens(ctx context.Context, id string, actor auditlog.Actor) error {
+    defer s.cache.Invalidate(id)
+    err := s.repo.IncrementTokenVersion(ctx, id)
+    s.audit.Record(auditEvent(actor, AuditUserTokensRevoke, err).On("user", id))
+    return err
+}
+
 // GetUserByID - Mengambil user berdasarkan ID
-func (s *userService) GetUserByID(id string) (*models.User, error) {
-    return s.repo.GetUserByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+    return s.repo.GetUserByID(ctx, id)
 }
 
 // GetUserByUsername - Mengambil user berdasarkan username
-func (s *userService) GetUserByUsername(username string) (*models.User, error) {
-    return s.repo.GetUserByUsername(username)
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+    return s.repo.GetUserByUsername(ctx, username)
+}
+
+// GetUserByEmail - Mengambil user berdasarkan email
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    return s.repo.GetUserByEmail(ctx, email)
+}
+
+// GetAuthUser - Mengambil user untuk middleware JWT, memakai cache agar tidak query di setiap request
+func (s *userService) GetAuthUser(ctx context.Context, id string) (*models.User, error) {
+    if user, ok := s.cache.Get(id); ok {
+        return user, nil
+    }
+
+    generation := s.cache.Generation()
+    user, err := s.repo.GetUserByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    s.cache.Set(user, generation)
+    return user, nil
+}
+
+// CacheStats - Statistik cache user milik GetAuthUser
+func (s *userService) CacheStats() UserCacheStats {
+    return s.cache.Stats()
+}
+
+// ListDeleted - Mengambil user yang sudah dihapus dan masih bisa di-restore
+func (s *userService) ListDeleted(ctx context.Context) ([]*models.User, error) {
+    return s.repo.GetDeletedUsers(ctx)
+}
+
+// Restore - Mengembalikan user yang dihapus selama belum di-purge
+func (s *userService) Restore(ctx context.Context, id string, actor auditlog.Actor) error {
+    defer s.cache.Invalidate(id)
+    err := s.repo.RestoreUser(ctx, id)
+    if isRecordNotFound(err) {
+        err = ErrUserNotDeleted
+    }
+    s.audit.Record(auditEvent(actor, AuditUserRestore, err).On("user", id))
+    return err
+}
+
+// Purge - Menganonimkan data pribadi user yang sudah dihapus, tidak bisa dibatalkan
+func (s *userService) Purge(ctx context.Context, id string, actor auditlog.Actor) error {
+    defer s.cache.Invalidate(id)
+    err := s.repo.PurgeUser(ctx, id)
+    if isRecordNotFound(err) {
+        err = ErrUserNotDeleted
+    }
+    s.audit.Record(auditEvent(actor, AuditUserPurge, err).On("user", id))
+    return err
+}
+
+// PurgeExpired - Purge semua user yang dihapus lebih lama dari retention
+func (s *userService) PurgeExpired(ctx context.Context, retention time.Duration) (int, error) {
+    users, err := s.repo.GetUsersDeletedBefore(ctx, time.Now().Add(-retention))
+    if err != nil {
+        return 0, err
+    }
+
+    // Dijalankan oleh sistem, bukan oleh user tertentu
+    actor := auditlog.Actor{}
+    purged := 0
+    for _, user := range users {
+        if err := s.Purge(ctx, user.ID, actor); err != nil {
+            // User yang sudah di-restore atau di-purge proses lain dilewati, tidak ikut dihitung
+            if err == ErrUserNotDeleted {
+                continue
+            }
+            return purged, err
+        }
+        purged++
+    }
+    return purged, nil
+}
+
+// StartRetentionPurger - Menjalankan PurgeExpired secara berkala sampai stop dipanggil
+func (s *userService) StartRetentionPurger(interval, retention time.Duration) (stop func()) {
+    ticker := time.NewTicker(interval)
+    done := make(chan struct{})
+
+    go func() {
+        for {
+            select {
+            case <-ticker.C:
+                purged, err := s.PurgeExpired(context.Background(), retention)
+                if err != nil {
+                    slog.Error("Failed to purge expired users", "error", err)
+                }
+                if purged > 0 {
+                    slog.Info("Purged expired users", "count", purged, "retention", retention)
+                }
+            case <-done:
+                ticker.Stop()
+                return
+            }
+        }
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            close(done)
+        })
+    }
+}
+
+// ChangeStatus - Mengubah status akun, alasan wajib diisi dan dicatat ke riwayat status
+func (s *userService) ChangeStatus(ctx context.Context, id, status, reason string, actor auditlog.Actor) (change *models.UserStatusChange, err error) {
+    reason = strings.TrimSpace(reason)
+    defer func() {
+        event := auditEvent(actor, AuditUserStatusChange, err).On("user", id).With("status", status)
+        if change != nil {
+            event = event.With("from_status", change.FromStatus)
+        }
+        s.audit.Record(event) // Alasan hanya disimpan di user_status_history, yang dikosongkan saat purge
+    }()
+
+    if !models.ValidStatus(status) {
+        return nil, ErrInvalidStatus
+    }
+    if reason == "" {
+        return nil, ErrStatusReasonRequired
+    }
+
+    // Cache milik GetAuthUser dibuang agar token yang sudah terbit langsung ditolak
+    defer s.cache.Invalidate(id)
+    change, err = s.repo.ChangeUserStatus(ctx, id, status, reason, actor.ID)
+    if isRecordNotFound(err) {
+        return nil, ErrUserNotFound
+    }
+    return change, err
+}
+
+// StatusHistory - Riwayat perubahan status akun, yang terbaru lebih dulu
+func (s *userService) StatusHistory(ctx context.Context, id string) ([]*models.UserStatusChange, error) {
+    if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+        if isRecordNotFound(err) {
+            return nil, ErrUserNotFound
+        }
+        return nil, err
+    }
+    return s.repo.GetUserStatusHistory(ctx, id)
 }
@@ -0,0 +1,43 @@
+// utils/cookie.go
+
+package utils
+
+import (
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+
+    "jwtauth"
+)
+
+// NewCookieConfigFromEnv mengembalikan nil jika AUTH_COOKIE_MODE tidak aktif.
+// AUTH_COOKIE_SECURE default true, set false hanya untuk development di http://localhost.
+func NewCookieConfigFromEnv() *jwtauth.CookieConfig {
+    if enabled, _ := strconv.ParseBool(os.Getenv("AUTH_COOKIE_MODE")); !enabled {
+        return nil
+    }
+
+    secure, err := strconv.ParseBool(getEnv("AUTH_COOKIE_SECURE", "true"))
+    if err != nil {
+        secure = true
+    }
+
+    return &jwtauth.CookieConfig{
+        Domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+        Secure:   secure,
+        SameSite: ParseSameSite(os.Getenv("AUTH_COOKIE_SAMESITE")),
+    }
+}
+
+// ParseSameSite menerjemahkan lax, strict atau none, nilai lain menjadi Lax
+func ParseSameSite(value string) http.SameSite {
+    switch strings.ToLower(value) {
+    case "strict":
+        return http.SameSiteStrictMode
+    case "none":
+        return http.SameSiteNoneMode
+    default:
+        return http.SameSiteLaxMode
+    }
+}
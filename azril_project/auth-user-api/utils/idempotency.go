@@ -0,0 +1,46 @@
+// utils/idempotency.go
+
+package utils
+
+import (
+    "log"
+    "strings"
+    "time"
+
+    "gorm.io/gorm"
+    "idempotency"
+)
+
+// idempotencyExcluded adalah rute yang response-nya berisi token, code atau secret. Response ini
+// tidak boleh tersimpan di idempotency_keys, jadi Idempotency-Key diabaikan di rute tersebut.
+var idempotencyExcluded = []string{
+    "POST /login",
+    "POST /login/magic/verify",
+    "POST /oauth/authorize",
+    "POST /oauth/token",
+    "POST /oauth/clients",
+    "POST /me/api-keys",
+    "POST /me/api-keys/:id/rotate",
+    "POST /users/:id/api-keys",
+    "POST /users/:id/api-keys/:key_id/rotate",
+}
+
+// NewIdempotencyConfigFromEnv membaca IDEMPOTENCY_STORE (postgres atau memory, default postgres) dan
+// IDEMPOTENCY_TTL (default 24 jam). Store memory hanya cocok jika service berjalan di satu instance.
+func NewIdempotencyConfigFromEnv(db *gorm.DB) idempotency.Config {
+    var store idempotency.Store
+    switch strings.ToLower(getEnv("IDEMPOTENCY_STORE", "postgres")) {
+    case "memory":
+        store = idempotency.NewMemoryStore()
+    case "postgres":
+        store = idempotency.NewPostgresStore(db)
+    default:
+        log.Fatalf("Invalid IDEMPOTENCY_STORE: must be postgres or memory")
+    }
+
+    return idempotency.Config{
+        Store:   store,
+        TTL:     getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+        Exclude: idempotencyExcluded,
+    }
+}
@@ -0,0 +1,40 @@
+// utils/jwt.go
+
+package utils
+
+import (
+    "os"
+    "time"
+
+    "jwtauth"
+)
+
+// NewJWTConfigFromEnv membaca JWT_SECRET, JWT_ISSUER, JWT_AUDIENCE, JWT_TTL dan JWT_CLOCK_SKEW
+func NewJWTConfigFromEnv() jwtauth.TokenConfig {
+    return jwtauth.TokenConfig{
+        SigningKey: []byte(getEnv("JWT_SECRET", "my_secret_key")),
+        Issuer:     getEnv("JWT_ISSUER", "auth-user-api"),
+        Audience:   getEnv("JWT_AUDIENCE", "auth-user-api"),
+        TTL:        getEnvDuration("JWT_TTL", 24*time.Hour),
+        ClockSkew:  getEnvDuration("JWT_CLOCK_SKEW", 30*time.Second),
+    }
+}
+
+func getEnv(key, fallback string) string {
+    if value := os.Getenv(key); value != "" {
+        return value
+    }
+    return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+    value := os.Getenv(key)
+    if value == "" {
+        return fallback
+    }
+    duration, err := time.ParseDuration(value)
+    if err != nil {
+        return fallback
+    }
+    return duration
+}
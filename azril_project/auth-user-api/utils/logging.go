@@ -0,0 +1,27 @@
+// utils/logging.go
+
+package utils
+
+import (
+    "log"
+    "time"
+
+    "logging"
+)
+
+// NewLogConfigFromEnv membaca LOG_LEVEL (debug, info, warn atau error, default info) dan LOG_FORMAT
+// (json atau text, default json). Di level debug body request ikut tercatat setelah password dan
+// token disamarkan, jangan dipakai di production.
+func NewLogConfigFromEnv() logging.Config {
+    level, err := logging.ParseLevel(getEnv("LOG_LEVEL", "info"))
+    if err != nil {
+        log.Fatalf("Invalid LOG_LEVEL: %v", err)
+    }
+    return logging.Config{Level: level, Format: getEnv("LOG_FORMAT", "json")}
+}
+
+// NewSlowQueryThresholdFromEnv membaca LOG_SLOW_QUERY (default 200ms), query yang lebih lama
+// tercatat sebagai warning
+func NewSlowQueryThresholdFromEnv() time.Duration {
+    return getEnvDuration("LOG_SLOW_QUERY", 200*time.Millisecond)
+}
@@ -0,0 +1,42 @@
+// utils/magic_link.go
+
+package utils
+
+import (
+    "os"
+    "strconv"
+    "time"
+
+    "jwtauth"
+)
+
+// MagicLinkConfig berisi pengaturan login passwordless lewat email
+type MagicLinkConfig struct {
+    Enabled      bool
+    VerifyURL    string              // URL yang dibuka dari email, token ditambahkan sebagai query ?token=
+    Token        jwtauth.TokenConfig // Audience berbeda dari access token agar keduanya tidak bisa saling dipakai
+    MaxPerWindow int                 // Jumlah link maksimal per email dalam satu Window
+    Window       time.Duration
+}
+
+// NewMagicLinkConfigFromEnv membaca MAGIC_LINK_ENABLED, MAGIC_LINK_URL, MAGIC_LINK_TTL,
+// MAGIC_LINK_MAX_PER_WINDOW dan MAGIC_LINK_WINDOW
+func NewMagicLinkConfigFromEnv(jwtConfig jwtauth.TokenConfig) MagicLinkConfig {
+    enabled, _ := strconv.ParseBool(os.Getenv("MAGIC_LINK_ENABLED"))
+    maxPerWindow, err := strconv.Atoi(os.Getenv("MAGIC_LINK_MAX_PER_WINDOW"))
+    if err != nil || maxPerWindow <= 0 {
+        maxPerWindow = 3
+    }
+
+    token := jwtConfig
+    token.Audience = jwtConfig.Audience + "/magic-link"
+    token.TTL = getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute)
+
+    return MagicLinkConfig{
+        Enabled:      enabled,
+        VerifyURL:    getEnv("MAGIC_LINK_URL", "http://localhost:8080/login/magic/verify"),
+        Token:        token,
+        MaxPerWindow: maxPerWindow,
+        Window:       getEnvDuration("MAGIC_LINK_WINDOW", time.Hour),
+    }
+}
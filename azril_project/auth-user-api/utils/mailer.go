@@ -0,0 +1,82 @@
+// utils/mailer.go
+
+package utils
+
+import (
+    "fmt"
+    "net/smtp"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// Mail adalah email teks biasa yang dikirim oleh service
+type Mail struct {
+    To      string
+    Subject string
+    Body    string
+}
+
+// Mailer mengirim email. Implementasi bisa diganti, misalnya FileMailer untuk development dan test.
+type Mailer interface {
+    Send(mail Mail) error
+}
+
+// FileMailer menulis setiap email sebagai file .eml di Dir, bukan mengirimnya
+type FileMailer struct {
+    Dir  string
+    From string
+}
+
+func (m FileMailer) Send(mail Mail) error {
+    if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+        return err
+    }
+    name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(mail.To))
+    return os.WriteFile(filepath.Join(m.Dir, name), formatMail(m.From, mail), 0o600)
+}
+
+// SMTPMailer mengirim email lewat server SMTP
+type SMTPMailer struct {
+    Addr     string // host:port
+    From     string
+    Username string
+    Password string
+}
+
+func (m SMTPMailer) Send(mail Mail) error {
+    var auth smtp.Auth
+    if m.Username != "" {
+        host, _, _ := strings.Cut(m.Addr, ":")
+        auth = smtp.PlainAuth("", m.Username, m.Password, host)
+    }
+    return smtp.SendMail(m.Addr, auth, m.From, []string{mail.To}, formatMail(m.From, mail))
+}
+
+// NewMailerFromEnv memakai SMTPMailer jika SMTP_ADDR diisi, selain itu FileMailer ke MAIL_DIR
+func NewMailerFromEnv() Mailer {
+    from := getEnv("MAIL_FROM", "no-reply@localhost")
+    if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+        return SMTPMailer{
+            Addr:     addr,
+            From:     from,
+            Username: os.Getenv("SMTP_USERNAME"),
+            Password: os.Getenv("SMTP_PASSWORD"),
+        }
+    }
+    return FileMailer{Dir: getEnv("MAIL_DIR", "tmp/mail"), From: from}
+}
+
+func formatMail(from string, mail Mail) []byte {
+    var b strings.Builder
+    b.WriteString("From: " + from + "\r\n")
+    b.WriteString("To: " + mail.To + "\r\n")
+    b.WriteString("Subject: " + mail.Subject + "\r\n")
+    b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+    b.WriteString("MIME-Version: 1.0\r\n")
+    b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+    b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+    return []byte(b.String())
+}
+
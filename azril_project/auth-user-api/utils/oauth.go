@@ -0,0 +1,50 @@
+// utils/oauth.go
+
+package utils
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/hex"
+    "time"
+)
+
+// OAuthConfig berisi masa berlaku token dan code pada OAuth 2.0
+type OAuthConfig struct {
+    AccessTokenTTL  time.Duration
+    RefreshTokenTTL time.Duration
+    CodeTTL         time.Duration
+}
+
+// NewOAuthConfigFromEnv membaca OAUTH_ACCESS_TOKEN_TTL, OAUTH_REFRESH_TOKEN_TTL dan OAUTH_CODE_TTL
+func NewOAuthConfigFromEnv() OAuthConfig {
+    return OAuthConfig{
+        AccessTokenTTL:  getEnvDuration("OAUTH_ACCESS_TOKEN_TTL", time.Hour),
+        RefreshTokenTTL: getEnvDuration("OAUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+        CodeTTL:         getEnvDuration("OAUTH_CODE_TTL", 10*time.Minute),
+    }
+}
+
+// GenerateRandomToken membuat string acak url-safe dari 32 byte
+func GenerateRandomToken() (string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken menghasilkan SHA-256 hex untuk disimpan di database, token aslinya tidak pernah disimpan
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// VerifyPKCE mencocokkan code_verifier dengan code_challenge metode S256 (RFC 7636)
+func VerifyPKCE(verifier, challenge string) bool {
+    sum := sha256.Sum256([]byte(verifier))
+    computed := base64.RawURLEncoding.EncodeToString(sum[:])
+    return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+    // Contoh dari RFC 7636 appendix B
+    const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+    const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+    tests := []struct {
+        name      string
+        verifier  string
+        challenge string
+        want      bool
+    }{
+        {"verifier cocok", verifier, challenge, true},
+        {"verifier salah", "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXj", challenge, false},
+        {"verifier kosong", "", challenge, false},
+        {"challenge kosong", verifier, "", false},
+        {"metode plain ditolak", verifier, verifier, false},
+        {"challenge dengan padding ditolak", verifier, challenge + "=", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+                t.Errorf("VerifyPKCE(%q, %q) = %v, want %v", tt.verifier, tt.challenge, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,112 @@
+// utils/oidc.go
+
+package utils
+
+import (
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/pem"
+    "errors"
+    "log/slog"
+    "math/big"
+    "os"
+    "time"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+// OIDCConfig berisi issuer dan kunci RSA untuk menandatangani id_token
+type OIDCConfig struct {
+    Issuer     string // URL publik auth-user-api, sama dengan "issuer" di discovery
+    SigningKey *rsa.PrivateKey
+    KeyID      string
+    IDTokenTTL time.Duration
+}
+
+// JWK adalah public key dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+    Kty string `json:"kty"`
+    Use string `json:"use"`
+    Alg string `json:"alg"`
+    Kid string `json:"kid"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+}
+
+// NewOIDCConfigFromEnv membaca OIDC_ISSUER, OIDC_SIGNING_KEY_FILE dan OIDC_ID_TOKEN_TTL.
+// Jika OIDC_SIGNING_KEY_FILE kosong, kunci sementara dibuat sehingga id_token tidak berlaku setelah restart.
+func NewOIDCConfigFromEnv() (OIDCConfig, error) {
+    cfg := OIDCConfig{
+        Issuer:     getEnv("OIDC_ISSUER", "http://localhost:8080"),
+        IDTokenTTL: getEnvDuration("OIDC_ID_TOKEN_TTL", time.Hour),
+    }
+
+    var err error
+    if path := os.Getenv("OIDC_SIGNING_KEY_FILE"); path != "" {
+        cfg.SigningKey, err = loadRSAPrivateKey(path)
+    } else {
+        slog.Warn("OIDC_SIGNING_KEY_FILE is not set, generating a temporary signing key")
+        cfg.SigningKey, err = rsa.GenerateKey(rand.Reader, 2048)
+    }
+    if err != nil {
+        return OIDCConfig{}, err
+    }
+
+    sum := sha256.Sum256(cfg.SigningKey.PublicKey.N.Bytes())
+    cfg.KeyID = base64.RawURLEncoding.EncodeToString(sum[:])[:16]
+    return cfg, nil
+}
+
+// Sign menandatangani claims dengan RS256 dan kid dari kunci aktif
+func (cfg OIDCConfig) Sign(claims jwt.Claims) (string, error) {
+    token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+    token.Header["kid"] = cfg.KeyID
+    return token.SignedString(cfg.SigningKey)
+}
+
+// JWKS mengembalikan public key untuk /.well-known/jwks.json
+func (cfg OIDCConfig) JWKS() []JWK {
+    public := cfg.SigningKey.PublicKey
+    return []JWK{{
+        Kty: "RSA",
+        Use: "sig",
+        Alg: "RS256",
+        Kid: cfg.KeyID,
+        N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+        E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+    }}
+}
+
+// AccessTokenHash menghitung at_hash: setengah kiri SHA-256 dari access token (OIDC Core 3.1.3.6)
+func AccessTokenHash(accessToken string) string {
+    sum := sha256.Sum256([]byte(accessToken))
+    return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return nil, errors.New("signing key file does not contain a PEM block")
+    }
+
+    if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+        return key, nil
+    }
+    parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        return nil, err
+    }
+    key, ok := parsed.(*rsa.PrivateKey)
+    if !ok {
+        return nil, errors.New("signing key is not an RSA key")
+    }
+    return key, nil
+}
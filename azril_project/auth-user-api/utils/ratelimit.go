@@ -0,0 +1,68 @@
+// utils/ratelimit.go
+
+package utils
+
+import (
+    "log"
+    "strings"
+    "time"
+
+    "github.com/labstack/echo/v4"
+    "gorm.io/gorm"
+    "ratelimit"
+)
+
+// rateLimitPolicies adalah batas per rute, rute lain memakai defaultRateLimit.
+// Batas dihitung per IP, dan untuk rute yang butuh login juga per user atau API key.
+// Rute dengan ratelimit.KeyFunc di Config.Keys dihitung per key tersebut, bukan per IP.
+var rateLimitPolicies = map[string]ratelimit.Policy{
+    "POST /register":           {Limit: 10, Period: time.Hour},
+    "POST /login":              {Limit: 10, Period: time.Minute},
+    "POST /login/magic":        {Limit: 5, Period: 10 * time.Minute},
+    "GET /login/magic/verify":  {Limit: 10, Period: time.Minute},
+    "POST /login/magic/verify": {Limit: 10, Period: time.Minute},
+    "POST /oauth/authorize":    {Limit: 10, Period: time.Minute}, // Memeriksa password, sama dengan POST /login
+    "PATCH /me":                {Limit: 10, Period: time.Minute}, // Memeriksa current_password saat ganti password
+    "POST /oauth/token":        {Limit: 30, Period: time.Minute},
+    "POST /oauth/introspect":   {Limit: 1200, Period: time.Minute}, // Per client, dipanggil resource server untuk setiap request
+    "GET /healthz":             {}, // Probe load balancer tidak dibatasi
+    "GET /readyz":              {},
+    "GET /metrics":             {}, // Scrape Prometheus
+}
+
+var defaultRateLimit = ratelimit.Policy{Limit: 120, Period: time.Minute}
+
+// NewRateLimitConfigFromEnv membaca RATE_LIMIT_STORE (memory atau postgres, default memory).
+// Pakai postgres jika service berjalan di lebih dari satu instance agar batasnya berlaku bersama.
+func NewRateLimitConfigFromEnv(db *gorm.DB) ratelimit.Config {
+    var store ratelimit.Store
+    switch strings.ToLower(getEnv("RATE_LIMIT_STORE", "memory")) {
+    case "memory":
+        store = ratelimit.NewMemoryStore()
+    case "postgres":
+        store = ratelimit.NewPostgresStore(db)
+    default:
+        log.Fatalf("Invalid RATE_LIMIT_STORE: must be memory or postgres")
+    }
+
+    return ratelimit.Config{
+        Store:   store,
+        Routes:  rateLimitPolicies,
+        Default: defaultRateLimit,
+    }
+}
+
+// NewIPExtractorFromEnv membaca TRUSTED_PROXIES, alamat atau CIDR reverse proxy dipisah koma.
+// Tanpa TRUSTED_PROXIES IP diambil dari koneksi, header X-Forwarded-For dari client diabaikan
+// agar batas per IP tidak bisa diakali.
+func NewIPExtractorFromEnv() echo.IPExtractor {
+    var proxies []string
+    if value := getEnv("TRUSTED_PROXIES", ""); value != "" {
+        proxies = strings.Split(value, ",")
+    }
+    extractor, err := ratelimit.IPExtractor(proxies)
+    if err != nil {
+        log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+    }
+    return extractor
+}
@@ -0,0 +1,20 @@
+// utils/retention.go
+
+package utils
+
+import "time"
+
+// RetentionConfig mengatur kapan user yang dihapus di-purge secara otomatis
+type RetentionConfig struct {
+    Period   time.Duration // 0 menonaktifkan purge otomatis
+    Interval time.Duration
+}
+
+// NewRetentionConfigFromEnv membaca USER_RETENTION (default 0, purge otomatis harus diaktifkan operator,
+// misalnya 720h) dan USER_PURGE_INTERVAL (default 24 jam)
+func NewRetentionConfigFromEnv() RetentionConfig {
+    return RetentionConfig{
+        Period:   getEnvDuration("USER_RETENTION", 0),
+        Interval: getEnvDuration("USER_PURGE_INTERVAL", 24*time.Hour),
+    }
+}
@@ -0,0 +1,19 @@
+// utils/server.go
+
+package utils
+
+import (
+    "time"
+
+    "health"
+)
+
+// NewServerConfigFromEnv membaca SHUTDOWN_TIMEOUT (default 15 detik) dan SHUTDOWN_DRAIN_DELAY (default 0).
+// Di belakang load balancer, isi SHUTDOWN_DRAIN_DELAY lebih lama dari interval cek /readyz.
+func NewServerConfigFromEnv() health.Config {
+    return health.Config{
+        Addr:            ":8080",
+        ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+        DrainDelay:      getEnvDuration("SHUTDOWN_DRAIN_DELAY", 0),
+    }
+}
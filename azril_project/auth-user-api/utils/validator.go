@@ -3,8 +3,9 @@
 package utils
 
 import (
-    "errors"
     "regexp"
+    "binding"
+    "i18n"
 	"github.com/go-playground/validator/v10"
     "github.com/labstack/echo/v4"
     "net/http"
@@ -17,14 +18,21 @@ type CustomValidator struct {
 
 // NewValidator mengembalikan instance baru dari CustomValidator
 func NewValidator() *CustomValidator {
-    return &CustomValidator{validator: validator.New()}
+    v := validator.New()
+    // Error validasi memakai nama field JSON, bukan nama field struct
+    v.RegisterTagNameFunc(binding.FieldName)
+    // Pesan error validasi diterjemahkan sesuai Accept-Language oleh binding.Bind
+    if err := i18n.RegisterValidator(v); err != nil {
+        panic(err)
+    }
+    return &CustomValidator{validator: v}
 }
 
 // Validate melakukan validasi terhadap struct menggunakan go-playground/validator
 func (cv *CustomValidator) Validate(i interface{}) error {
     if err := cv.validator.Struct(i); err != nil {
-        // Mengonversi error validasi ke HTTP Error
-        return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+        // Mengonversi error validasi ke HTTP Error, error aslinya tetap bisa dibaca binding.Bind per field
+        return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
     }
     return nil
 }
@@ -48,16 +56,16 @@ func ValidatePassword(password string) error {
     )
 
     if len(password) < minLength {
-        return errors.New("password harus minimal 8 karakter")
+        return i18n.NewError("password.too_short", minLength)
     }
     if !hasUpper.MatchString(password) {
-        return errors.New("password harus mengandung minimal 1 huruf besar")
+        return i18n.NewError("password.no_uppercase")
     }
     if !hasNumber.MatchString(password) {
-        return errors.New("password harus mengandung minimal 1 angka")
+        return i18n.NewError("password.no_number")
     }
     if !hasSpecial.MatchString(password) {
-        return errors.New("password harus mengandung minimal 1 simbol")
+        return i18n.NewError("password.no_symbol")
     }
 
     // Alfanumerik + simbol sudah dipenuhi dengan pengecekan di atas
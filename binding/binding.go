@@ -0,0 +1,236 @@
+// Package binding decodes JSON request bodies strictly and reports every problem with the body
+// at once, each with the JSON field name as parameter, in the envelope format.
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// MaxBodySize is the largest request body Bind accepts, in bytes
+var MaxBodySize int64 = 1 << 20
+
+// Error is returned by Bind when the body cannot be used. Details has one entry per problem.
+type Error struct {
+	Status  int
+	Message string
+	Details []envelope.ErrorDetail
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Details))
+	for _, d := range e.Details {
+		messages = append(messages, d.Parameter+": "+d.Message)
+	}
+	return e.Message + ": " + strings.Join(messages, "; ")
+}
+
+// Bind decodes the JSON body into v, which must be a pointer to a struct, and validates it with
+// the echo Validator if one is registered. Unknown fields, values of the wrong JSON type and
+// validation failures are all reported together in an *Error, in the locale of the request.
+func Bind(c echo.Context, v interface{}) error {
+	locale := i18n.LocaleOf(c)
+	body, err := readBody(c, locale, echo.MIMEApplicationJSON)
+	if err != nil {
+		return err
+	}
+	return bindBody(c, locale, body, v)
+}
+
+// bindBody decodes and validates body into v, see Bind
+func bindBody(c echo.Context, locale string, body []byte, v interface{}) error {
+	details, ok := decode(locale, body, v)
+	if !ok {
+		return &Error{Status: http.StatusBadRequest, Message: i18n.Message(locale, "request.invalid_body"), Details: details}
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(v); err != nil {
+			details = appendValidationErrors(locale, details, err)
+		}
+	}
+	if len(details) > 0 {
+		return &Error{Status: http.StatusBadRequest, Message: i18n.Message(locale, "request.invalid_body"), Details: details}
+	}
+	return nil
+}
+
+// Write writes err returned by Bind as an error response
+func Write(c echo.Context, err error) error {
+	var bindErr *Error
+	if errors.As(err, &bindErr) {
+		return envelope.Error(c, bindErr.Status, bindErr.Message, bindErr.Details...)
+	}
+	return envelope.Error(c, http.StatusBadRequest, i18n.T(c, "request.invalid_body"), envelope.Detail(i18n.Err(c, err), "body"))
+}
+
+// readBody reads the request body, which must be of the given media type if it is not empty
+func readBody(c echo.Context, locale, mediaType string) ([]byte, error) {
+	req := c.Request()
+	if req.Body == nil {
+		return nil, nil
+	}
+	if req.ContentLength > 0 && !strings.HasPrefix(strings.ToLower(req.Header.Get(echo.HeaderContentType)), mediaType) {
+		return nil, &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: i18n.Message(locale, "request.unsupported_content_type"),
+			Details: []envelope.ErrorDetail{envelope.Detail(i18n.Message(locale, "request.content_type", mediaType), "Content-Type")},
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &Error{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: i18n.Message(locale, "request.too_large"),
+				Details: []envelope.ErrorDetail{envelope.Detail(i18n.Message(locale, "request.body_limit", MaxBodySize), "body")},
+			}
+		}
+		return nil, &Error{Status: http.StatusBadRequest, Message: i18n.Message(locale, "request.invalid_body"), Details: []envelope.ErrorDetail{envelope.Detail(err.Error(), "body")}}
+	}
+	return body, nil
+}
+
+// decode fills v field by field so that every unknown field and type mismatch is reported,
+// instead of stopping at the first one like json.Decoder. ok is false if the body is not a JSON
+// object at all, validating it would then only add noise.
+func decode(locale string, body []byte, v interface{}) (details []envelope.ErrorDetail, ok bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, true
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return []envelope.ErrorDetail{envelope.Detail(i18n.Message(locale, "request.malformed_json", syntaxErr.Offset), "body")}, false
+		}
+		return []envelope.ErrorDetail{envelope.Detail(i18n.Message(locale, "request.not_object"), "body")}, false
+	}
+
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return []envelope.ErrorDetail{envelope.Detail(fmt.Sprintf("cannot bind into %T", v), "body")}, false
+	}
+	target = target.Elem()
+
+	known := make(map[string]bool)
+	for _, field := range jsonFields(target.Type()) {
+		known[field.name] = true
+		value, ok := raw[field.name]
+		if !ok {
+			continue
+		}
+		if detail, failed := decodeField(locale, value, target.FieldByIndex(field.index), field.name); failed {
+			details = append(details, detail)
+		}
+	}
+
+	var unknown []string
+	for name := range raw {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		details = append(details, envelope.Detail(i18n.Message(locale, "request.unknown_field"), name))
+	}
+	return details, true
+}
+
+func decodeField(locale string, raw json.RawMessage, field reflect.Value, name string) (envelope.ErrorDetail, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(field.Addr().Interface())
+	if err == nil {
+		return envelope.ErrorDetail{}, false
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		parameter := name
+		if typeErr.Field != "" {
+			parameter += "." + typeErr.Field
+		}
+		return envelope.Detail(i18n.Message(locale, "request.type."+jsonType(typeErr.Type)), parameter), true
+	}
+	if unknown, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return envelope.Detail(i18n.Message(locale, "request.unknown_field"), name+"."+strings.Trim(unknown, `"`)), true
+	}
+	return envelope.Detail(err.Error(), name), true
+}
+
+type jsonField struct {
+	name  string
+	index []int
+}
+
+// jsonFields lists the fields encoding/json would decode, including promoted fields of
+// embedded structs, in declaration order
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := FieldName(f)
+		if name == "" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			for _, inner := range jsonFields(f.Type) {
+				fields = append(fields, jsonField{name: inner.name, index: append([]int{i}, inner.index...)})
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fields = append(fields, jsonField{name: name, index: []int{i}})
+	}
+	return fields
+}
+
+// FieldName returns the JSON name of a struct field, or "" if it is not decoded from JSON.
+// Register it with validator.RegisterTagNameFunc so validation errors use the same names.
+func FieldName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	default:
+		return "object"
+	}
+}
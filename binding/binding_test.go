@@ -0,0 +1,101 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type bindTarget struct {
+	Username string   `json:"username" validate:"required,min=3"`
+	Age      int      `json:"age" validate:"gte=0"`
+	Tags     []string `json:"tags"`
+	Profile  struct {
+		City string `json:"city"`
+	} `json:"profile"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int      // 0 when the body is accepted
+		wantParams  []string // Parameter of each detail, in order
+	}{
+		{"valid body", echo.MIMEApplicationJSON, `{"username":"budi","age":30,"tags":["a"],"profile":{"city":"Bandung"}}`, 0, nil},
+		{"content type with charset", echo.MIMEApplicationJSONCharsetUTF8, `{"username":"budi"}`, 0, nil},
+		{"unknown top-level fields", echo.MIMEApplicationJSON, `{"username":"budi","zeta":1,"alpha":2}`, http.StatusBadRequest, []string{"alpha", "zeta"}},
+		{"unknown nested field", echo.MIMEApplicationJSON, `{"username":"budi","profile":{"country":"ID"}}`, http.StatusBadRequest, []string{"profile.country"}},
+		{"wrong type", echo.MIMEApplicationJSON, `{"username":"budi","age":"thirty"}`, http.StatusBadRequest, []string{"age"}},
+		{"wrong nested type", echo.MIMEApplicationJSON, `{"username":"budi","profile":{"city":5}}`, http.StatusBadRequest, []string{"profile.city"}},
+		{"validation failure", echo.MIMEApplicationJSON, `{"username":"bu"}`, http.StatusBadRequest, []string{"username"}},
+		{"empty body is validated", echo.MIMEApplicationJSON, ``, http.StatusBadRequest, []string{"username"}},
+		{"decode and validation errors together", echo.MIMEApplicationJSON, `{"username":"bu","age":"thirty","extra":true}`,
+			http.StatusBadRequest, []string{"age", "extra", "username"}},
+		{"field that failed to decode is not validated again", echo.MIMEApplicationJSON, `{"username":5}`, http.StatusBadRequest, []string{"username"}},
+		{"malformed json", echo.MIMEApplicationJSON, `{"username":`, http.StatusBadRequest, []string{"body"}},
+		{"not an object", echo.MIMEApplicationJSON, `["budi"]`, http.StatusBadRequest, []string{"body"}},
+		{"wrong content type", echo.MIMETextPlain, `{"username":"budi"}`, http.StatusUnsupportedMediaType, []string{"Content-Type"}},
+		{"missing content type", "", `{"username":"budi"}`, http.StatusUnsupportedMediaType, []string{"Content-Type"}},
+		{"body too large", echo.MIMEApplicationJSON, `{"username":"` + strings.Repeat("a", 128) + `"}`, http.StatusRequestEntityTooLarge, []string{"body"}},
+	}
+
+	defer func(size int64) { MaxBodySize = size }(MaxBodySize)
+	MaxBodySize = 128
+
+	e := echo.New()
+	e.Validator = NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, tt.contentType)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var target bindTarget
+			err := Bind(c, &target)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Bind: %v", err)
+				}
+				return
+			}
+
+			var bindErr *Error
+			if !errors.As(err, &bindErr) {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			var params []string
+			for _, d := range bindErr.Details {
+				if d.Message == "" {
+					t.Errorf("detail for %s has no message", d.Parameter)
+				}
+				params = append(params, d.Parameter)
+			}
+			if bindErr.Status != tt.wantStatus || !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("got %d %v, want %d %v", bindErr.Status, params, tt.wantStatus, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestBindDecodesKnownFieldsAlongsideErrors(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"username":"budi","age":"thirty","profile":{"city":"Bandung"}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	var target bindTarget
+	if err := Bind(e.NewContext(req, httptest.NewRecorder()), &target); err == nil {
+		t.Fatal("Bind accepted a body with a wrong type")
+	}
+	if target.Username != "budi" || target.Profile.City != "Bandung" {
+		t.Errorf("valid fields were not decoded: %+v", target)
+	}
+}
@@ -0,0 +1,81 @@
+package binding
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// MIMEMergePatchJSON is the media type of JSON merge patch documents, RFC 7386
+const MIMEMergePatchJSON = "application/merge-patch+json"
+
+// BindMergePatch applies the JSON merge patch in the body to v, which must be a pointer to a struct
+// already holding the current values, then validates the result like Bind. Members the patch
+// sets to null are reset to their zero value, members it leaves out keep their current value.
+// The body must be sent as application/merge-patch+json.
+func BindMergePatch(c echo.Context, v interface{}) error {
+	locale := i18n.LocaleOf(c)
+	patch, err := readBody(c, locale, MIMEMergePatchJSON)
+	if err != nil {
+		return err
+	}
+
+	// Body kosong tidak mengubah apa pun, patch yang bukan objek akan mengganti seluruh dokumen
+	// dan dilaporkan oleh decode
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil || !isObject(patchDoc) {
+		return bindBody(c, locale, patch, v)
+	}
+
+	current, err := json.Marshal(v)
+	if err != nil {
+		return invalidBody(locale, err)
+	}
+	var target interface{}
+	if err := json.Unmarshal(current, &target); err != nil {
+		return invalidBody(locale, err)
+	}
+	merged, err := json.Marshal(mergePatch(target, patchDoc))
+	if err != nil {
+		return invalidBody(locale, err)
+	}
+
+	// Mulai dari nilai nol agar field yang dihapus patch tidak membawa nilai lama
+	if target := reflect.ValueOf(v); target.Kind() == reflect.Pointer && !target.IsNil() {
+		target.Elem().Set(reflect.Zero(target.Elem().Type()))
+	}
+	return bindBody(c, locale, merged, v)
+}
+
+// mergePatch applies patch to target as defined by RFC 7386 section 2
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+func invalidBody(locale string, err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Message: i18n.Message(locale, "request.invalid_body"), Details: []envelope.ErrorDetail{envelope.Detail(err.Error(), "body")}}
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
@@ -0,0 +1,107 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TestMergePatch uses the examples of RFC 7386 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			var target, patch, want interface{}
+			for _, doc := range []struct {
+				raw string
+				v   *interface{}
+			}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+				if err := json.Unmarshal([]byte(doc.raw), doc.v); err != nil {
+					t.Fatalf("unmarshal %s: %v", doc.raw, err)
+				}
+			}
+			if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+				t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+type patchedUser struct {
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Age      int    `json:"age,omitempty"`
+}
+
+func TestBindMergePatch(t *testing.T) {
+	current := patchedUser{Username: "budi", Email: "budi@example.com", Age: 30}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        patchedUser
+		wantStatus  int // 0 means no error
+	}{
+		{name: "members left out keep their value", contentType: MIMEMergePatchJSON, body: `{"email":"baru@example.com"}`,
+			want: patchedUser{Username: "budi", Email: "baru@example.com", Age: 30}},
+		{name: "null resets a member", contentType: MIMEMergePatchJSON, body: `{"email":null}`,
+			want: patchedUser{Username: "budi", Age: 30}},
+		{name: "empty body changes nothing", contentType: MIMEMergePatchJSON, body: ``, want: current},
+		{name: "empty object changes nothing", contentType: MIMEMergePatchJSON, body: `{}`, want: current},
+		{name: "plain JSON content type is refused", contentType: echo.MIMEApplicationJSON, body: `{"email":null}`,
+			wantStatus: http.StatusUnsupportedMediaType},
+		{name: "wrong type", contentType: MIMEMergePatchJSON, body: `{"age":"thirty"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", contentType: MIMEMergePatchJSON, body: `{"role":"admin"}`, wantStatus: http.StatusBadRequest},
+		{name: "patch that is not an object", contentType: MIMEMergePatchJSON, body: `["a"]`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got := current
+			err := BindMergePatch(c, &got)
+			if tt.wantStatus != 0 {
+				var bindErr *Error
+				if !errors.As(err, &bindErr) || bindErr.Status != tt.wantStatus {
+					t.Fatalf("BindMergePatch error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BindMergePatch: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BindMergePatch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package binding
+
+import (
+	"errors"
+	"strings"
+
+	"envelope"
+	"github.com/go-playground/validator/v10"
+	"i18n"
+)
+
+// Validator is an echo.Validator for services without their own, it reports JSON field names
+type Validator struct {
+	validate *validator.Validate
+}
+
+// NewValidator returns a Validator for `validate` struct tags, with messages in every locale of
+// the i18n package
+func NewValidator() *Validator {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(FieldName)
+	if err := i18n.RegisterValidator(validate); err != nil {
+		panic(err)
+	}
+	return &Validator{validate: validate}
+}
+
+// Validate implements echo.Validator
+func (v *Validator) Validate(i interface{}) error {
+	return v.validate.Struct(i)
+}
+
+// appendValidationErrors adds one detail per failed rule, skipping fields that already failed
+// to decode since their validation error would only repeat the problem
+func appendValidationErrors(locale string, details []envelope.ErrorDetail, err error) []envelope.ErrorDetail {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return append(details, envelope.Detail(i18n.Translate(locale, err), "body"))
+	}
+
+	reported := make(map[string]bool, len(details))
+	for _, d := range details {
+		reported[d.Parameter] = true
+	}
+	for _, fe := range validationErrs {
+		parameter := fe.Namespace()
+		if _, rest, ok := strings.Cut(parameter, "."); ok {
+			parameter = rest // Tanpa nama struct root
+		}
+		if reported[parameter] {
+			continue
+		}
+		details = append(details, envelope.Detail(i18n.ValidationMessage(locale, fe), parameter))
+	}
+	return details
+}
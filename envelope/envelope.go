@@ -0,0 +1,99 @@
+// Package envelope is the response format shared by the services in this repository:
+//
+//	{"message": "...", "data": ..., "errors": [{"message": "...", "parameter": "..."}], "code": 400}
+//
+// Error responses can also be written as RFC 7807 problem details when the client asks for
+// application/problem+json in its Accept header. Status texts are written in the locale of the
+// request, see package i18n.
+package envelope
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// Response is the envelope of every JSON response. Code is always the HTTP status code.
+type Response struct {
+	Message string        `json:"message"`
+	Data    interface{}   `json:"data"`
+	Errors  []ErrorDetail `json:"errors"`
+	Code    int           `json:"code"`
+}
+
+// ErrorDetail describes one problem with the request, Parameter names the field or part of the
+// request it is about
+type ErrorDetail struct {
+	Message   string `json:"message"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// JSON writes r with the given status. Code is set to status so the two cannot disagree.
+// Error responses are written as problem details if the client prefers application/problem+json.
+func JSON(c echo.Context, status int, r Response) error {
+	r.Code = status
+	if status >= http.StatusBadRequest && prefersProblem(c.Request().Header.Get(echo.HeaderAccept)) {
+		return writeProblem(c, status, r)
+	}
+	return c.JSON(status, r)
+}
+
+// OK writes a success response with data
+func OK(c echo.Context, status int, message string, data interface{}) error {
+	return JSON(c, status, Response{Message: message, Data: data})
+}
+
+// Error writes an error response with optional details
+func Error(c echo.Context, status int, message string, details ...ErrorDetail) error {
+	return JSON(c, status, Response{Message: message, Errors: details})
+}
+
+// Detail is shorthand for a single ErrorDetail
+func Detail(message, parameter string) ErrorDetail {
+	return ErrorDetail{Message: message, Parameter: parameter}
+}
+
+// HTTPErrorHandler replaces echo's default error handler so unknown routes, panics recovered by
+// middleware and errors returned by handlers use the same envelope
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := StatusText(c, status)
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+		message = StatusText(c, status)
+		if m, ok := he.Message.(string); ok && m != http.StatusText(status) {
+			message = m
+		}
+	}
+	ctx := c.Request().Context()
+	if status >= http.StatusInternalServerError {
+		// Penyebab error hanya ke log, client cukup menerima status text
+		slog.ErrorContext(ctx, "request failed", "error", err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = Error(c, status, message)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "write error response failed", "error", err)
+	}
+}
+
+// StatusText is http.StatusText in the locale of the request
+func StatusText(c echo.Context, status int) string {
+	if text, ok := i18n.Lookup(i18n.LocaleOf(c), "http."+strconv.Itoa(status)); ok {
+		return text
+	}
+	return http.StatusText(status)
+}
@@ -0,0 +1,75 @@
+package envelope
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEProblemJSON is the media type of RFC 7807 problem details
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Errors is an extension member with the same
+// details the envelope would carry.
+type Problem struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Status   int           `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Errors   []ErrorDetail `json:"errors,omitempty"`
+}
+
+func writeProblem(c echo.Context, status int, r Response) error {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    StatusText(c, status),
+		Status:   status,
+		Detail:   r.Message,
+		Instance: c.Request().URL.Path,
+		Errors:   r.Errors,
+	}
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, MIMEProblemJSON, body)
+}
+
+// prefersProblem reports whether the Accept header ranks application/problem+json at least as
+// high as application/json. Wildcards only count for application/json, so clients that send
+// */* keep getting the envelope.
+func prefersProblem(accept string) bool {
+	if accept == "" {
+		return false
+	}
+
+	problemQ, jsonQ := 0.0, 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(mediaRange)
+		switch mediaType {
+		case MIMEProblemJSON:
+			problemQ = max(problemQ, q)
+		case echo.MIMEApplicationJSON, "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+func parseMediaRange(mediaRange string) (string, float64) {
+	parts := strings.Split(mediaRange, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+	q := 1.0
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(key, "q") {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return mediaType, q
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// Ping checks that the database answers
+func Ping(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Migrated checks that the tables of models have every column of their struct. Once the schema
+// is complete the result is remembered, a schema does not lose columns while the service runs.
+func Migrated(db *gorm.DB, models ...interface{}) Check {
+	var done atomic.Bool
+	return func(ctx context.Context) error {
+		if done.Load() {
+			return nil
+		}
+
+		tx := db.WithContext(ctx)
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			columns, err := tx.Migrator().ColumnTypes(model)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", stmt.Table, err)
+			}
+
+			existing := make(map[string]bool, len(columns))
+			for _, column := range columns {
+				existing[column.Name()] = true
+			}
+			for _, name := range stmt.Schema.DBNames {
+				if !existing[name] {
+					return fmt.Errorf("table %s: column %s is not migrated", stmt.Table, name)
+				}
+			}
+		}
+		done.Store(true)
+		return nil
+	}
+}
+
+// Loaded checks that ok reports true, for state set up once at startup such as key material.
+// what names the state in the error.
+func Loaded(what string, ok func() bool) Check {
+	return func(context.Context) error {
+		if !ok() {
+			return fmt.Errorf("%s is not loaded", what)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+// Package health serves the liveness and readiness endpoints of a service and shuts it down
+// gracefully. GET /healthz answers 200 as long as the process serves requests. GET /readyz
+// answers 200 only once initialization completed and while every check passes, and 503 during
+// startup, when a check fails and after shutdown began, so load balancers stop sending traffic.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// Endpoints
+const (
+	PathLiveness  = "/healthz"
+	PathReadiness = "/readyz"
+)
+
+// Check reports why a dependency of the service is not usable, or nil if it is
+type Check func(ctx context.Context) error
+
+// Status is the data of a health response
+type Status struct {
+	Status string            `json:"status"`           // "ok" or "ready"
+	Checks map[string]string `json:"checks,omitempty"` // Check name to "ok"
+}
+
+const (
+	stateStarting int32 = iota
+	stateReady
+	stateStopping
+)
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks. It starts not ready, Server marks it ready when Serve is
+// called and not ready again when shutdown begins.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []namedCheck
+	state  atomic.Int32
+}
+
+// New returns a Checker whose checks get timeout to finish per readiness request, defaults to 2s
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a readiness check, checks run in the order they were added
+func (h *Checker) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Register adds GET /healthz and GET /readyz to e
+func (h *Checker) Register(e *echo.Echo) {
+	e.GET(PathLiveness, h.liveness)
+	e.GET(PathReadiness, h.readiness)
+}
+
+// Ready reports whether initialization completed and shutdown did not begin yet. It does not
+// run the checks.
+func (h *Checker) Ready() bool {
+	return h.state.Load() == stateReady
+}
+
+func (h *Checker) liveness(c echo.Context) error {
+	return envelope.OK(c, http.StatusOK, i18n.T(c, "health.alive"), Status{Status: "ok"})
+}
+
+func (h *Checker) readiness(c echo.Context) error {
+	switch h.state.Load() {
+	case stateStarting:
+		return unavailable(c, "health.starting")
+	case stateStopping:
+		return unavailable(c, "health.stopping")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	status := Status{Status: "ready", Checks: make(map[string]string, len(checks))}
+	var failed []envelope.ErrorDetail
+	for _, nc := range checks {
+		if err := nc.check(ctx); err != nil {
+			// Detail error hanya ke log, endpoint ini bisa diakses tanpa login
+			slog.ErrorContext(ctx, "health: check failed", "check", nc.name, "error", err)
+			failed = append(failed, envelope.Detail(i18n.T(c, "health.check_failed"), nc.name))
+			continue
+		}
+		status.Checks[nc.name] = "ok"
+	}
+	if len(failed) > 0 {
+		return envelope.Error(c, http.StatusServiceUnavailable, i18n.T(c, "health.not_ready"), failed...)
+	}
+	return envelope.OK(c, http.StatusOK, i18n.T(c, "health.ready"), status)
+}
+
+// unavailable answers 503 while the service starts or stops, clients should retry shortly
+func unavailable(c echo.Context, code string) error {
+	c.Response().Header().Set("Retry-After", "1")
+	return envelope.Error(c, http.StatusServiceUnavailable, i18n.T(c, code))
+}
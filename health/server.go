@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// Config configures a Server
+type Config struct {
+	Addr string
+
+	// ShutdownTimeout is how long in-flight requests get to finish after a shutdown signal,
+	// defaults to 15s
+	ShutdownTimeout time.Duration
+
+	// DrainDelay is how long /readyz reports 503 before the listener closes, so load balancers
+	// stop routing new requests first. Zero closes it right away.
+	DrainDelay time.Duration
+}
+
+// Server serves an echo instance and shuts it down gracefully. It listens as soon as it is
+// started, so /healthz and /readyz answer while the service initializes; other requests get 503
+// until Serve hands over to the echo instance of the service.
+type Server struct {
+	cfg     Config
+	checker *Checker
+	http    *http.Server
+	handler atomic.Pointer[echo.Echo]
+	errc    chan error
+}
+
+// Start listens on cfg.Addr and serves the startup handler
+func Start(cfg Config, checker *Checker) (*Server, error) {
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 15 * time.Second
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{cfg: cfg, checker: checker, errc: make(chan error, 1)}
+	s.handler.Store(startup(checker))
+	s.http = &http.Server{Handler: s}
+	go func() {
+		s.errc <- s.http.Serve(listener)
+	}()
+	slog.Info("server listening", "addr", listener.Addr().String())
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.Load().ServeHTTP(w, r)
+}
+
+// Serve hands requests over to e and marks the service ready. Call it once initialization
+// completed and every route is registered, e must not change afterwards.
+func (s *Server) Serve(e *echo.Echo) {
+	s.handler.Store(e)
+	s.checker.state.Store(stateReady)
+}
+
+// Wait blocks until SIGINT or SIGTERM, or until the server fails, then shuts down: /readyz
+// reports 503, after DrainDelay the listener closes and in-flight requests get ShutdownTimeout
+// to finish. A second signal during shutdown exits the process immediately.
+func (s *Server) Wait() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-s.errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutting down, draining in-flight requests")
+	s.checker.state.Store(stateStopping)
+	time.Sleep(s.cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-s.errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+// startup answers requests until Serve is called: the health endpoints, and 503 for everything
+// else
+func startup(checker *Checker) *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.HTTPErrorHandler = envelope.HTTPErrorHandler
+	e.Use(i18n.Middleware())
+	checker.Register(e)
+	e.Any("/*", func(c echo.Context) error {
+		return unavailable(c, "health.starting")
+	})
+	return e
+}
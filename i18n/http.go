@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+)
+
+// Headers used for locale negotiation
+const (
+	HeaderAcceptLanguage  = "Accept-Language"
+	HeaderContentLanguage = "Content-Language" // Set by Middleware to the locale of the response
+)
+
+const contextKey = "i18n.locale"
+
+var matcher = language.NewMatcher(tags(Locales))
+
+func tags(locales []string) []language.Tag {
+	result := make([]language.Tag, len(locales))
+	for i, locale := range locales {
+		result[i] = language.MustParse(locale)
+	}
+	return result
+}
+
+// Negotiate picks the supported locale that best matches an Accept-Language header, or
+// DefaultLocale if none does
+func Negotiate(acceptLanguage string) string {
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(preferred) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(preferred...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return Locales[index]
+}
+
+// Middleware negotiates the locale of every request once and announces it in Content-Language
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			locale := Negotiate(c.Request().Header.Get(HeaderAcceptLanguage))
+			c.Set(contextKey, locale)
+			c.Response().Header().Set(HeaderContentLanguage, locale)
+			c.Response().Header().Add(echo.HeaderVary, HeaderAcceptLanguage)
+			return next(c)
+		}
+	}
+}
+
+// LocaleOf returns the locale of the request, negotiated by Middleware or from the
+// Accept-Language header if the middleware did not run
+func LocaleOf(c echo.Context) string {
+	if locale, ok := c.Get(contextKey).(string); ok {
+		return locale
+	}
+	return Negotiate(c.Request().Header.Get(HeaderAcceptLanguage))
+}
+
+// T returns the message for code in the locale of the request
+func T(c echo.Context, code string, args ...interface{}) string {
+	return Message(LocaleOf(c), code, args...)
+}
+
+// Err returns the message of err in the locale of the request, see Translate
+func Err(c echo.Context, err error) string {
+	return Translate(LocaleOf(c), err)
+}
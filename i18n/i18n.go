@@ -0,0 +1,157 @@
+// Package i18n holds the message catalogs shared by the services in this repository. Messages
+// are keyed by code, for example "request.unknown_field", and looked up in the locale the client
+// asked for with Accept-Language, falling back to English.
+//
+// The catalogs of this package cover request binding, validation, preconditions, idempotency
+// keys, rate limits, health checks and HTTP errors. Services add their own codes with Load.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when the client does not ask for a supported locale and when a code is
+// missing from the catalog of the requested locale
+const DefaultLocale = "en"
+
+// Locales are the supported locales, the first one is the default
+var Locales = []string{DefaultLocale, "id"}
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+var (
+	mu       sync.RWMutex
+	catalogs = make(map[string]map[string]string)
+)
+
+func init() {
+	MustLoad(builtin, "locales")
+}
+
+// Load adds the catalogs in dir of fsys, one <locale>.json file per supported locale containing
+// an object of code to message. Messages are fmt format strings. Codes that already exist are
+// replaced.
+func Load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, entry := range entries {
+		locale, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		if !supported(locale) {
+			return fmt.Errorf("i18n: unsupported locale %q in %s", locale, entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: %s: %w", entry.Name(), err)
+		}
+
+		if catalogs[locale] == nil {
+			catalogs[locale] = make(map[string]string, len(messages))
+		}
+		for code, message := range messages {
+			catalogs[locale][code] = message
+		}
+	}
+	return nil
+}
+
+// MustLoad is like Load but panics on error, for catalogs embedded in the binary
+func MustLoad(fsys fs.FS, dir string) {
+	if err := Load(fsys, dir); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the message for code in locale, or in DefaultLocale if locale has none. ok is
+// false if neither catalog has the code.
+func Lookup(locale, code string, args ...interface{}) (message string, ok bool) {
+	mu.RLock()
+	message, ok = catalogs[locale][code]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][code]
+	}
+	mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return format(locale, message, args), true
+}
+
+// Message is like Lookup but returns the code itself if it is not in any catalog
+func Message(locale, code string, args ...interface{}) string {
+	if message, ok := Lookup(locale, code, args...); ok {
+		return message
+	}
+	return code
+}
+
+// format fills the placeholders of message. Coded errors among args are translated to the same
+// locale, so messages can embed the reason of an error.
+func format(locale, message string, args []interface{}) string {
+	if len(args) == 0 {
+		return message
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+		if err, ok := arg.(error); ok {
+			values[i] = Translate(locale, err)
+		}
+	}
+	return fmt.Sprintf(message, values...)
+}
+
+func supported(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Error is an error with a catalog code, so it can be shown to the client in their locale.
+// Error() returns the message in DefaultLocale.
+type Error struct {
+	Code string
+	Args []interface{}
+}
+
+// NewError returns an *Error for code, args fill the placeholders of the message
+func NewError(code string, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+func (e *Error) Error() string {
+	return Message(DefaultLocale, e.Code, e.Args...)
+}
+
+// Translate returns the message of err in locale if err is or wraps an *Error, otherwise
+// err.Error()
+func Translate(locale string, err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return Message(locale, coded.Code, coded.Args...)
+	}
+	return err.Error()
+}
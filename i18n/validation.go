@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+var (
+	universal    = ut.New(en.New(), en.New(), id.New())
+	translators  = make(map[string]ut.Translator, len(Locales))
+	defaultsFunc = map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"id": id_translations.RegisterDefaultTranslations,
+	}
+)
+
+func init() {
+	for _, locale := range Locales {
+		trans, _ := universal.GetTranslator(locale)
+		translators[locale] = overriding{trans}
+	}
+}
+
+// catalogTags are validator tags whose messages come from the catalog instead of the default
+// translations of the validator, which repeat the field name already given as parameter
+var catalogTags = []string{
+	"required", "email", "url", "uri", "http_url", "uuid", "uuid4", "oneof", "alphanum",
+	"min", "gte", "max", "lte", "len", "gt", "lt",
+}
+
+// overriding lets RegisterValidator run for more than one validator: the default translations
+// refuse to add a text that already exists in the shared translator
+type overriding struct {
+	ut.Translator
+}
+
+func (o overriding) Add(key interface{}, text string, _ bool) error {
+	return o.Translator.Add(key, text, true)
+}
+
+func (o overriding) AddCardinal(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return o.Translator.AddCardinal(key, text, rule, true)
+}
+
+func (o overriding) AddOrdinal(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return o.Translator.AddOrdinal(key, text, rule, true)
+}
+
+func (o overriding) AddRange(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return o.Translator.AddRange(key, text, rule, true)
+}
+
+// Translator returns the universal-translator for locale, or for DefaultLocale if locale is not
+// supported
+func Translator(locale string) ut.Translator {
+	if trans, ok := translators[locale]; ok {
+		return trans
+	}
+	return translators[DefaultLocale]
+}
+
+// RegisterValidator registers the translations of every supported locale on v, so
+// ValidationMessage can translate its errors
+func RegisterValidator(v *validator.Validate) error {
+	for _, locale := range Locales {
+		trans := Translator(locale)
+		if err := defaultsFunc[locale](v, trans); err != nil {
+			return err
+		}
+
+		locale := locale
+		for _, tag := range catalogTags {
+			err := v.RegisterTranslation(tag, trans,
+				func(ut.Translator) error { return nil },
+				func(_ ut.Translator, fe validator.FieldError) string { return catalogMessage(locale, fe) },
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidationMessage returns the message for a failed validation rule in locale. Rules without a
+// translation get a generic message naming the rule.
+func ValidationMessage(locale string, fe validator.FieldError) string {
+	if message := fe.Translate(Translator(locale)); message != fe.Error() {
+		return message
+	}
+	return catalogMessage(locale, fe)
+}
+
+func catalogMessage(locale string, fe validator.FieldError) string {
+	kind := fe.Kind()
+	if kind == reflect.Pointer {
+		kind = fe.Type().Elem().Kind()
+	}
+	unit := ""
+	switch kind {
+	case reflect.String:
+		unit = ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = ".items"
+	}
+
+	switch tag := fe.Tag(); tag {
+	case "required", "email", "alphanum":
+		return Message(locale, "validation."+tag)
+	case "url", "uri", "http_url":
+		return Message(locale, "validation.url")
+	case "uuid", "uuid4":
+		return Message(locale, "validation.uuid")
+	case "oneof":
+		return Message(locale, "validation.oneof", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "min", "gte":
+		return Message(locale, "validation.min"+unit, fe.Param())
+	case "max", "lte":
+		return Message(locale, "validation.max"+unit, fe.Param())
+	case "len", "gt", "lt":
+		return Message(locale, "validation."+tag+unit, fe.Param())
+	default:
+		if message, ok := Lookup(locale, "validation."+tag, fe.Param()); ok {
+			return message
+		}
+		return Message(locale, "validation.failed", tag)
+	}
+}
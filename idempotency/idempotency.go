@@ -0,0 +1,222 @@
+// Package idempotency makes POST requests safe to retry. A client sends the same Idempotency-Key
+// header with every attempt of one operation, the first attempt runs and its response is stored,
+// and later attempts within the TTL get that response replayed instead of running the handler
+// again. Reusing a key for a different request is rejected.
+//
+// Keys are namespaced by the credentials of the request, so a key can only replay responses to
+// the client that made the first request. Routes whose responses carry secrets, such as tokens or
+// new API keys, should be listed in Config.Exclude so those responses are never stored.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// Headers of idempotent requests
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed" // "true" on replayed responses
+)
+
+// MaxKeyLength is the longest accepted Idempotency-Key
+const MaxKeyLength = 255
+
+// Config configures Middleware
+type Config struct {
+	Store       Store
+	TTL         time.Duration // How long a response is replayed, defaults to 24h
+	Lease       time.Duration // How long a running request holds its key, defaults to 30s
+	MaxBodySize int64         // Defaults to 1 MiB, larger bodies are rejected with 413
+
+	// Exclude lists routes as "METHOD /path" registered with echo, e.g. "POST /login", that ignore
+	// Idempotency-Key. Their responses are not stored, list every route that returns secrets.
+	Exclude []string
+}
+
+// Middleware applies idempotency keys to POST requests that send one. Requests without the
+// header, and other methods, pass through unchanged. Responses with a 5xx status, 429 or a
+// Retry-After header are not stored, and neither are handlers that panic, so the client can
+// retry them with the same key.
+//
+// A request that runs longer than Lease, or whose process dies, frees its key when the lease
+// ends, and a retry after that runs the handler again.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	excluded := make(map[string]bool, len(cfg.Exclude))
+	for _, route := range cfg.Exclude {
+		excluded[route] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if req.Method != http.MethodPost || key == "" || excluded[req.Method+" "+c.Path()] {
+				return next(c)
+			}
+			if len(key) > MaxKeyLength {
+				return envelope.Error(c, http.StatusBadRequest, i18n.T(c, "idempotency.invalid_key"),
+					envelope.Detail(i18n.T(c, "idempotency.key_length", MaxKeyLength), HeaderIdempotencyKey))
+			}
+
+			body, err := readBody(c, cfg.MaxBodySize)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					return envelope.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "request.too_large"),
+						envelope.Detail(i18n.T(c, "request.body_limit", cfg.MaxBodySize), "body"))
+				}
+				return err
+			}
+
+			ctx := req.Context()
+			logKey := key
+			key = namespacedKey(req, key)
+			signature := fingerprint(req, body)
+			existing, err := cfg.Store.Begin(ctx, key, signature, cfg.Lease)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return replay(c, existing, signature)
+			}
+
+			release := func() {
+				if err := cfg.Store.Release(ctx, key); err != nil {
+					slog.ErrorContext(ctx, "idempotency: release failed", "key", logKey, "error", err)
+				}
+			}
+
+			// Response direkam sambil tetap dikirim ke client
+			recorder := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			defer func() {
+				// Recover dipasang di luar middleware ini, jadi key dilepas sebelum panic diteruskan
+				if r := recover(); r != nil {
+					c.Response().Writer = recorder.ResponseWriter
+					release()
+					panic(r)
+				}
+			}()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			c.Response().Writer = recorder.ResponseWriter
+
+			res := c.Response()
+			if !res.Committed || !storable(res) {
+				release()
+				return nil
+			}
+			// Cookie sesi tidak pernah disimpan, replay hanya berisi status, header lain dan body
+			header := res.Header().Clone()
+			header.Del(echo.HeaderSetCookie)
+			if err := cfg.Store.Complete(ctx, key, res.Status, header, recorder.body.Bytes(), cfg.TTL); err != nil {
+				slog.ErrorContext(ctx, "idempotency: store response failed", "key", logKey, "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+// storable reports whether a response is final. Server errors and responses that ask the client
+// to come back later, such as 429 from a rate limit, would otherwise be replayed for the whole TTL.
+func storable(res *echo.Response) bool {
+	if res.Status >= http.StatusInternalServerError || res.Status == http.StatusTooManyRequests {
+		return false
+	}
+	return res.Header().Get("Retry-After") == ""
+}
+
+// replay answers a retry with the stored response, or an error if the key cannot be replayed
+func replay(c echo.Context, record *Record, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return envelope.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "idempotency.key_reused"),
+			envelope.Detail(i18n.T(c, "idempotency.key_reused_detail"), HeaderIdempotencyKey))
+	}
+	if !record.Completed() {
+		c.Response().Header().Set("Retry-After", "1")
+		return envelope.Error(c, http.StatusConflict, i18n.T(c, "idempotency.in_progress"),
+			envelope.Detail(i18n.T(c, "idempotency.in_progress_detail"), HeaderIdempotencyKey))
+	}
+
+	header := c.Response().Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(HeaderReplayed, "true")
+	c.Response().WriteHeader(record.Status)
+	_, err := c.Response().Write(record.Body)
+	return err
+}
+
+// readBody reads the request body and puts it back for the handler
+func readBody(c echo.Context, limit int64) ([]byte, error) {
+	req := c.Request()
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// credentialHeaders identify the client of a request, anonymous requests share one namespace
+var credentialHeaders = []string{echo.HeaderAuthorization, "X-API-Key", echo.HeaderCookie}
+
+// namespacedKey is the key stored for the Idempotency-Key of a request: a hash of the key and
+// the credentials sent with it, so clients cannot replay each other's responses by guessing keys
+func namespacedKey(req *http.Request, key string) string {
+	hash := sha256.New()
+	for _, name := range credentialHeaders {
+		hash.Write([]byte(req.Header.Get(name)))
+		hash.Write([]byte{0})
+	}
+	hash.Write([]byte(key))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fingerprint identifies a request: the same key sent with another method, path or body is a
+// different request
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{req.Method, req.URL.RequestURI()} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recorder keeps a copy of everything written to the response
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,230 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+type request struct {
+	method, path, key, body, authorization string
+}
+
+func serve(e *echo.Echo, r request) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if r.key != "" {
+		req.Header.Set(HeaderIdempotencyKey, r.key)
+	}
+	if r.authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, r.authorization)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// newTestServer counts handler calls. POST /orders answers 201 with the call number, so a replay
+// is recognised by an old number. POST /fail answers 500, POST /limited 429, POST /later 202 with
+// Retry-After, POST /panic panics and POST /login is excluded. Recover runs outside the
+// middleware, as in the services.
+func newTestServer() (*echo.Echo, *atomic.Int32) {
+	return newTestServerWithStore(NewMemoryStore())
+}
+
+func newTestServerWithStore(store Store) (*echo.Echo, *atomic.Int32) {
+	var calls atomic.Int32
+	e := echo.New()
+	e.Use(middleware.Recover())
+	e.Use(Middleware(Config{Store: store, Exclude: []string{"POST /login"}}))
+	e.POST("/orders", func(c echo.Context) error {
+		c.SetCookie(&http.Cookie{Name: "session", Value: "secret"})
+		return c.String(http.StatusCreated, strconv.Itoa(int(calls.Add(1))))
+	})
+	e.POST("/fail", func(c echo.Context) error {
+		calls.Add(1)
+		return c.String(http.StatusInternalServerError, "boom")
+	})
+	e.POST("/limited", func(c echo.Context) error {
+		calls.Add(1)
+		c.Response().Header().Set("Retry-After", "30")
+		return c.String(http.StatusTooManyRequests, "slow down")
+	})
+	e.POST("/later", func(c echo.Context) error {
+		calls.Add(1)
+		c.Response().Header().Set("Retry-After", "5")
+		return c.String(http.StatusAccepted, "queued")
+	})
+	e.POST("/panic", func(c echo.Context) error {
+		calls.Add(1)
+		panic("boom")
+	})
+	e.POST("/login", func(c echo.Context) error {
+		return c.String(http.StatusOK, strconv.Itoa(int(calls.Add(1))))
+	})
+	e.GET("/orders", func(c echo.Context) error {
+		return c.String(http.StatusOK, strconv.Itoa(int(calls.Add(1))))
+	})
+	return e, &calls
+}
+
+func TestMiddleware(t *testing.T) {
+	first := request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":1}`, authorization: "Bearer a"}
+
+	tests := []struct {
+		name         string
+		retry        request
+		wantStatus   int
+		wantBody     string // Empty to skip the check
+		wantReplayed bool
+		wantCalls    int32
+	}{
+		{name: "same request is replayed", retry: first, wantStatus: http.StatusCreated, wantBody: "1", wantReplayed: true, wantCalls: 1},
+		{name: "different body is rejected",
+			retry:      request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":2}`, authorization: "Bearer a"},
+			wantStatus: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "different path is rejected",
+			retry:      request{method: http.MethodPost, path: "/orders?draft=1", key: "k1", body: `{"item":1}`, authorization: "Bearer a"},
+			wantStatus: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "other credentials get their own namespace",
+			retry:      request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":1}`, authorization: "Bearer b"},
+			wantStatus: http.StatusCreated, wantBody: "2", wantCalls: 2},
+		{name: "anonymous request cannot replay an authenticated response",
+			retry:      request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":1}`},
+			wantStatus: http.StatusCreated, wantBody: "2", wantCalls: 2},
+		{name: "other key runs again",
+			retry:      request{method: http.MethodPost, path: "/orders", key: "k2", body: `{"item":1}`, authorization: "Bearer a"},
+			wantStatus: http.StatusCreated, wantBody: "2", wantCalls: 2},
+		{name: "request without key runs again",
+			retry:      request{method: http.MethodPost, path: "/orders", body: `{"item":1}`, authorization: "Bearer a"},
+			wantStatus: http.StatusCreated, wantBody: "2", wantCalls: 2},
+		{name: "key longer than the maximum",
+			retry:      request{method: http.MethodPost, path: "/orders", key: strings.Repeat("k", MaxKeyLength+1), authorization: "Bearer a"},
+			wantStatus: http.StatusBadRequest, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, calls := newTestServer()
+			if rec := serve(e, first); rec.Code != http.StatusCreated {
+				t.Fatalf("first request: status = %d", rec.Code)
+			}
+
+			rec := serve(e, tt.retry)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if replayed := rec.Header().Get(HeaderReplayed) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed && rec.Header().Get(echo.HeaderSetCookie) != "" {
+				t.Error("replayed response carries Set-Cookie")
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassThrough(t *testing.T) {
+	tests := []struct {
+		name string
+		req  request
+	}{
+		{"5xx responses are not stored", request{method: http.MethodPost, path: "/fail", key: "k1"}},
+		{"429 responses are not stored", request{method: http.MethodPost, path: "/limited", key: "k1"}},
+		{"responses with Retry-After are not stored", request{method: http.MethodPost, path: "/later", key: "k1"}},
+		{"panicking handlers release the key", request{method: http.MethodPost, path: "/panic", key: "k1"}},
+		{"excluded routes ignore the key", request{method: http.MethodPost, path: "/login", key: "k1", body: `{"username":"budi"}`}},
+		{"other methods ignore the key", request{method: http.MethodGet, path: "/orders", key: "k1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, calls := newTestServer()
+			for i := 0; i < 2; i++ {
+				if rec := serve(e, tt.req); rec.Header().Get(HeaderReplayed) != "" {
+					t.Fatalf("attempt %d was replayed", i+1)
+				}
+			}
+			if got := calls.Load(); got != 2 {
+				t.Errorf("handler calls = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e := echo.New()
+	e.Use(Middleware(Config{Store: NewMemoryStore()}))
+	e.POST("/orders", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.String(http.StatusCreated, "done")
+	})
+	req := request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":1}`}
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = serve(e, req)
+	}()
+	<-started
+
+	// The first attempt is still running, a retry must not run the handler a second time
+	rec := serve(e, req)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("retry while in flight: status = %d, Retry-After = %q, want 409 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	wg.Wait()
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status = %d", first.Code)
+	}
+	if rec := serve(e, req); rec.Code != http.StatusCreated || rec.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("retry after completion: status = %d, replayed = %q", rec.Code, rec.Header().Get(HeaderReplayed))
+	}
+}
+
+func TestMiddlewareLeaseExpires(t *testing.T) {
+	store := NewMemoryStore()
+	e, calls := newTestServerWithStore(store)
+	req := request{method: http.MethodPost, path: "/orders", key: "k1", body: `{"item":1}`}
+
+	// A reservation left behind by a crashed process, neither completed nor released
+	httpReq := httptest.NewRequest(req.method, req.path, nil)
+	httpReq.Header.Set(HeaderIdempotencyKey, req.key)
+	if _, err := store.Begin(context.Background(), namespacedKey(httpReq, req.key), "crashed", time.Millisecond); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if rec := serve(e, req); rec.Code != http.StatusCreated {
+		t.Fatalf("status after the lease ended = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	// Completing extends the record to the TTL, so the retry is replayed instead of running again
+	if rec := serve(e, req); rec.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("retry was not replayed")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("handler calls = %d, want 1", got)
+	}
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in process memory. Retries only replay if they reach the same
+// instance, use PostgresStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*Record
+	nextSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record)}
+}
+
+// Begin implements Store
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, record := range s.records {
+			if !now.Before(record.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+
+	if record, ok := s.records[key]; ok && now.Before(record.ExpiresAt) {
+		existing := *record
+		return &existing, nil
+	}
+	s.records[key] = &Record{Key: key, Fingerprint: fingerprint, CreatedAt: now, ExpiresAt: now.Add(lease)}
+	return nil, nil
+}
+
+// Complete implements Store
+func (s *MemoryStore) Complete(_ context.Context, key string, status int, header http.Header, body []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok && !record.Completed() {
+		record.Status = status
+		record.Header = Header(header)
+		record.Body = body
+		record.ExpiresAt = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok && !record.Completed() {
+		delete(s.records, key)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sweepInterval is how often stores delete expired records
+const sweepInterval = time.Minute
+
+// PostgresStore keeps records in the idempotency_keys table, so every instance of a service
+// sees the same keys
+type PostgresStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Begin implements Store. The insert and the takeover of an expired key are one statement, so
+// two concurrent requests with the same key cannot both reserve it.
+func (s *PostgresStore) Begin(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	now := time.Now()
+	s.sweep(ctx, now)
+
+	record := Record{Key: key, Fingerprint: fingerprint, Header: Header{}, CreatedAt: now, ExpiresAt: now.Add(lease)}
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status", "header", "body", "created_at", "expires_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{now}}}},
+	}).Create(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing Record
+	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete implements Store
+func (s *PostgresStore) Complete(ctx context.Context, key string, status int, header http.Header, body []byte, ttl time.Duration) error {
+	return s.db.WithContext(ctx).Model(&Record{}).
+		Where("key = ? AND status = 0", key).
+		Updates(map[string]interface{}{"status": status, "header": Header(header), "body": body, "expires_at": time.Now().Add(ttl)}).Error
+}
+
+// Release implements Store
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ? AND status = 0", key).Delete(&Record{}).Error
+}
+
+// sweep deletes expired records at most once per sweepInterval. Failures are ignored, the next
+// sweep tries again.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Before(s.nextSweep) {
+		s.mu.Unlock()
+		return
+	}
+	s.nextSweep = now.Add(sweepInterval)
+	s.mu.Unlock()
+
+	s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&Record{})
+}
@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Record is a row of the idempotency_keys table: the first request made with a key and, once it
+// finished, the response that is replayed to retries
+type Record struct {
+	Key         string    `gorm:"primaryKey;size:255"` // Hash of the Idempotency-Key and the credentials
+	Fingerprint string    `gorm:"not null"`            // Hash of method, path and body
+	Status      int       `gorm:"not null"`            // 0 while the first request is still running
+	Header      Header    `gorm:"type:jsonb;not null;default:'{}'"`
+	Body        []byte    `gorm:"type:bytea"`
+	CreatedAt   time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"` // End of the lease while running, of the replay once completed
+}
+
+// TableName keeps the table name independent of the struct name
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the response of the record is stored
+func (r *Record) Completed() bool {
+	return r.Status != 0
+}
+
+// Store keeps records until they expire. Implementations must be safe for concurrent use.
+type Store interface {
+	// Begin reserves key for a request with fingerprint for lease. It returns nil if the key was
+	// free or expired, otherwise the record already stored for it. A reservation that is neither
+	// completed nor released, because the process crashed, frees up when the lease ends.
+	Begin(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error)
+	// Complete stores the response of the request that reserved key and keeps it for ttl
+	Complete(ctx context.Context, key string, status int, header http.Header, body []byte, ttl time.Duration) error
+	// Release frees a reserved key without storing a response, so a retry runs the request again
+	Release(ctx context.Context, key string) error
+}
+
+// Header is a response header stored as jsonb
+type Header http.Header
+
+// Value implements driver.Valuer
+func (h Header) Value() (driver.Value, error) {
+	if h == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (h *Header) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*h = Header{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("idempotency: unsupported header type")
+	}
+	return json.Unmarshal(b, h)
+}
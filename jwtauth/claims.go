@@ -0,0 +1,15 @@
+package jwtauth
+
+import "github.com/golang-jwt/jwt/v4"
+
+// Claims is the token payload shared by both services.
+// The subject (sub) always holds the immutable user ID.
+type Claims struct {
+	Username     string   `json:"username,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	TokenVersion int      `json:"token_version,omitempty"`
+	SessionID    string   `json:"sid,omitempty"`
+	Scope        string   `json:"scope,omitempty"`     // Space separated OAuth scopes
+	ClientID     string   `json:"client_id,omitempty"` // OAuth client the token was issued to
+	jwt.RegisteredClaims
+}
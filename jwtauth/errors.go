@@ -0,0 +1,64 @@
+package jwtauth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error describes why a request was rejected by the middleware
+type Error struct {
+	Status  int    // HTTP status to respond with
+	Reason  string // Short machine readable reason
+	Message string // Human readable message
+	Err     error  // Underlying cause, if any
+
+	// Args are the variable parts of Message, in order, for renderers that translate the
+	// message by Reason
+	Args []interface{}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Unauthorized builds a 401 error, typically returned from a PrincipalResolver
+func Unauthorized(reason, message string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Reason: reason, Message: message}
+}
+
+// Forbidden builds a 403 error
+func Forbidden(reason, message string) *Error {
+	return &Error{Status: http.StatusForbidden, Reason: reason, Message: message}
+}
+
+// WithArgs sets Args and returns e
+func (e *Error) WithArgs(args ...interface{}) *Error {
+	e.Args = args
+	return e
+}
+
+var (
+	ErrMissingToken  = Unauthorized("missing_token", "Missing Authorization header")
+	ErrInvalidToken  = Unauthorized("invalid_token", "Invalid or expired token")
+	ErrBadScheme     = Unauthorized("invalid_scheme", "Token must be provided in Bearer <token> format")
+	ErrInvalidAPIKey = Unauthorized("invalid_api_key", "Invalid, revoked or expired API key")
+)
+
+// ErrorRenderer writes the response for a rejected request
+type ErrorRenderer func(c echo.Context, err *Error) error
+
+// DefaultErrorRenderer responds with {"message": ..., "error": ...}
+func DefaultErrorRenderer(c echo.Context, err *Error) error {
+	return c.JSON(err.Status, map[string]string{
+		"message": err.Message,
+		"error":   err.Reason,
+	})
+}
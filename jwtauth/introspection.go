@@ -0,0 +1,243 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IntrospectionResponse is the RFC 7662 token introspection response
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+}
+
+// IntrospectionConfig configures an Introspector
+type IntrospectionConfig struct {
+	Endpoint     string
+	ClientID     string
+	ClientSecret string
+	CacheTTL     time.Duration // Defaults to 30 seconds
+	MaxEntries   int           // Defaults to 10000
+	HTTPClient   *http.Client  // Defaults to a client with a 5 second timeout
+}
+
+// Introspector validates tokens against a remote RFC 7662 endpoint and caches the results briefly
+type Introspector struct {
+	cfg IntrospectionConfig
+
+	mu    sync.Mutex
+	cache map[string]introspectionEntry
+}
+
+type introspectionEntry struct {
+	response  IntrospectionResponse
+	expiresAt time.Time
+}
+
+// NewIntrospector creates an Introspector, filling in defaults
+func NewIntrospector(cfg IntrospectionConfig) *Introspector {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &Introspector{cfg: cfg, cache: make(map[string]introspectionEntry)}
+}
+
+// Introspect returns the claims of an active access token, or ErrInvalidToken when it is
+// inactive or another kind of token, such as a refresh token. Strings that are not shaped like a
+// JWT are rejected without calling the endpoint, so random bearer values cannot use up its rate
+// limit.
+func (i *Introspector) Introspect(ctx context.Context, token string) (*Claims, error) {
+	if !isJWTShaped(token) {
+		return nil, ErrInvalidToken
+	}
+	key := hashToken(token)
+	now := time.Now()
+
+	i.mu.Lock()
+	entry, ok := i.cache[key]
+	i.mu.Unlock()
+
+	if !ok || now.After(entry.expiresAt) {
+		response, err := i.fetch(ctx, token)
+		if err != nil {
+			return nil, &Error{
+				Status:  http.StatusServiceUnavailable,
+				Reason:  "introspection_unavailable",
+				Message: "Token introspection is unavailable",
+				Err:     err,
+			}
+		}
+		entry = introspectionEntry{response: response, expiresAt: now.Add(i.cfg.CacheTTL)}
+		if response.Exp > 0 && time.Unix(response.Exp, 0).Before(entry.expiresAt) {
+			entry.expiresAt = time.Unix(response.Exp, 0)
+		}
+		i.store(key, entry, now)
+	}
+
+	if !entry.response.Active || !entry.response.IsAccessToken() {
+		return nil, ErrInvalidToken
+	}
+	return entry.response.claims(), nil
+}
+
+// isJWTShaped reports whether token is three non-empty base64url segments separated by dots
+func isJWTShaped(token string) bool {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return false
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (i *Introspector) fetch(ctx context.Context, token string) (IntrospectionResponse, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return IntrospectionResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(i.cfg.ClientID), url.QueryEscape(i.cfg.ClientSecret))
+
+	resp, err := i.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return IntrospectionResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return IntrospectionResponse{}, fmt.Errorf("introspection endpoint returned %d", resp.StatusCode)
+	}
+
+	var response IntrospectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return IntrospectionResponse{}, err
+	}
+	return response, nil
+}
+
+// store adds an entry, dropping expired entries first and then arbitrary ones when full
+func (i *Introspector) store(key string, entry introspectionEntry, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.cache) >= i.cfg.MaxEntries {
+		for k, e := range i.cache {
+			if now.After(e.expiresAt) {
+				delete(i.cache, k)
+			}
+		}
+		for k := range i.cache {
+			if len(i.cache) < i.cfg.MaxEntries {
+				break
+			}
+			delete(i.cache, k)
+		}
+	}
+	i.cache[key] = entry
+}
+
+func (r IntrospectionResponse) claims() *Claims {
+	claims := &Claims{
+		Username:  r.Username,
+		Roles:     r.Roles,
+		SessionID: r.SessionID,
+		Scope:     r.Scope,
+		ClientID:  r.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  r.Sub,
+			Issuer:   r.Iss,
+			Audience: r.Aud,
+			ID:       r.Jti,
+		},
+	}
+	if r.Exp > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(r.Exp, 0))
+	}
+	if r.Iat > 0 {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(r.Iat, 0))
+	}
+	if r.Nbf > 0 {
+		claims.NotBefore = jwt.NewNumericDate(time.Unix(r.Nbf, 0))
+	}
+	return claims
+}
+
+// IsAccessToken reports whether the response describes an access token. Refresh tokens must not
+// be accepted as bearer tokens, so a missing token_type is not enough.
+func (r IntrospectionResponse) IsAccessToken() bool {
+	return strings.EqualFold(r.TokenType, "Bearer") || r.TokenType == "access_token"
+}
+
+// IntrospectionFromClaims builds an active introspection response for validated claims
+func IntrospectionFromClaims(claims *Claims) IntrospectionResponse {
+	response := IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Username,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Roles:     claims.Roles,
+		SessionID: claims.SessionID,
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.Nbf = claims.NotBefore.Unix()
+	}
+	return response
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newIntrospectionServer answers introspection requests from responses, keyed by token, and
+// counts the calls made for each token
+func newIntrospectionServer(t *testing.T, responses map[string]IntrospectionResponse) (*httptest.Server, func(token string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "resource" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := r.PostFormValue("token")
+		mu.Lock()
+		calls[token]++
+		mu.Unlock()
+		if token == "fail.fail.fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(responses[token])
+	}))
+	t.Cleanup(server.Close)
+	return server, func(token string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[token]
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	server, calls := newIntrospectionServer(t, map[string]IntrospectionResponse{
+		"access.token.aaa":  {Active: true, TokenType: "Bearer", Sub: "user-1", Scope: "users:read", ClientID: "client-1"},
+		"access.token.bbb":  {Active: true, TokenType: "access_token", Sub: "user-2"},
+		"refresh.token.aaa": {Active: true, TokenType: "refresh_token", Sub: "user-1"},
+		"untyped.token.aaa": {Active: true, Sub: "user-1"},
+		"revoked.token.aaa": {Active: false},
+	})
+	introspector := NewIntrospector(IntrospectionConfig{Endpoint: server.URL, ClientID: "resource", ClientSecret: "secret"})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantSub    string
+		wantStatus int // Status of the returned *Error, 0 when the token is accepted
+	}{
+		{"bearer access token", "access.token.aaa", "user-1", 0},
+		{"access_token type", "access.token.bbb", "user-2", 0},
+		{"refresh token is rejected", "refresh.token.aaa", "", http.StatusUnauthorized},
+		{"token without a type is rejected", "untyped.token.aaa", "", http.StatusUnauthorized},
+		{"inactive token", "revoked.token.aaa", "", http.StatusUnauthorized},
+		{"endpoint failure", "fail.fail.fail", "", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt := 0; attempt < 2; attempt++ {
+				claims, err := introspector.Introspect(context.Background(), tt.token)
+				if tt.wantStatus == 0 {
+					if err != nil {
+						t.Fatalf("Introspect: %v", err)
+					}
+					if claims.Subject != tt.wantSub {
+						t.Errorf("subject = %q, want %q", claims.Subject, tt.wantSub)
+					}
+					continue
+				}
+				var authErr *Error
+				if !errors.As(err, &authErr) || authErr.Status != tt.wantStatus {
+					t.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+				}
+			}
+		})
+	}
+
+	// Values that are not shaped like a JWT never reach the endpoint
+	for _, token := range []string{"opaque-token", "a.b", "a..c", "a.b.c.d", "a+b.c.d"} {
+		if _, err := introspector.Introspect(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", token, err)
+		}
+		if got := calls(token); got != 0 {
+			t.Errorf("%s: %d endpoint calls, want 0", token, got)
+		}
+	}
+
+	// Answers are cached whether active or not, failures are retried
+	for token, want := range map[string]int{
+		"access.token.aaa":  1,
+		"refresh.token.aaa": 1,
+		"revoked.token.aaa": 1,
+		"fail.fail.fail":    2,
+	} {
+		if got := calls(token); got != want {
+			t.Errorf("%s: %d endpoint calls, want %d", token, got, want)
+		}
+	}
+}
+
+func TestIntrospectClaims(t *testing.T) {
+	server, _ := newIntrospectionServer(t, map[string]IntrospectionResponse{
+		"access.token.aaa": {Active: true, TokenType: "Bearer", Sub: "user-1", Scope: "openid users:read", ClientID: "client-1", Roles: []string{"admin"}, SessionID: "session-1"},
+	})
+	introspector := NewIntrospector(IntrospectionConfig{Endpoint: server.URL, ClientID: "resource", ClientSecret: "secret"})
+
+	claims, err := introspector.Introspect(context.Background(), "access.token.aaa")
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	principal, _ := ClaimsPrincipal(nil, claims)
+	if principal.ClientID != "client-1" || principal.SessionID != "session-1" || len(principal.Roles) != 1 {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+	if !principal.HasScope("users:read") || principal.HasScope("users:write") {
+		t.Errorf("scopes = %v, want openid and users:read only", principal.Scopes)
+	}
+}
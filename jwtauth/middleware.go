@@ -0,0 +1,208 @@
+package jwtauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Config configures Middleware
+type Config struct {
+	Token TokenConfig
+
+	// Cookies enables the cookie fallback with CSRF protection when the Authorization header is absent
+	Cookies *CookieConfig
+
+	// QueryParam enables a query string fallback, e.g. for WebSocket upgrades
+	QueryParam string
+
+	// Skipper lets public routes through without a token
+	Skipper func(c echo.Context) bool
+
+	// Introspector, when set, validates tokens remotely instead of with Token
+	Introspector *Introspector
+
+	// Resolve defaults to ClaimsPrincipal
+	Resolve PrincipalResolver
+
+	// ResolveAPIKey enables API key authentication alongside Bearer tokens.
+	// The key is read from APIKeyHeader, which defaults to X-API-Key.
+	ResolveAPIKey APIKeyResolver
+	APIKeyHeader  string
+
+	// RenderError defaults to DefaultErrorRenderer
+	RenderError ErrorRenderer
+}
+
+// SkipPaths returns a Skipper for the given route paths
+func SkipPaths(paths ...string) func(c echo.Context) bool {
+	skip := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		skip[path] = true
+	}
+	return func(c echo.Context) bool {
+		return skip[c.Path()]
+	}
+}
+
+// Middleware authenticates requests and stores the principal in the echo.Context
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	if cfg.Resolve == nil {
+		cfg.Resolve = ClaimsPrincipal
+	}
+	if cfg.RenderError == nil {
+		cfg.RenderError = DefaultErrorRenderer
+	}
+	if cfg.APIKeyHeader == "" {
+		cfg.APIKeyHeader = "X-API-Key"
+	}
+	if cfg.Cookies != nil {
+		cookies := cfg.Cookies.withDefaults()
+		cfg.Cookies = &cookies
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+
+			// An Authorization header wins when a client sends both
+			if cfg.ResolveAPIKey != nil && c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+				if key := strings.TrimSpace(c.Request().Header.Get(cfg.APIKeyHeader)); key != "" {
+					return cfg.authenticateAPIKey(c, next, key)
+				}
+			}
+
+			tokenString, fromCookie, authErr := cfg.extractToken(c)
+			if authErr != nil {
+				return cfg.RenderError(c, authErr)
+			}
+			if fromCookie {
+				if csrfErr := cfg.Cookies.verifyCSRF(c); csrfErr != nil {
+					return cfg.RenderError(c, csrfErr)
+				}
+			}
+
+			claims, err := cfg.parse(c, tokenString)
+			if err != nil {
+				var parseErr *Error
+				if errors.As(err, &parseErr) {
+					return cfg.RenderError(c, parseErr)
+				}
+				return cfg.RenderError(c, &Error{
+					Status:  ErrInvalidToken.Status,
+					Reason:  ErrInvalidToken.Reason,
+					Message: ErrInvalidToken.Message,
+					Err:     err,
+				})
+			}
+
+			principal, err := cfg.Resolve(c, claims)
+			if err != nil {
+				var resolveErr *Error
+				if errors.As(err, &resolveErr) {
+					return cfg.RenderError(c, resolveErr)
+				}
+				return cfg.RenderError(c, &Error{
+					Status:  ErrInvalidToken.Status,
+					Reason:  ErrInvalidToken.Reason,
+					Message: ErrInvalidToken.Message,
+					Err:     err,
+				})
+			}
+			principal.Claims = claims
+
+			c.Set(ContextKey, principal)
+			return next(c)
+		}
+	}
+}
+
+// RequireRole rejects principals without the given role. It must run after Middleware.
+func RequireRole(role string, render ErrorRenderer) echo.MiddlewareFunc {
+	if render == nil {
+		render = DefaultErrorRenderer
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := PrincipalFromContext(c)
+			if !ok || !principal.HasRole(role) {
+				return render(c, Forbidden("insufficient_role", "Forbidden - "+role+" role required").WithArgs(role))
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScope rejects OAuth tokens that were not granted the given scope. It must run after Middleware.
+func RequireScope(scope string, render ErrorRenderer) echo.MiddlewareFunc {
+	if render == nil {
+		render = DefaultErrorRenderer
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := PrincipalFromContext(c)
+			if !ok || !principal.HasScope(scope) {
+				return render(c, Forbidden("insufficient_scope", "Forbidden - "+scope+" scope required").WithArgs(scope))
+			}
+			return next(c)
+		}
+	}
+}
+
+// authenticateAPIKey resolves the API key principal and continues the chain
+func (cfg Config) authenticateAPIKey(c echo.Context, next echo.HandlerFunc, key string) error {
+	principal, err := cfg.ResolveAPIKey(c, key)
+	if err != nil {
+		var resolveErr *Error
+		if errors.As(err, &resolveErr) {
+			return cfg.RenderError(c, resolveErr)
+		}
+		return cfg.RenderError(c, &Error{
+			Status:  ErrInvalidAPIKey.Status,
+			Reason:  ErrInvalidAPIKey.Reason,
+			Message: ErrInvalidAPIKey.Message,
+			Err:     err,
+		})
+	}
+
+	c.Set(ContextKey, principal)
+	return next(c)
+}
+
+// parse validates the token locally, or through the introspection endpoint when configured
+func (cfg Config) parse(c echo.Context, tokenString string) (*Claims, error) {
+	if cfg.Introspector != nil {
+		return cfg.Introspector.Introspect(c.Request().Context(), tokenString)
+	}
+	return cfg.Token.Parse(tokenString)
+}
+
+// extractToken reads the Bearer token from the header, then the cookie and query fallbacks.
+// fromCookie reports whether the token came from the session cookie and needs a CSRF check.
+func (cfg Config) extractToken(c echo.Context) (token string, fromCookie bool, authErr *Error) {
+	if header := c.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+		scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+		token = strings.TrimSpace(token)
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", false, ErrBadScheme
+		}
+		return token, false, nil
+	}
+
+	if cfg.Cookies != nil {
+		if cookie, err := c.Cookie(cfg.Cookies.Name); err == nil && cookie.Value != "" {
+			return cookie.Value, true, nil
+		}
+	}
+
+	if cfg.QueryParam != "" {
+		if token := c.QueryParam(cfg.QueryParam); token != "" {
+			return token, false, nil
+		}
+	}
+
+	return "", false, ErrMissingToken
+}
@@ -0,0 +1,296 @@
+package jwtauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+var testTokens = TokenConfig{SigningKey: []byte("test-signing-key"), Issuer: "test", TTL: time.Hour}
+
+func issueTestToken(t *testing.T, cfg TokenConfig, claims *Claims) string {
+	t.Helper()
+	token, err := cfg.Issue(claims)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+// newTestServer serves /me and /public behind Middleware, responding with the principal ID or
+// "anonymous" when the request was let through without one
+func newTestServer(cfg Config) *echo.Echo {
+	e := echo.New()
+	e.Use(Middleware(cfg))
+	handler := func(c echo.Context) error {
+		if principal, ok := PrincipalFromContext(c); ok {
+			return c.String(http.StatusOK, principal.ID)
+		}
+		return c.String(http.StatusOK, "anonymous")
+	}
+	e.GET("/me", handler)
+	e.POST("/me", handler)
+	e.GET("/public", handler)
+	return e
+}
+
+// serve returns the status and either the body of a success or the reason of a rejection
+func serve(e *echo.Echo, req *http.Request) (int, string) {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body.Error
+	}
+	return rec.Code, rec.Body.String()
+}
+
+func TestMiddlewareBearer(t *testing.T) {
+	token := issueTestToken(t, testTokens, &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	otherKey := testTokens
+	otherKey.SigningKey = []byte("another-key")
+	expired := testTokens
+	expired.TTL = -time.Minute
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		want          string // Principal ID, or the reason of the rejection
+	}{
+		{"bearer token", "Bearer " + token, http.StatusOK, "user-1"},
+		{"scheme is case-insensitive", "bearer " + token, http.StatusOK, "user-1"},
+		{"upper case scheme and extra spaces", "BEARER   " + token + "  ", http.StatusOK, "user-1"},
+		{"missing header", "", http.StatusUnauthorized, "missing_token"},
+		{"other scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, "invalid_scheme"},
+		{"scheme without token", "Bearer", http.StatusUnauthorized, "invalid_scheme"},
+		{"token without scheme", token, http.StatusUnauthorized, "invalid_scheme"},
+		{"signed with another key", "Bearer " + issueTestToken(t, otherKey, &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}),
+			http.StatusUnauthorized, "invalid_token"},
+		{"expired", "Bearer " + issueTestToken(t, expired, &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}),
+			http.StatusUnauthorized, "invalid_token"},
+		{"not a token", "Bearer not-a-token", http.StatusUnauthorized, "invalid_token"},
+	}
+
+	e := newTestServer(Config{Token: testTokens})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			status, got := serve(e, req)
+			if status != tt.wantStatus || got != tt.want {
+				t.Errorf("got %d %q, want %d %q", status, got, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareSkipper(t *testing.T) {
+	e := newTestServer(Config{Token: testTokens, Skipper: SkipPaths("/public")})
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+		want          string
+	}{
+		{"skipped route without token", "/public", "", http.StatusOK, "anonymous"},
+		{"skipped route ignores an invalid token", "/public", "Bearer not-a-token", http.StatusOK, "anonymous"},
+		{"other routes still need a token", "/me", "", http.StatusUnauthorized, "missing_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			status, got := serve(e, req)
+			if status != tt.wantStatus || got != tt.want {
+				t.Errorf("got %d %q, want %d %q", status, got, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(Config{Token: testTokens}))
+	e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, RequireScope("users:write", nil))
+
+	tests := []struct {
+		name       string
+		claims     Claims
+		wantStatus int
+	}{
+		{"first-party token is not scope restricted", Claims{}, http.StatusOK},
+		{"first-party token with matching scope", Claims{Scope: "users:write"}, http.StatusOK},
+		{"first-party token with other scopes", Claims{Scope: "users:read"}, http.StatusForbidden},
+		{"delegated token with the scope", Claims{ClientID: "client-1", Scope: "openid users:write"}, http.StatusOK},
+		{"delegated token without the scope", Claims{ClientID: "client-1", Scope: "openid users:read"}, http.StatusForbidden},
+		{"delegated token without any scope", Claims{ClientID: "client-1"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tt.claims
+			claims.Subject = "user-1"
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+issueTestToken(t, testTokens, &claims))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAPIKey(t *testing.T) {
+	token := issueTestToken(t, testTokens, &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	e := newTestServer(Config{
+		Token: testTokens,
+		ResolveAPIKey: func(c echo.Context, key string) (*Principal, error) {
+			if key != "valid-key" {
+				return nil, ErrInvalidAPIKey
+			}
+			return &Principal{ID: "key-owner", APIKeyID: "key-1", Scopes: []string{"users:read"}}, nil
+		},
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		apiKey        string
+		wantStatus    int
+		want          string
+	}{
+		{"api key", "", "valid-key", http.StatusOK, "key-owner"},
+		{"invalid api key", "", "other-key", http.StatusUnauthorized, "invalid_api_key"},
+		{"blank api key falls back to the token", "", "   ", http.StatusUnauthorized, "missing_token"},
+		{"authorization header wins over an api key", "Bearer " + token, "valid-key", http.StatusOK, "user-1"},
+		{"invalid authorization header does not fall back to the api key", "Bearer not-a-token", "valid-key",
+			http.StatusUnauthorized, "invalid_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			req.Header.Set("X-API-Key", tt.apiKey)
+			status, got := serve(e, req)
+			if status != tt.wantStatus || got != tt.want {
+				t.Errorf("got %d %q, want %d %q", status, got, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		scope     string
+		want      bool
+	}{
+		{"first-party principal", Principal{ID: "user-1"}, "users:write", true},
+		{"oauth principal with the scope", Principal{ClientID: "client-1", Scopes: []string{"users:write"}}, "users:write", true},
+		{"oauth principal without the scope", Principal{ClientID: "client-1", Scopes: []string{"users:read"}}, "users:write", false},
+		{"oauth principal without scopes", Principal{ClientID: "client-1"}, "users:read", false},
+		{"api key with the scope", Principal{APIKeyID: "key-1", Scopes: []string{"users:read"}}, "users:read", true},
+		{"api key without the scope", Principal{APIKeyID: "key-1", Scopes: []string{"users:read"}}, "users:write", false},
+		{"api key without scopes", Principal{APIKeyID: "key-1"}, "users:read", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCookie(t *testing.T) {
+	token := issueTestToken(t, testTokens, &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	e := newTestServer(Config{Token: testTokens, Cookies: &CookieConfig{}})
+
+	tests := []struct {
+		name          string
+		method        string
+		sessionCookie string
+		csrfCookie    string
+		csrfHeader    string
+		authorization string
+		wantStatus    int
+		want          string
+	}{
+		{"safe method needs no csrf token", http.MethodGet, token, "", "", "", http.StatusOK, "user-1"},
+		{"matching csrf token", http.MethodPost, token, "csrf-1", "csrf-1", "", http.StatusOK, "user-1"},
+		{"missing csrf header", http.MethodPost, token, "csrf-1", "", "", http.StatusForbidden, "invalid_csrf_token"},
+		{"missing csrf cookie", http.MethodPost, token, "", "csrf-1", "", http.StatusForbidden, "invalid_csrf_token"},
+		{"mismatched csrf token", http.MethodPost, token, "csrf-1", "csrf-2", "", http.StatusForbidden, "invalid_csrf_token"},
+		{"csrf is checked before the token", http.MethodPost, "not-a-token", "", "", "", http.StatusForbidden, "invalid_csrf_token"},
+		{"invalid session cookie", http.MethodPost, "not-a-token", "csrf-1", "csrf-1", "", http.StatusUnauthorized, "invalid_token"},
+		{"bearer requests are not csrf checked", http.MethodPost, "", "", "", "Bearer " + token, http.StatusOK, "user-1"},
+		{"bearer header wins over the cookie", http.MethodPost, "not-a-token", "", "", "Bearer " + token, http.StatusOK, "user-1"},
+		{"no cookie", http.MethodPost, "", "csrf-1", "csrf-1", "", http.StatusUnauthorized, "missing_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/me", nil)
+			if tt.sessionCookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.sessionCookie})
+			}
+			if tt.csrfCookie != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set("X-CSRF-Token", tt.csrfHeader)
+			}
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			status, got := serve(e, req)
+			if status != tt.wantStatus || got != tt.want {
+				t.Errorf("got %d %q, want %d %q", status, got, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieSetSession(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/login", nil), httptest.NewRecorder())
+	rec := c.Response().Writer.(*httptest.ResponseRecorder)
+
+	csrfToken, err := CookieConfig{Secure: true}.SetSession(c, "token-1", time.Hour)
+	if err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	session, csrf := cookies["access_token"], cookies["csrf_token"]
+	if session == nil || session.Value != "token-1" || !session.HttpOnly || !session.Secure || session.MaxAge != 3600 {
+		t.Errorf("unexpected session cookie %+v", session)
+	}
+	if csrf == nil || csrf.Value != csrfToken || csrfToken == "" || csrf.HttpOnly {
+		t.Errorf("unexpected csrf cookie %+v, token %q", csrf, csrfToken)
+	}
+}
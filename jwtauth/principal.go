@@ -0,0 +1,71 @@
+package jwtauth
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ContextKey is the echo.Context key the principal is stored under
+const ContextKey = "principal"
+
+// Principal is the authenticated user behind a request
+type Principal struct {
+	ID        string   `json:"id"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"session_id,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	APIKeyID  string   `json:"api_key_id,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"` // Empty for first-party tokens, which are not scope restricted
+	Claims    *Claims  `json:"-"`
+}
+
+// HasRole reports whether the principal has the given role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal may use the given scope
+func (p *Principal) HasScope(scope string) bool {
+	if p.ClientID == "" && p.APIKeyID == "" && len(p.Scopes) == 0 {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromContext returns the principal set by Middleware, if any
+func PrincipalFromContext(c echo.Context) (*Principal, bool) {
+	principal, ok := c.Get(ContextKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// PrincipalResolver turns validated claims into a principal, e.g. by loading the user.
+// Returning a *Error controls the rejection response.
+type PrincipalResolver func(c echo.Context, claims *Claims) (*Principal, error)
+
+// APIKeyResolver authenticates a raw API key. Returning a *Error controls the rejection response.
+type APIKeyResolver func(c echo.Context, key string) (*Principal, error)
+
+// ClaimsPrincipal builds the principal from the claims alone without any lookup
+func ClaimsPrincipal(c echo.Context, claims *Claims) (*Principal, error) {
+	return &Principal{
+		ID:        claims.Subject,
+		Username:  claims.Username,
+		Roles:     claims.Roles,
+		SessionID: claims.SessionID,
+		ClientID:  claims.ClientID,
+		Scopes:    strings.Fields(claims.Scope),
+		Claims:    claims,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenConfig holds the settings used to issue and validate tokens
+type TokenConfig struct {
+	SigningKey []byte
+	Issuer     string        // Validated when not empty
+	Audience   string        // Validated when not empty
+	TTL        time.Duration // Lifetime of issued tokens
+	ClockSkew  time.Duration // Leeway applied to exp, nbf and iat
+}
+
+// Issue signs the claims with HS256 after filling iss, aud, iat, nbf and exp
+func (cfg TokenConfig) Issue(claims *Claims) (string, error) {
+	now := time.Now()
+	claims.Issuer = cfg.Issuer
+	if cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{cfg.Audience}
+	}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(cfg.TTL))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(cfg.SigningKey)
+}
+
+// Parse verifies the signature and the registered claims of a token
+func (cfg TokenConfig) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return cfg.SigningKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (cfg TokenConfig) validate(claims *Claims, now time.Time) error {
+	if claims.Subject == "" {
+		return errors.New("token has no subject")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-cfg.ClockSkew), true) {
+		return errors.New("token is expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(cfg.ClockSkew), false) {
+		return errors.New("token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(now.Add(cfg.ClockSkew), false) {
+		return errors.New("token used before issued")
+	}
+	if cfg.Issuer != "" && !claims.VerifyIssuer(cfg.Issuer, true) {
+		return errors.New("token has invalid issuer")
+	}
+	if cfg.Audience != "" && !claims.VerifyAudience(cfg.Audience, true) {
+		return errors.New("token has invalid audience")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package logging sets up structured logging with log/slog for the services. Every record logged
+// with a context that carries a request ID gets a request_id attribute, so the request log line,
+// application logs and GORM's SQL logs of one request can be correlated. Attributes with
+// sensitive keys such as password or token are redacted before they are written.
+//
+// Setup also routes the standard library log package through slog, so existing log.Printf calls
+// become structured records at INFO level.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config configures Setup
+type Config struct {
+	Level  slog.Level
+	Format string    // "json" (default) or "text"
+	Output io.Writer // Defaults to os.Stdout
+}
+
+// ParseLevel parses debug, info, warn or error, case-insensitively
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("logging: invalid level %q", s)
+	}
+	return level, nil
+}
+
+// Setup builds the logger described by cfg and makes it the default slog logger
+func Setup(cfg Config) (*slog.Logger, error) {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	opts := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(cfg.Output, opts)
+	case "text":
+		handler = slog.NewTextHandler(cfg.Output, opts)
+	default:
+		return nil, fmt.Errorf("logging: invalid format %q, must be json or text", cfg.Format)
+	}
+
+	logger := slog.New(contextHandler{handler})
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// contextHandler adds the request ID of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
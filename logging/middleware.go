@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderRequestID carries the request ID. A valid ID sent by the client or a proxy is kept,
+// otherwise one is generated; the response always returns it.
+const HeaderRequestID = echo.HeaderXRequestID
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// maxLoggedBody is how much of a request body is read for DEBUG logs. Larger bodies are not
+// logged because a truncated body cannot be redacted reliably.
+const maxLoggedBody = 8 << 10
+
+// Middleware assigns every request an ID, puts it in the request context for the handlers and
+// the database layer, and logs one record per request when it finishes: ERROR for 5xx, INFO
+// otherwise. At DEBUG level the record also has the request body with sensitive fields
+// redacted. Install it first so every other middleware sees the ID.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			id := req.Header.Get(HeaderRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			ctx := WithRequestID(req.Context(), id)
+			req = req.WithContext(ctx)
+			c.SetRequest(req)
+			c.Response().Header().Set(HeaderRequestID, id)
+
+			var body []byte
+			debug := slog.Default().Enabled(ctx, slog.LevelDebug)
+			if debug {
+				body = peekBody(req)
+			}
+
+			// Status dari error baru diketahui setelah error handler menulis response
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			level := slog.LevelInfo
+			if res.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("route", c.Path()),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int64("bytes_out", res.Size),
+				slog.String("ip", c.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+			}
+			if req.URL.RawQuery != "" {
+				attrs = append(attrs, slog.String("query", RedactQuery(req.URL.Query())))
+			}
+			if debug && len(body) > 0 {
+				if redacted, ok := RedactBody(req.Header.Get(echo.HeaderContentType), body); ok {
+					attrs = append(attrs, slog.String("body", redacted))
+				}
+			}
+			slog.LogAttrs(ctx, level, "request", attrs...)
+			return nil
+		}
+	}
+}
+
+// peekBody reads up to maxLoggedBody of the request body and puts it back for the handler. It
+// returns nil for larger bodies.
+func peekBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(req.Body, maxLoggedBody+1))
+	req.Body = readCloser{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	if err != nil || len(head) > maxLoggedBody {
+		return nil
+	}
+	return head
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// validRequestID accepts printable ASCII IDs without spaces, so they are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive keys
+const Redacted = "[REDACTED]"
+
+// sensitiveParts are matched against lowercased keys with hyphens read as underscores,
+// sensitiveKeys must match exactly
+var (
+	sensitiveParts = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey", "code_verifier"}
+	sensitiveKeys  = map[string]bool{"code": true} // OAuth authorization code
+)
+
+// Sensitive reports whether values under key must not be logged
+func Sensitive(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr is the ReplaceAttr of the handlers built by Setup
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindGroup && Sensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// RedactBody returns a request body with the values of sensitive fields replaced, for JSON and
+// form bodies. ok is false for other content types and bodies that cannot be parsed, which must
+// then not be logged.
+func RedactBody(contentType string, body []byte) (redacted string, ok bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", false
+		}
+		b, err := json.Marshal(redactJSON(v))
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", false
+		}
+		return RedactQuery(values), true
+	}
+	return "", false
+}
+
+// RedactQuery encodes query or form values with the values of sensitive keys replaced
+func RedactQuery(values url.Values) string {
+	redacted := make(url.Values, len(values))
+	for key, vs := range values {
+		if Sensitive(key) {
+			redacted[key] = []string{Redacted}
+			continue
+		}
+		redacted[key] = vs
+	}
+	return redacted.Encode()
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if Sensitive(key) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactJSON(value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSensitive(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"current_password", true},
+		{"Password", true},
+		{"client_secret", true},
+		{"refresh_token", true},
+		{"Authorization", true},
+		{"Set-Cookie", true},
+		{"X-API-Key", true},
+		{"client-secret", true},
+		{"api_key", true},
+		{"code_verifier", true},
+		{"code", true},
+		{"CODE", true},
+		{"code_challenge", false}, // Only the authorization code itself matches exactly
+		{"country_code", false},
+		{"username", false},
+		{"email", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := Sensitive(tt.key); got != tt.want {
+				t.Errorf("Sensitive(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantOK      bool
+	}{
+		{"json", "application/json", `{"username":"budi","password":"rahasia"}`,
+			`{"password":"[REDACTED]","username":"budi"}`, true},
+		{"json with charset", "application/json; charset=utf-8", `{"code":"abc","state":"xyz"}`,
+			`{"code":"[REDACTED]","state":"xyz"}`, true},
+		{"nested json", "application/json", `{"user":{"email":"a@b.c","credentials":{"api_key":"k","scope":"read"}}}`,
+			`{"user":{"credentials":{"api_key":"[REDACTED]","scope":"read"},"email":"a@b.c"}}`, true},
+		{"sensitive key holding an object", "application/json", `{"token":{"access":"a","refresh":"r"}}`,
+			`{"token":"[REDACTED]"}`, true},
+		{"arrays", "application/json", `[{"client_secret":"s"},{"tags":["a",{"refresh_token":"r"}]}]`,
+			`[{"client_secret":"[REDACTED]"},{"tags":["a",{"refresh_token":"[REDACTED]"}]}]`, true},
+		{"json suffix", "application/merge-patch+json", `{"password":null,"age":30}`,
+			`{"age":30,"password":"[REDACTED]"}`, true},
+		{"form", "application/x-www-form-urlencoded", "grant_type=authorization_code&code=abc&code_verifier=v&redirect_uri=https%3A%2F%2Fapp",
+			"code=%5BREDACTED%5D&code_verifier=%5BREDACTED%5D&grant_type=authorization_code&redirect_uri=https%3A%2F%2Fapp", true},
+		{"malformed json", "application/json", `{"password":`, "", false},
+		{"malformed form", "application/x-www-form-urlencoded", "password=%zz", "", false},
+		{"other content type", "text/plain", "password=rahasia", "", false},
+		{"no content type", "", `{"password":"rahasia"}`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RedactBody(tt.contentType, []byte(tt.body))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("RedactBody = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	values := url.Values{
+		"access_token": {"t1", "t2"},
+		"code":         {"abc"},
+		"page":         {"2"},
+		"search":       {"budi"},
+	}
+	want := "access_token=%5BREDACTED%5D&code=%5BREDACTED%5D&page=2&search=budi"
+	if got := RedactQuery(values); got != want {
+		t.Errorf("RedactQuery = %q, want %q", got, want)
+	}
+	if values.Get("code") != "abc" {
+		t.Error("RedactQuery modified its input")
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"i18n"
+)
+
+// Authentication counters, shared by the services so dashboards work for both
+var (
+	// LoginAttempts is labeled with the login method (password, magic_link) and the Outcome
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_attempts_total",
+		Help: "Login attempts by method and outcome.",
+	}, []string{"method", "outcome"})
+
+	// Registrations is labeled with the Outcome
+	Registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "User registrations by outcome.",
+	}, []string{"outcome"})
+
+	// TokensIssued is labeled with the token type: access, refresh or id
+	TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_tokens_issued_total",
+		Help: "Tokens issued by type.",
+	}, []string{"type"})
+)
+
+// Outcome labels the result of an operation: "success" for nil, the catalog code of an
+// *i18n.Error such as "account.locked", otherwise "error". Codes keep the label values bounded.
+func Outcome(err error) string {
+	if err == nil {
+		return "success"
+	}
+	var coded *i18n.Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return "error"
+}
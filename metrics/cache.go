@@ -0,0 +1,46 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// CacheStats is a snapshot of the counters of an in-memory cache
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+var (
+	cacheHitsDesc      = prometheus.NewDesc("cache_hits_total", "Cache lookups that found a fresh entry.", []string{"cache"}, nil)
+	cacheMissesDesc    = prometheus.NewDesc("cache_misses_total", "Cache lookups that found no entry or an expired one.", []string{"cache"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc("cache_evictions_total", "Entries dropped because the cache was full.", []string{"cache"}, nil)
+	cacheEntriesDesc   = prometheus.NewDesc("cache_entries", "Entries currently in the cache.", []string{"cache"}, nil)
+)
+
+// RegisterCache exports the stats of a cache labeled with its name. stats is called on every
+// scrape, so the cache keeps its own counters and nothing is recorded twice.
+func RegisterCache(name string, stats func() CacheStats) error {
+	return prometheus.Register(cacheCollector{name: name, stats: stats})
+}
+
+type cacheCollector struct {
+	name  string
+	stats func() CacheStats
+}
+
+// Describe implements prometheus.Collector
+func (c cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheEntriesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(s.Hits), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(s.Misses), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(s.Evictions), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(s.Size), c.name)
+}
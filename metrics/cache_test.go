@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterCache(t *testing.T) {
+	stats := CacheStats{Hits: 7, Misses: 3, Evictions: 1, Size: 5}
+	if err := RegisterCache("test", func() CacheStats { return stats }); err != nil {
+		t.Fatalf("RegisterCache: %v", err)
+	}
+
+	expected := `
+# HELP cache_entries Entries currently in the cache.
+# TYPE cache_entries gauge
+cache_entries{cache="test"} 5
+# HELP cache_evictions_total Entries dropped because the cache was full.
+# TYPE cache_evictions_total counter
+cache_evictions_total{cache="test"} 1
+# HELP cache_hits_total Cache lookups that found a fresh entry.
+# TYPE cache_hits_total counter
+cache_hits_total{cache="test"} 7
+# HELP cache_misses_total Cache lookups that found no entry or an expired one.
+# TYPE cache_misses_total counter
+cache_misses_total{cache="test"} 3
+`
+	names := []string{"cache_entries", "cache_evictions_total", "cache_hits_total", "cache_misses_total"}
+	if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), names...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stats are read again on every scrape
+	stats.Hits = 8
+	expected = `
+# HELP cache_hits_total Cache lookups that found a fresh entry.
+# TYPE cache_hits_total counter
+cache_hits_total{cache="test"} 8
+`
+	if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "cache_hits_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of GORM queries by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "GORM queries that failed by operation and table, not counting record not found.",
+	}, []string{"operation", "table"})
+)
+
+const startKey = "metrics:start"
+
+// GORMPlugin records the duration and errors of every query and exports the stats of the
+// connection pool as go_sql_* metrics labeled with the database name
+type GORMPlugin struct {
+	DBName string
+}
+
+// Name implements gorm.Plugin
+func (GORMPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin
+func (p GORMPlugin) Initialize(db *gorm.DB) error {
+	c := db.Callback()
+	for _, err := range []error{
+		c.Create().Before("gorm:create").Register("metrics:before_create", before),
+		c.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		c.Query().Before("gorm:query").Register("metrics:before_query", before),
+		c.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		c.Update().Before("gorm:update").Register("metrics:before_update", before),
+		c.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		c.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		c.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		c.Row().Before("gorm:row").Register("metrics:before_row", before),
+		c.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		c.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		c.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, p.DBName))
+}
+
+func before(tx *gorm.DB) {
+	tx.InstanceSet(startKey, time.Now())
+}
+
+func after(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		start, ok := tx.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		queryDuration.WithLabelValues(operation, table).Observe(time.Since(start.(time.Time)).Seconds())
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package metrics exposes Prometheus metrics of the services at GET /metrics: request latency per
+// route and status, GORM query durations and errors, database pool stats, the Go runtime, in-memory
+// cache stats, and domain counters such as login outcomes and issued tokens.
+//
+// Metrics are registered with the default Prometheus registry, so a service exposes everything
+// its packages record.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PathMetrics is the endpoint scraped by Prometheus
+const PathMetrics = "/metrics"
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	requestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+)
+
+// Register adds GET /metrics to e
+func Register(e *echo.Echo) {
+	e.GET(PathMetrics, echo.WrapHandler(promhttp.Handler()))
+}
+
+// Middleware records the latency of every request. Install it before echo's Recover middleware
+// so requests that panic are recorded with their 500 status.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			requestsInFlight.Inc()
+			defer requestsInFlight.Dec()
+
+			// Status dari error baru diketahui setelah error handler menulis response
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := strconv.Itoa(c.Response().Status)
+			requestDuration.WithLabelValues(method(c.Request().Method), route(c), status).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// route returns the route template of the request, e.g. /users/:id, so label values stay
+// bounded. Requests that match no route share "*".
+func route(c echo.Context) string {
+	if path := c.Path(); path != "" {
+		return path
+	}
+	return "*"
+}
+
+// method keeps arbitrary methods sent by clients out of the labels
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "OTHER"
+}
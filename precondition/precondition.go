@@ -0,0 +1,79 @@
+// Package precondition implements optimistic concurrency for records that carry a version
+// number. The version is sent to clients as a strong ETag, and a write whose If-Match names an
+// older version is refused with 412 Precondition Failed instead of silently overwriting the
+// newer change, as described in RFC 9110 section 13.1.1.
+package precondition
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+)
+
+// Headers used for conditional requests
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+var (
+	// ErrRequired is returned by Check when the request has no If-Match but the endpoint needs one
+	ErrRequired = i18n.NewError("precondition.required")
+	// ErrFailed means the record changed since the client read it. Stores return it too when
+	// their versioned update matched no row.
+	ErrFailed = i18n.NewError("precondition.failed")
+)
+
+// ETag returns the entity tag of a record version
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag announces the current version of the record in the response
+func SetETag(c echo.Context, version int) {
+	c.Response().Header().Set(HeaderETag, ETag(version))
+}
+
+// Check compares the If-Match header of the request with the current version of the record. A
+// request without If-Match gets ErrRequired if required is true and passes otherwise.
+func Check(c echo.Context, version int, required bool) error {
+	ifMatch := strings.Join(c.Request().Header.Values(HeaderIfMatch), ",")
+	if strings.TrimSpace(ifMatch) == "" {
+		if required {
+			return ErrRequired
+		}
+		return nil
+	}
+	if !Matches(ifMatch, version) {
+		return ErrFailed
+	}
+	return nil
+}
+
+// Matches reports whether an If-Match header value names version. "*" matches every version and
+// weak tags never match, since If-Match uses the strong comparison.
+func Matches(ifMatch string, version int) bool {
+	current := ETag(version)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes err returned by Check, or ErrFailed from a store, as an error response: 428 for
+// ErrRequired and 412 otherwise
+func Write(c echo.Context, err error) error {
+	status := http.StatusPreconditionFailed
+	if errors.Is(err, ErrRequired) {
+		status = http.StatusPreconditionRequired
+	}
+	return envelope.Error(c, status, envelope.StatusText(c, status), envelope.Detail(i18n.Err(c, err), HeaderIfMatch))
+}
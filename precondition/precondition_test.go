@@ -0,0 +1,78 @@
+package precondition
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    []string
+		required   bool
+		wantErr    error
+		wantStatus int // Status written by Write, 0 when Check passes
+	}{
+		{name: "current version", ifMatch: []string{`"3"`}, wantErr: nil},
+		{name: "older version", ifMatch: []string{`"2"`}, wantErr: ErrFailed, wantStatus: http.StatusPreconditionFailed},
+		{name: "wildcard", ifMatch: []string{"*"}, wantErr: nil},
+		{name: "list containing the current version", ifMatch: []string{`"1", "3"`}, wantErr: nil},
+		{name: "repeated headers", ifMatch: []string{`"1"`, `"3"`}, wantErr: nil},
+		{name: "weak tag never matches", ifMatch: []string{`W/"3"`}, wantErr: ErrFailed, wantStatus: http.StatusPreconditionFailed},
+		{name: "unquoted tag", ifMatch: []string{"3"}, wantErr: ErrFailed, wantStatus: http.StatusPreconditionFailed},
+		{name: "missing and optional", wantErr: nil},
+		{name: "missing and required", required: true, wantErr: ErrRequired, wantStatus: http.StatusPreconditionRequired},
+		{name: "blank and required", ifMatch: []string{" "}, required: true, wantErr: ErrRequired, wantStatus: http.StatusPreconditionRequired},
+		{name: "older version and required", ifMatch: []string{`"2"`}, required: true, wantErr: ErrFailed, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/users/1", nil)
+			for _, value := range tt.ifMatch {
+				req.Header.Add(HeaderIfMatch, value)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			err := Check(c, 3, tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if err := Write(c, err); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWriteStoreConflict(t *testing.T) {
+	// Stores return ErrFailed when their versioned update matched no row, which is also a 412
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPut, "/users/1", nil), rec)
+	if err := Write(c, ErrFailed); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/users/1", nil), rec)
+	SetETag(c, 7)
+	if got := rec.Header().Get(HeaderETag); got != `"7"` {
+		t.Errorf("ETag = %s, want %q", got, `"7"`)
+	}
+}
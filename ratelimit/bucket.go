@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result is the state of a bucket after taking a token from it
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // Whole tokens left
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, zero if Allowed
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take refills the bucket of key for the time since it was last used and takes one token
+	// from it if there is one
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// bucket is the token bucket algorithm shared by the stores: a bucket holds up to Limit tokens
+// and refills continuously at Limit tokens per Period
+type bucket struct {
+	tokens  float64
+	updated time.Time // Zero for a new bucket, which starts full
+}
+
+func (b *bucket) take(policy Policy, now time.Time) Result {
+	limit := float64(policy.Limit)
+	rate := limit / policy.Period.Seconds()
+	if b.updated.IsZero() {
+		b.tokens = limit
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(limit, b.tokens+elapsed*rate)
+	}
+	if now.After(b.updated) {
+		b.updated = now
+	}
+
+	result := Result{Limit: policy.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((limit - b.tokens) / rate)
+	return result
+}
+
+// fullAt is when the bucket is full again, after that it is the same as a new bucket and can be
+// deleted
+func (b *bucket) fullAt(policy Policy) time.Time {
+	rate := float64(policy.Limit) / policy.Period.Seconds()
+	return b.updated.Add(seconds((float64(policy.Limit) - b.tokens) / rate))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// sweepInterval is how often stores delete buckets that are full again
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory, every replica counts on its own. Use
+// PostgresStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	nextSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, b := range s.buckets {
+			if now.After(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	result := b.take(policy, now)
+	b.full = b.fullAt(policy)
+	return result, nil
+}
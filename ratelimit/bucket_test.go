@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	// 2 requests at once, refilled at one request every 5 seconds
+	policy := Policy{Limit: 2, Period: 10 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name           string
+		at             time.Duration // Since start
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantReset      time.Duration
+	}{
+		{name: "new bucket starts full", at: 0, wantAllowed: true, wantRemaining: 1, wantReset: 5 * time.Second},
+		{name: "burst up to the limit", at: 0, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+		{name: "empty bucket", at: 0, wantRetryAfter: 5 * time.Second, wantReset: 10 * time.Second},
+		{name: "partial refill is not enough", at: 4 * time.Second, wantRetryAfter: time.Second, wantReset: 6 * time.Second},
+		{name: "one token refilled", at: 5 * time.Second, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+		{name: "clock going back refills nothing", at: 3 * time.Second, wantRetryAfter: 5 * time.Second, wantReset: 10 * time.Second},
+		{name: "refill is capped at the limit", at: time.Hour, wantAllowed: true, wantRemaining: 1, wantReset: 5 * time.Second},
+	}
+
+	var b bucket
+	for _, step := range steps {
+		result := b.take(policy, start.Add(step.at))
+		if result.Allowed != step.wantAllowed || result.Remaining != step.wantRemaining ||
+			result.RetryAfter != step.wantRetryAfter || result.Reset != step.wantReset || result.Limit != policy.Limit {
+			t.Fatalf("%s: take = %+v, want allowed=%v remaining=%d retryAfter=%v reset=%v",
+				step.name, result, step.wantAllowed, step.wantRemaining, step.wantRetryAfter, step.wantReset)
+		}
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Limit: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		key         string
+		wantAllowed bool
+	}{
+		{"ip:10.0.0.1 POST /login", true},
+		{"ip:10.0.0.1 POST /login", false},
+		{"ip:10.0.0.2 POST /login", true},    // Every client has its own bucket
+		{"ip:10.0.0.1 POST /register", true}, // and every route too
+	} {
+		result, err := store.Take(ctx, tt.key, policy)
+		if err != nil {
+			t.Fatalf("Take(%q): %v", tt.key, err)
+		}
+		if result.Allowed != tt.wantAllowed {
+			t.Errorf("Take(%q) allowed = %v, want %v", tt.key, result.Allowed, tt.wantAllowed)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bucket is a row of the rate_limit_buckets table
+type Bucket struct {
+	Key       string    `gorm:"primaryKey;size:512"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime:false"`
+	FullAt    time.Time `gorm:"not null;index"` // The row can be deleted after this
+}
+
+// TableName keeps the table name independent of the struct name
+func (Bucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so replicas share their counters
+type PostgresStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take implements Store. The row of the bucket is locked while it is updated, so concurrent
+// requests of all replicas take their tokens one after another.
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := time.Now()
+	s.sweep(ctx, now)
+
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Bucket baru dibuat dulu agar selalu ada row yang bisa dikunci
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Bucket{Key: key, Tokens: float64(policy.Limit), UpdatedAt: now, FullAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var row Bucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).Take(&row).Error; err != nil {
+			return err
+		}
+
+		b := bucket{tokens: row.Tokens, updated: row.UpdatedAt}
+		result = b.take(policy, now)
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"tokens":     b.tokens,
+			"updated_at": b.updated,
+			"full_at":    b.fullAt(policy),
+		}).Error
+	})
+	return result, err
+}
+
+// sweep deletes buckets that are full again at most once per sweepInterval. Failures are
+// ignored, the next sweep tries again.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Before(s.nextSweep) {
+		s.mu.Unlock()
+		return
+	}
+	s.nextSweep = now.Add(sweepInterval)
+	s.mu.Unlock()
+
+	s.db.WithContext(ctx).Where("full_at < ?", now).Delete(&Bucket{})
+}
@@ -0,0 +1,168 @@
+// Package ratelimit limits how often clients call each route with token buckets. Every route has
+// a Policy, set centrally in Config, and every client gets its own bucket per route: anonymous
+// requests are counted by IP address, authenticated ones also by user or API key.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers of the IETF RateLimit header fields draft. Rejected requests get 429 Too Many Requests
+// with Retry-After in the envelope format.
+package ratelimit
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"envelope"
+	"github.com/labstack/echo/v4"
+	"i18n"
+	"jwtauth"
+)
+
+// Response headers
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset" // Seconds until the bucket is full again
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Policy allows Limit requests at once, refilled at Limit requests per Period. A zero Limit
+// disables limiting.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// String returns the policy in the RateLimit-Policy format, for example 10;w=60
+func (p Policy) String() string {
+	return strconv.Itoa(p.Limit) + ";w=" + strconv.Itoa(int(p.Period/time.Second))
+}
+
+// Config configures a Limiter
+type Config struct {
+	Store Store
+
+	// Routes maps "METHOD /path" as registered with echo, e.g. "POST /login", to its policy
+	Routes map[string]Policy
+
+	// Default applies to routes missing from Routes
+	Default Policy
+
+	// Keys maps a route to a KeyFunc that identifies its caller. Middleware counts the requests
+	// it identifies by that key instead of by IP address.
+	Keys map[string]KeyFunc
+}
+
+// KeyFunc identifies the caller of a request that WithAuth does not cover, such as an OAuth
+// client authenticating with its credentials, so callers behind one address like a resource
+// server do not share a bucket. It returns an empty id for requests it cannot identify, which
+// are counted by IP. It must only return identities it verified: a forged one would drain the
+// bucket of another caller.
+type KeyFunc func(c echo.Context) (kind, id string)
+
+// Limiter applies the policies of a Config
+type Limiter struct {
+	cfg Config
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg}
+}
+
+// Middleware limits every request by client IP address, as reported by echo.Context.RealIP, or
+// by the key of Config.Keys for its route. Set e.IPExtractor with IPExtractor, echo otherwise
+// trusts X-Forwarded-For sent by any client. Install it with e.Use so routes without
+// authentication are covered too.
+func (l *Limiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if key, ok := l.cfg.Keys[routeOf(c)]; ok {
+				if kind, id := key(c); id != "" {
+					return l.limit(c, next, kind, id)
+				}
+			}
+			return l.limit(c, next, "ip", c.RealIP())
+		}
+	}
+}
+
+// WithAuth returns auth followed by limiting by the authenticated principal: a bucket per API
+// key for API key requests, otherwise per user. Use it in place of auth for authenticated
+// routes, the IP limit of Middleware still applies.
+func (l *Limiter) WithAuth(auth echo.MiddlewareFunc) echo.MiddlewareFunc {
+	byPrincipal := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := jwtauth.PrincipalFromContext(c)
+			if !ok {
+				return next(c)
+			}
+			if principal.APIKeyID != "" {
+				return l.limit(c, next, "api_key", principal.APIKeyID)
+			}
+			return l.limit(c, next, "user", principal.ID)
+		}
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return auth(byPrincipal(next))
+	}
+}
+
+// Policy returns the policy of the route of the request
+func (l *Limiter) Policy(c echo.Context) Policy {
+	if policy, ok := l.cfg.Routes[routeOf(c)]; ok {
+		return policy
+	}
+	return l.cfg.Default
+}
+
+func (l *Limiter) limit(c echo.Context, next echo.HandlerFunc, kind, id string) error {
+	policy := l.Policy(c)
+	if policy.Limit <= 0 || policy.Period <= 0 {
+		return next(c)
+	}
+
+	result, err := l.cfg.Store.Take(c.Request().Context(), kind+":"+id+" "+routeOf(c), policy)
+	if err != nil {
+		// Limiter yang gagal tidak boleh membuat service ikut tidak bisa dipakai
+		slog.ErrorContext(c.Request().Context(), "ratelimit: store failed", "error", err)
+		return next(c)
+	}
+
+	setHeaders(c, policy, result)
+	if !result.Allowed {
+		retryAfter := int(result.RetryAfter.Round(time.Second) / time.Second)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return envelope.Error(c, http.StatusTooManyRequests, envelope.StatusText(c, http.StatusTooManyRequests),
+			envelope.Detail(i18n.T(c, "ratelimit.exceeded", retryAfter), kind))
+	}
+	return next(c)
+}
+
+// setHeaders announces the bucket with the fewest remaining requests, a request can be counted
+// by both the IP and the principal limit
+func setHeaders(c echo.Context, policy Policy, result Result) {
+	header := c.Response().Header()
+	if previous := header.Get(HeaderRemaining); previous != "" {
+		if remaining, err := strconv.Atoi(previous); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+	header.Set(HeaderLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderReset, strconv.Itoa(int((result.Reset+time.Second-1)/time.Second)))
+	header.Set(HeaderPolicy, policy.String())
+}
+
+// routeOf returns "METHOD /path" of the matched route, requests that match no route share "*"
+func routeOf(c echo.Context) string {
+	path := c.Path()
+	if path == "" {
+		path = "*"
+	}
+	return c.Request().Method + " " + path
+}
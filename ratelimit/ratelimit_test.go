@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	limiter := New(Config{
+		Store: NewMemoryStore(),
+		Routes: map[string]Policy{
+			"POST /login":  {Limit: 2, Period: time.Minute},
+			"GET /healthz": {},
+		},
+		Default: Policy{Limit: 100, Period: time.Minute},
+	})
+	e.Use(limiter.Middleware())
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.POST("/login", ok)
+	e.GET("/healthz", ok)
+
+	tests := []struct {
+		name          string
+		method, path  string
+		remoteAddr    string
+		wantStatus    int
+		wantRemaining string
+		wantPolicy    string
+	}{
+		{"first request", http.MethodPost, "/login", "10.0.0.1:1234", http.StatusNoContent, "1", "2;w=60"},
+		{"second request", http.MethodPost, "/login", "10.0.0.1:1234", http.StatusNoContent, "0", "2;w=60"},
+		{"over the limit", http.MethodPost, "/login", "10.0.0.1:1234", http.StatusTooManyRequests, "0", "2;w=60"},
+		{"another client", http.MethodPost, "/login", "10.0.0.2:1234", http.StatusNoContent, "1", "2;w=60"},
+		{"zero policy is not limited", http.MethodGet, "/healthz", "10.0.0.1:1234", http.StatusNoContent, "", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+		if got := rec.Header().Get(HeaderRemaining); got != tt.wantRemaining {
+			t.Errorf("%s: %s = %q, want %q", tt.name, HeaderRemaining, got, tt.wantRemaining)
+		}
+		if got := rec.Header().Get(HeaderPolicy); got != tt.wantPolicy {
+			t.Errorf("%s: %s = %q, want %q", tt.name, HeaderPolicy, got, tt.wantPolicy)
+		}
+		if tt.wantStatus == http.StatusTooManyRequests && rec.Header().Get(HeaderRetryAfter) != "30" {
+			t.Errorf("%s: %s = %q, want 30", tt.name, HeaderRetryAfter, rec.Header().Get(HeaderRetryAfter))
+		}
+	}
+}
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{name: "no proxies ignores X-Forwarded-For", remoteAddr: "203.0.113.7:1234", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy forwards the client", proxies: []string{"10.0.0.1"}, remoteAddr: "10.0.0.1:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "spoofed entry before the proxy is skipped", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234",
+			xff: "192.0.2.9, 198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer cannot spoof", proxies: []string{"10.0.0.1"}, remoteAddr: "203.0.113.7:1234", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "private networks are not trusted by default", proxies: []string{"10.0.0.1"}, remoteAddr: "192.168.1.5:1234", xff: "198.51.100.1", want: "192.168.1.5"},
+		{name: "IPv6 proxy", proxies: []string{"2001:db8::1"}, remoteAddr: "[2001:db8::1]:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract, err := IPExtractor(tt.proxies)
+			if err != nil {
+				t.Fatalf("IPExtractor: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.xff)
+			if got := extract(req); got != tt.want {
+				t.Errorf("IP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := IPExtractor([]string{"not-an-ip"}); err == nil {
+		t.Error("IPExtractor accepted an invalid proxy")
+	}
+}
+
+func TestMiddlewareKeys(t *testing.T) {
+	e := echo.New()
+	limiter := New(Config{
+		Store:  NewMemoryStore(),
+		Routes: map[string]Policy{"POST /oauth/introspect": {Limit: 1, Period: time.Minute}},
+		Keys: map[string]KeyFunc{
+			// Only the client with the right secret is identified
+			"POST /oauth/introspect": func(c echo.Context) (string, string) {
+				if id, secret, ok := c.Request().BasicAuth(); ok && secret == "secret" {
+					return "client", id
+				}
+				return "", ""
+			},
+		},
+	})
+	e.Use(limiter.Middleware())
+	e.POST("/oauth/introspect", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		client     string
+		secret     string
+		wantStatus int
+	}{
+		{"client is counted by its ID", "client-1", "secret", http.StatusOK},
+		{"anonymous request from the same IP has its own bucket", "", "", http.StatusOK},
+		{"anonymous request over the IP limit", "", "", http.StatusTooManyRequests},
+		{"wrong secret is counted by IP", "client-2", "wrong", http.StatusTooManyRequests},
+		{"another client from the same IP", "client-2", "secret", http.StatusOK},
+		{"client over its own limit", "client-1", "secret", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if tt.client != "" {
+			req.SetBasicAuth(tt.client, tt.secret)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+	}
+}
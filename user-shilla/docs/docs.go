@@ -0,0 +1,8 @@
+package docs
+
+import _ "embed"
+
+// OpenAPI adalah dokumen OpenAPI 3 service ini, disajikan di /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -1,52 +1,190 @@
 package domains
 
 import (
+	"context"
 	"time"
 	"errors"
+	"strings"
+
+	"auditlog"
+	"i18n"
+	"jwtauth"
+	"precondition"
 )
 
 type User struct {
-	ID        string `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
-	Username  string `gorm:"unique;not null" json:"username"`
-	Email     string `gorm:"unique;not null" json:"email"`
-	Password  string `gorm:"not null" json:"-"`
-	CreatedAt time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
-	DeletedAt *time.Time `json:"deleted_at" gorm:"index"`
+	ID              string     `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Username        string     `gorm:"not null" json:"username"` // Unik tanpa membedakan huruf besar/kecil di antara user aktif
+	Email           string     `gorm:"not null" json:"email"`
+	Password        string     `gorm:"not null" json:"-"`
+	Role            string     `gorm:"not null;default:user" json:"role"`
+	Status          string     `gorm:"not null;default:active" json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	DeletedAt       *time.Time `json:"deleted_at" gorm:"index"`
+	PurgedAt        *time.Time `json:"purged_at,omitempty"` // Data pribadi sudah dianonimkan, user tidak bisa di-restore
+	Version         int        `gorm:"not null;default:1" json:"-"` // Naik setiap kali row diubah, dikirim ke client sebagai ETag
+}
+
+// UserStatusChange adalah satu perubahan status akun oleh admin, tidak pernah diubah setelah dibuat
+type UserStatusChange struct {
+	ID         string    `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	UserID     string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	FromStatus string    `gorm:"not null" json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	Reason     string    `gorm:"not null" json:"reason"`
+	ChangedBy  string    `json:"changed_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (UserStatusChange) TableName() string {
+	return "user_status_history"
+}
+
+// Status values stored in User.Status. User yang dihapus tetap ditandai lewat DeletedAt.
+const (
+	StatusPending   = "pending"   // Menunggu verifikasi, belum boleh login
+	StatusActive    = "active"
+	StatusSuspended = "suspended" // Dibekukan admin, misalnya karena denda belum dibayar
+	StatusLocked    = "locked"    // Dikunci karena alasan keamanan
+)
+
+// ValidStatus memeriksa apakah status bisa di-set lewat endpoint admin
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusActive, StatusSuspended, StatusLocked:
+		return true
+	}
+	return false
+}
+
+// StatusError mengembalikan error jika status akun tidak mengizinkan login atau memakai token.
+// Status yang tidak dikenal ikut ditolak.
+func (u *User) StatusError() error {
+	switch u.Status {
+	case StatusActive:
+		return nil
+	case StatusPending:
+		return ErrAccountPending
+	case StatusSuspended:
+		return ErrAccountSuspended
+	}
+	return ErrAccountLocked
 }
 
 type UserRepository interface{
-	Create(user *User) error
-	Update(user *User) error
-	Delete(id string) error
-	GetByUsername(username string) (*User, error)
-	GetByID(id string) (*User, error) 
-	GetAll() ([]User, error)
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id string) error
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByID(ctx context.Context, id string) (*User, error) 
+	GetAll(ctx context.Context) ([]User, error)
+	GetDeleted(ctx context.Context) ([]User, error)
+	GetDeletedBefore(ctx context.Context, cutoff time.Time) ([]User, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) error
+	ChangeStatus(ctx context.Context, id, status, reason, changedBy string) (*UserStatusChange, error)
+	GetStatusHistory(ctx context.Context, id string) ([]UserStatusChange, error)
 }
 
 type UserUsecase interface{
-	Register(username, email, password string) (*User,  error)
-	Update(id string, username, email, password string)error
-	Delete(id string) (*User, error)
-	Validate(username, password string) (string, error)
-	GetByUsername(username string) (*User, error)
-	GetByID(id string) (*User, error) 
-	GetAll() ([]User, error)
+	Register(ctx context.Context, username, email, password string, actor auditlog.Actor) (*User,  error)
+	// version adalah versi user yang terakhir dilihat client, 0 berarti tanpa pengecekan versi
+	Update(ctx context.Context, id string, version int, username, email, password string, actor auditlog.Actor)error
+	Delete(ctx context.Context, id string, actor auditlog.Actor) (*User, error)
+	Authenticate(ctx context.Context, username, password string, actor auditlog.Actor) (*User, error)
+	Validate(ctx context.Context, username, password string) error
+	VerifyPassword(ctx context.Context, id, password string) error
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByID(ctx context.Context, id string) (*User, error) 
+	GetAll(ctx context.Context) ([]User, error)
+	ListDeleted(ctx context.Context) ([]User, error)
+	Restore(ctx context.Context, id string, actor auditlog.Actor) error
+	Purge(ctx context.Context, id string, actor auditlog.Actor) error
+	PurgeExpired(ctx context.Context, retention time.Duration) (int, error)
+	ChangeStatus(ctx context.Context, id, status, reason string, actor auditlog.Actor) (*UserStatusChange, error)
+	StatusHistory(ctx context.Context, id string) ([]UserStatusChange, error)
+}
+type DeleteRequest struct {
+    ID string `json:"id" validate:"required"` // ID yang diterima dari request body
+}
+var ErrUserNotFound = i18n.NewError("user.not_found")
+// ErrInvalidCredentials dipakai untuk username yang tidak ada maupun password yang salah,
+// agar username yang terdaftar tidak bisa ditebak dari response login
+var ErrInvalidCredentials = i18n.NewError("auth.invalid_credentials")
+// ErrCurrentPasswordInvalid dikembalikan VerifyPassword jika password saat ini salah
+var ErrCurrentPasswordInvalid = i18n.NewError("password.current_invalid")
+var ErrUserNotDeleted = i18n.NewError("user.not_deleted")
+
+// Dikembalikan saat username atau email sudah dipakai user lain yang belum dihapus
+var ErrDuplicateUsername = i18n.NewError("user.username_taken")
+var ErrDuplicateEmail = i18n.NewError("user.email_taken")
+
+// Dikembalikan saat user sudah diubah request lain sejak dibaca, lihat If-Match pada PATCH /users/:id
+var ErrVersionConflict = precondition.ErrFailed
+
+// Dikembalikan saat login atau memakai token dengan akun yang statusnya bukan active
+var ErrAccountPending = i18n.NewError("account.pending")
+var ErrAccountSuspended = i18n.NewError("account.suspended")
+var ErrAccountLocked = i18n.NewError("account.locked")
+
+// Dikembalikan oleh ChangeStatus untuk input yang tidak valid
+var ErrInvalidStatus = i18n.NewError("user.invalid_status")
+var ErrStatusReasonRequired = i18n.NewError("user.status_reason_required")
+
+// FieldError adalah error validasi usecase untuk satu field request
+type FieldError struct {
+	Field string
+	Err   error
 }
-type Response struct {
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
-    Errors []ErrorDetail `json:"errors"`
-	Code    int         `json:"code"`
+
+// ValidationErrors berisi semua error validasi dari usecase, handler menerjemahkannya per field
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fe := range v {
+		messages = append(messages, fe.Err.Error())
+	}
+	return strings.Join(messages, "; ")
 }
-type ErrorDetail struct {
-    Message  string `json:"message"`
-    Parameter string `json:"parameter"`
+
+// IsAccountStatusError memeriksa apakah err berasal dari status akun yang tidak aktif
+func IsAccountStatusError(err error) bool {
+	return errors.Is(err, ErrAccountPending) || errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrAccountLocked)
 }
 
-type DeleteRequest struct {
-    ID string `json:"id"` // ID yang diterima dari request body
+// Action yang dicatat ke audit_events
+const (
+	AuditUserRegister       = "user.register"
+	AuditUserLogin          = "user.login"
+	AuditUserUpdate         = "user.update"
+	AuditUserPasswordChange = "user.password_change"
+	AuditUserDelete         = "user.delete"
+	AuditUserRestore        = "user.restore"
+	AuditUserPurge          = "user.purge"
+	AuditUserStatusChange   = "user.status_change"
+)
+
+// Role values stored in User.Role
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Principal is the authenticated user placed in the echo.Context by the JWT middleware
+type Principal = jwtauth.Principal
+
+type ProfileResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
-var ErrUserNotFound = errors.New("user not found")
 
 
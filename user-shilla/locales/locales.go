@@ -0,0 +1,8 @@
+package locales
+
+import "embed"
+
+// Files berisi katalog pesan service ini per bahasa (en.json, id.json), dimuat dengan i18n.MustLoad
+//
+//go:embed *.json
+var Files embed.FS
@@ -1,43 +1,210 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"project-golang-crud/docs"
 	"project-golang-crud/domains"
+	"project-golang-crud/locales"
 	"project-golang-crud/pkg/config"
 	"project-golang-crud/pkg/delivery"
 	"project-golang-crud/pkg/repository"
 	"project-golang-crud/pkg/usecase"
 
+	"project-golang-crud/middleware"
+	"sync"
+	"time"
+
+	"apidocs"
+	"auditlog"
+	"binding"
+	"envelope"
+	"health"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"i18n"
+	"idempotency"
+	"ratelimit"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	"gorm.io/gorm"
+	"jwtauth"
+	"logging"
+	"metrics"
 )
 
 func main() {
+	config.LoadEnv()
+	// Log JSON ke stdout, setiap baris dari satu request membawa request_id yang sama
+	config.SetupLogging()
+
 	db := config.ConnectDB()
 	if db == nil {
 		log.Fatal("Database connection failed")
 	}
-	log.Println("Database connection successfully")
+	slog.Info("Database connection successfully")
+	// Durasi dan error query serta statistik pool koneksi untuk /metrics
+	if err := db.Use(metrics.GORMPlugin{DBName: "user-shilla"}); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
+	// Pool koneksi ditutup paling akhir, setelah semua yang memakai database berhenti
+	defer closeDB(db)
+
+	// /healthz dan /readyz sudah menjawab selama inisialisasi, /readyz baru 200 setelah server.Serve
+	checker := health.New(0)
+	server, err := health.Start(config.LoadServerConfig(), checker)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+
+	// Katalog pesan service ini, melengkapi katalog bawaan package i18n
+	i18n.MustLoad(locales.Files, ".")
 
 	e := echo.New()
+	// IP client untuk rate limit dan log, X-Forwarded-For hanya dibaca dari TRUSTED_PROXIES
+	e.IPExtractor = config.LoadIPExtractor()
 
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	// Paling awal agar middleware lain dan query database sudah membawa request ID
+	e.Use(logging.Middleware())
+	// Sebelum Recover agar request yang panic tercatat sebagai 500
+	e.Use(metrics.Middleware())
+	e.Use(echoMiddleware.Recover())
+	// Bahasa response dari header Accept-Language, default en
+	e.Use(i18n.Middleware())
+	// Batas request per IP, rute yang butuh login juga dibatasi per user atau API key lewat auth
+	limiter := ratelimit.New(config.LoadRateLimitConfig(db))
+	e.Use(limiter.Middleware())
+	// Retry POST dengan Idempotency-Key yang sama mendapat response pertama, tidak diproses ulang
+	e.Use(idempotency.Middleware(config.LoadIdempotencyConfig(db)))
+	// Error dari echo sendiri (rute tidak ada, method salah, panic) memakai format response yang sama
+	e.HTTPErrorHandler = envelope.HTTPErrorHandler
+	// Tag validate pada request body dicek oleh binding.Bind
+	e.Validator = binding.NewValidator()
 
 	migrate(db)
 
 	userRepo := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepo)
-	delivery.NewUserHandler(e,userUsecase)
+	// Audit log ditulis lewat antrean di memori agar database yang lambat tidak menggagalkan request
+	auditStore := auditlog.NewStore(db)
+	auditLogger := auditlog.NewLogger(auditStore, auditlog.Config{})
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := auditLogger.Close(ctx); err != nil {
+			slog.Error("Failed to flush audit log", "error", err)
+		}
+	}()
+
+	userUsecase := usecase.NewUserUsecase(userRepo, auditLogger)
+
+	tokens := config.LoadJWTConfig()
+	cookies := config.LoadCookieConfig()
+	authConfig := jwtauth.Config{
+		Token:       tokens,
+		Cookies:     cookies,
+		Resolve:     middleware.PrincipalResolver(userUsecase),
+		RenderError: middleware.RenderError,
+	}
+	// Token dari auth-user-api diperiksa lewat introspection, user-nya tidak ada di database ini
+	if introspection := config.LoadIntrospectionConfig(); introspection != nil {
+		authConfig.Introspector = jwtauth.NewIntrospector(*introspection)
+		authConfig.Resolve = jwtauth.ClaimsPrincipal
+	}
+	auth := limiter.WithAuth(jwtauth.Middleware(authConfig))
+
+	checker.Add("database", health.Ping(db))
+	checker.Add("migrations", health.Migrated(db, tables...))
+	checker.Add("keys", health.Loaded("JWT signing key", func() bool {
+		return len(tokens.SigningKey) > 0
+	}))
+	registerRoutes(e, userUsecase, auditStore, auditLogger, tokens, cookies, auth, checker)
+
+	// User yang dihapus lebih lama dari USER_RETENTION di-purge otomatis, hanya jika diaktifkan
+	if retention, interval := config.LoadUserRetention(); retention > 0 {
+		stopPurger := startRetentionPurger(userUsecase, retention, interval)
+		defer stopPurger()
+	}
+
+	// Inisialisasi selesai, request diteruskan ke e sampai SIGINT atau SIGTERM
+	server.Serve(e)
+	if err := server.Wait(); err != nil {
+		slog.Error("Server stopped", "error", err)
+	}
+}
 
-	e.Logger.Fatal(e.Start(":8082"))
+// registerRoutes mendaftarkan semua handler beserta dokumentasinya di /openapi.json dan /docs.
+// Rute baru juga harus ditambahkan ke docs/openapi.json, dicek oleh main_test.go.
+func registerRoutes(e *echo.Echo, u domains.UserUsecase, auditStore *auditlog.Store, auditLogger *auditlog.Logger, tokens jwtauth.TokenConfig, cookies *jwtauth.CookieConfig, auth echo.MiddlewareFunc, checker *health.Checker) {
+	// Token OAuth untuk rute admin juga harus punya scope admin, bukan hanya role admin
+	adminRole := jwtauth.RequireRole(domains.RoleAdmin, middleware.RenderError)
+	adminScope := jwtauth.RequireScope("admin", middleware.RenderError)
+	adminOnly := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return adminRole(adminScope(next))
+	}
+
+	apidocs.Register(e, docs.OpenAPI)
+	checker.Register(e)
+	metrics.Register(e)
+	delivery.NewUserHandler(e, u, tokens, cookies, auth)
+	delivery.NewAdminUserHandler(e, u, auth, adminOnly)
+	delivery.NewAuditHandler(e, auditStore, auditLogger, auth, adminOnly)
 }
 
+// startRetentionPurger menjalankan PurgeExpired secara berkala sampai stop dipanggil
+func startRetentionPurger(u domains.UserUsecase, retention, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := u.PurgeExpired(context.Background(), retention)
+				if err != nil {
+					slog.Error("Failed to purge expired users", "error", err)
+				}
+				if purged > 0 {
+					slog.Info("Purged expired users", "count", purged, "retention", retention)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// tables adalah model yang dibuat AutoMigrate, /readyz juga memeriksa semua kolomnya sudah ada
+var tables = []interface{}{&domains.User{}, &domains.UserStatusChange{}, &auditlog.Event{}, &idempotency.Record{}, &ratelimit.Bucket{}}
+
 func migrate(db *gorm.DB)  {
-	err := db.AutoMigrate(&domains.User{})
+	if err := repository.DropUserUniqueConstraints(db); err != nil {
+		log.Fatalf("Error dropping old user unique constraints: %v", err)
+	}
+	err := db.AutoMigrate(tables...)
 	if err != nil {
 		log.Fatalf("Error in database migration: %v", err)
 	}
-	log.Println("Database migration completed!")
-}
\ No newline at end of file
+	if err := repository.CreateUserUniqueIndexes(db); err != nil {
+		log.Fatalf("Error creating user unique indexes: %v", err)
+	}
+	// Trigger append-only dari migrations.sql, AutoMigrate tidak membuat trigger
+	if err := auditlog.InstallAppendOnly(db); err != nil {
+		log.Fatalf("Error making audit log append-only: %v", err)
+	}
+	slog.Info("Database migration completed!")
+}
+
+func closeDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
+}
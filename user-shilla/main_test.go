@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"apidocs"
+	"jwtauth"
+	"project-golang-crud/docs"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TestRoutesDocumented gagal jika ada rute yang didaftarkan registerRoutes tapi tidak ada di
+// docs/openapi.json, atau sebaliknya operasi di dokumen yang tidak punya rute lagi
+func TestRoutesDocumented(t *testing.T) {
+	e := echo.New()
+	// Middleware hanya dipanggil saat request masuk, jadi dependency kosong cukup untuk mendaftarkan rute.
+	// CookieConfig diisi agar rute /logout ikut terdaftar.
+	registerRoutes(e, nil, nil, nil, jwtauth.TokenConfig{}, &jwtauth.CookieConfig{}, nil, nil)
+
+	missing, err := apidocs.Undocumented(e, docs.OpenAPI)
+	if err != nil {
+		t.Fatalf("parse docs/openapi.json: %v", err)
+	}
+	for _, route := range missing {
+		t.Errorf("route %s is not documented in docs/openapi.json", route)
+	}
+
+	stale, err := apidocs.Unregistered(e, docs.OpenAPI)
+	if err != nil {
+		t.Fatalf("parse docs/openapi.json: %v", err)
+	}
+	for _, route := range stale {
+		t.Errorf("docs/openapi.json documents %s but no such route is registered", route)
+	}
+}
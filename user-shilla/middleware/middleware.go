@@ -2,74 +2,58 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"project-golang-crud/domains"
-	"strings"
 
-	"github.com/golang-jwt/jwt/v4"
+	"envelope"
 	"github.com/labstack/echo/v4"
+	"i18n"
+	"jwtauth"
 )
 
-// JWTMiddleware function
-func JWTMiddleware() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// Mendapatkan token dari header Authorization
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return c.JSON(http.StatusUnauthorized, domains.Response{
-					Message: "Authorization header missing",
-					Errors: []domains.ErrorDetail{
-						{
-							Message: "Authorization header is required",
-							Parameter: "Authorization",
-						},
-					},
-					Code: http.StatusUnauthorized,
-				})
-			}
+// GetPrincipal returns the principal set by the JWT middleware, if any
+func GetPrincipal(c echo.Context) (*domains.Principal, bool) {
+	return jwtauth.PrincipalFromContext(c)
+}
 
-			// Memeriksa apakah token menggunakan skema Bearer
-			tokenString := strings.Split(authHeader, " ")
-			if len(tokenString) != 2 || tokenString[0] != "Bearer" {
-				return c.JSON(http.StatusUnauthorized, domains.Response{
-					Message: "Invalid token format",
-					Errors: []domains.ErrorDetail{
-						{
-							Message:  "Token must be provided in Bearer <token> format",
-							Parameter: "Authorization",
-						},
-					},
-					Code: http.StatusUnauthorized,
-				})
-			}
+// PrincipalResolver memastikan user pemilik token masih ada, belum dihapus dan statusnya active
+func PrincipalResolver(u domains.UserUsecase) jwtauth.PrincipalResolver {
+	return func(c echo.Context, claims *jwtauth.Claims) (*jwtauth.Principal, error) {
+		user, err := u.GetByID(c.Request().Context(), claims.Subject)
+		if err != nil || user == nil || user.DeletedAt != nil {
+			return nil, jwtauth.Unauthorized("user_not_found", "The user for this token no longer exists")
+		}
+		if err := user.StatusError(); err != nil {
+			return nil, jwtauth.Forbidden("account_"+user.Status, "Account is "+user.Status+" - "+err.Error()).WithArgs(err)
+		}
 
-			// Parsing token JWT
-			secretKey := []byte(os.Getenv("JWT_SECRET"))
-			token, err := jwt.Parse(tokenString[1], func(token *jwt.Token) (interface{}, error) {
-				// Pastikan algoritma enkripsi token cocok dengan yang diharapkan
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, echo.NewHTTPError(http.StatusUnauthorized, "Unexpected signing method")
-				}
-				return secretKey, nil
-			})
+		return &jwtauth.Principal{
+			ID:       user.ID,
+			Username: user.Username,
+			Roles:    []string{user.Role},
+		}, nil
+	}
+}
 
-			// Jika token tidak valid
-			if err != nil || !token.Valid {
-				return c.JSON(http.StatusUnauthorized, domains.Response{
-					Message: "Invalid or expired token",
-					Errors: []domains.ErrorDetail{
-						{
-							Message:  "The token provided is either invalid or has expired. Please login again to obtain a new token.",
-							Parameter: "Authorization",
-						},
-					},
-					Code: http.StatusUnauthorized,
-				})
-			}
+// RenderError menulis error autentikasi dalam format envelope.Response
+func RenderError(c echo.Context, err *jwtauth.Error) error {
+	message := i18n.T(c, "auth.invalid_token")
+	if err.Status == http.StatusForbidden {
+		message = envelope.StatusText(c, http.StatusForbidden)
+	}
 
-			// Token valid, lanjutkan ke handler berikutnya
-			return next(c)
-		}
+	// Detail diterjemahkan dengan kode "auth.<reason>", pesan aslinya dipakai jika kodenya tidak ada di katalog
+	detail, ok := i18n.Lookup(i18n.LocaleOf(c), "auth."+err.Reason, err.Args...)
+	if !ok {
+		detail = err.Message
 	}
+
+	return envelope.JSON(c, err.Status, envelope.Response{
+		Message: message,
+		Errors: []envelope.ErrorDetail{
+			{
+				Message:   detail,
+				Parameter: "Authorization",
+			},
+		},
+	})
 }
@@ -2,28 +2,224 @@ package config
 
 import (
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"health"
+	"idempotency"
+	"jwtauth"
+	"logging"
+	"ratelimit"
 
 	"github.com/joho/godotenv"
+	"github.com/labstack/echo/v4"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func ConnectDB() *gorm.DB {
-	err := godotenv.Load("conf/config.env")
-	if err != nil {
+// LoadEnv memuat conf/config.env, dipanggil pertama kali sebelum konfigurasi lain dibaca
+func LoadEnv() {
+	if err := godotenv.Load("conf/config.env"); err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
+}
 
+// SetupLogging membaca LOG_LEVEL (debug, info, warn atau error, default info) dan LOG_FORMAT
+// (json atau text, default json). Di level debug body request ikut tercatat setelah password dan
+// token disamarkan, jangan dipakai di production.
+func SetupLogging() {
+	level := slog.LevelInfo
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		parsed, err := logging.ParseLevel(value)
+		if err != nil {
+			log.Fatalf("Invalid LOG_LEVEL: %v", err)
+		}
+		level = parsed
+	}
+
+	if _, err := logging.Setup(logging.Config{Level: level, Format: os.Getenv("LOG_FORMAT")}); err != nil {
+		log.Fatalf("Invalid LOG_FORMAT: %v", err)
+	}
+}
+
+// ConnectDB membuka koneksi database. Query SQL tercatat di level debug tanpa nilai parameternya,
+// query yang lebih lama dari LOG_SLOW_QUERY (default 200ms) tercatat sebagai warning.
+func ConnectDB() *gorm.DB {
 	dsn := os.Getenv("DB_URL")
 	if dsn == "" {
 		log.Fatalf("DB_URL is not set in environment")
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logging.NewGORMLogger(durationEnv("LOG_SLOW_QUERY", 200*time.Millisecond)),
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
-	log.Println("Database connection succesfully established!")
+	slog.Info("Database connection succesfully established!")
 	return db
-}
\ No newline at end of file
+}
+
+// LoadJWTConfig membaca pengaturan token dari environment, dipanggil setelah LoadEnv memuat conf/config.env
+func LoadJWTConfig() jwtauth.TokenConfig {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Fatalf("JWT_SECRET is not set in environment")
+	}
+
+	return jwtauth.TokenConfig{
+		SigningKey: []byte(secret),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		TTL:        durationEnv("JWT_TTL", time.Minute),
+		ClockSkew:  durationEnv("JWT_CLOCK_SKEW", 30*time.Second),
+	}
+}
+
+// LoadIntrospectionConfig mengembalikan nil jika AUTH_INTROSPECTION_URL kosong, token lalu diverifikasi secara lokal
+func LoadIntrospectionConfig() *jwtauth.IntrospectionConfig {
+	endpoint := os.Getenv("AUTH_INTROSPECTION_URL")
+	if endpoint == "" {
+		return nil
+	}
+
+	clientID := os.Getenv("AUTH_INTROSPECTION_CLIENT_ID")
+	clientSecret := os.Getenv("AUTH_INTROSPECTION_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		log.Fatalf("AUTH_INTROSPECTION_CLIENT_ID and AUTH_INTROSPECTION_CLIENT_SECRET are required with AUTH_INTROSPECTION_URL")
+	}
+
+	return &jwtauth.IntrospectionConfig{
+		Endpoint:     endpoint,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		CacheTTL:     durationEnv("AUTH_INTROSPECTION_CACHE_TTL", 30*time.Second),
+	}
+}
+
+// LoadCookieConfig mengembalikan nil jika AUTH_COOKIE_MODE tidak aktif.
+// AUTH_COOKIE_SECURE default true, set false hanya untuk development di http://localhost.
+func LoadCookieConfig() *jwtauth.CookieConfig {
+	if enabled, _ := strconv.ParseBool(os.Getenv("AUTH_COOKIE_MODE")); !enabled {
+		return nil
+	}
+
+	secure := true
+	if value := os.Getenv("AUTH_COOKIE_SECURE"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid AUTH_COOKIE_SECURE: %v", err)
+		}
+		secure = parsed
+	}
+
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(os.Getenv("AUTH_COOKIE_SAMESITE")) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &jwtauth.CookieConfig{
+		Domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+		Secure:   secure,
+		SameSite: sameSite,
+	}
+}
+
+// LoadUserRetention membaca USER_RETENTION (default 0, purge otomatis harus diaktifkan operator,
+// misalnya 720h) dan USER_PURGE_INTERVAL (default 24 jam)
+func LoadUserRetention() (retention, interval time.Duration) {
+	return durationEnv("USER_RETENTION", 0), durationEnv("USER_PURGE_INTERVAL", 24*time.Hour)
+}
+
+// LoadIdempotencyConfig membaca IDEMPOTENCY_STORE (postgres atau memory, default postgres) dan
+// IDEMPOTENCY_TTL (default 24 jam). Store memory hanya cocok jika service berjalan di satu instance.
+func LoadIdempotencyConfig(db *gorm.DB) idempotency.Config {
+	var store idempotency.Store
+	switch strings.ToLower(os.Getenv("IDEMPOTENCY_STORE")) {
+	case "", "postgres":
+		store = idempotency.NewPostgresStore(db)
+	case "memory":
+		store = idempotency.NewMemoryStore()
+	default:
+		log.Fatalf("Invalid IDEMPOTENCY_STORE: must be postgres or memory")
+	}
+	return idempotency.Config{
+		Store: store,
+		TTL:   durationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+		// Response login berisi token, tidak boleh tersimpan di idempotency_keys
+		Exclude: []string{"POST /login"},
+	}
+}
+
+// rateLimitPolicies adalah batas per rute, rute lain memakai defaultRateLimit. Batas dihitung per
+// IP, dan untuk rute yang butuh login juga per user atau API key.
+var rateLimitPolicies = map[string]ratelimit.Policy{
+	"POST /register": {Limit: 10, Period: time.Hour},
+	"POST /login":    {Limit: 10, Period: time.Minute},
+	"POST /validate": {Limit: 10, Period: time.Minute},
+	"PATCH /me":      {Limit: 10, Period: time.Minute}, // Memeriksa current_password saat ganti password
+	"GET /healthz":   {},                               // Probe load balancer tidak dibatasi
+	"GET /readyz":    {},
+	"GET /metrics":   {}, // Scrape Prometheus
+}
+
+var defaultRateLimit = ratelimit.Policy{Limit: 120, Period: time.Minute}
+
+// LoadRateLimitConfig membaca RATE_LIMIT_STORE (memory atau postgres, default memory). Pakai
+// postgres jika service berjalan di lebih dari satu instance agar batasnya berlaku bersama.
+func LoadRateLimitConfig(db *gorm.DB) ratelimit.Config {
+	var store ratelimit.Store
+	switch strings.ToLower(os.Getenv("RATE_LIMIT_STORE")) {
+	case "", "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		store = ratelimit.NewPostgresStore(db)
+	default:
+		log.Fatalf("Invalid RATE_LIMIT_STORE: must be memory or postgres")
+	}
+	return ratelimit.Config{Store: store, Routes: rateLimitPolicies, Default: defaultRateLimit}
+}
+
+// LoadIPExtractor membaca TRUSTED_PROXIES, alamat atau CIDR reverse proxy dipisah koma. Tanpa
+// TRUSTED_PROXIES IP diambil dari koneksi, header X-Forwarded-For dari client diabaikan agar batas
+// per IP tidak bisa diakali.
+func LoadIPExtractor() echo.IPExtractor {
+	var proxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		proxies = strings.Split(value, ",")
+	}
+	extractor, err := ratelimit.IPExtractor(proxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	return extractor
+}
+
+// LoadServerConfig membaca SHUTDOWN_TIMEOUT (default 15 detik) dan SHUTDOWN_DRAIN_DELAY (default 0).
+// Di belakang load balancer, isi SHUTDOWN_DRAIN_DELAY lebih lama dari interval cek /readyz.
+func LoadServerConfig() health.Config {
+	return health.Config{
+		Addr:            ":8082",
+		ShutdownTimeout: durationEnv("SHUTDOWN_TIMEOUT", 15*time.Second),
+		DrainDelay:      durationEnv("SHUTDOWN_DRAIN_DELAY", 0),
+	}
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return duration
+}
@@ -0,0 +1,261 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"project-golang-crud/domains"
+
+	"binding"
+	"envelope"
+	"i18n"
+	"github.com/labstack/echo/v4"
+	"precondition"
+)
+
+type AdminUserHandler struct {
+	Usecase domains.UserUsecase
+}
+
+func NewAdminUserHandler(e *echo.Echo, u domains.UserUsecase, auth, adminOnly echo.MiddlewareFunc) {
+	handler := &AdminUserHandler{Usecase: u}
+
+	e.GET("/admin/users/deleted", handler.ListDeleted, auth, adminOnly)
+	e.POST("/admin/users/:id/restore", handler.Restore, auth, adminOnly)
+	e.POST("/admin/users/:id/purge", handler.Purge, auth, adminOnly)
+	e.PUT("/admin/users/:id/status", handler.ChangeStatus, auth, adminOnly)
+	e.GET("/admin/users/:id/status-history", handler.StatusHistory, auth, adminOnly)
+	e.GET("/users/:id", handler.Get, auth, adminOnly)
+	e.PATCH("/users/:id", handler.Patch, auth, adminOnly)
+}
+
+// userPatch adalah representasi user yang diubah oleh JSON merge patch pada PATCH /users/:id.
+// Password tidak pernah dikirim balik, jadi hanya diubah jika ada di patch.
+type userPatch struct {
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password,omitempty"`
+}
+
+// Get menampilkan user yang belum dihapus, versinya dikirim di header ETag untuk If-Match pada Patch
+func (h *AdminUserHandler) Get(c echo.Context) error {
+	user, err := h.Usecase.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil || user.DeletedAt != nil {
+		return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+			Message: i18n.T(c, "user.not_found_title"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
+			},
+		})
+	}
+
+	precondition.SetETag(c, user.Version)
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "user.retrieved"),
+		Data:    toProfile(user),
+	})
+}
+
+// Patch mengubah user dengan JSON merge patch. If-Match wajib berisi ETag dari Get, jika user
+// sudah diubah request lain sejak itu response-nya 412 dan tidak ada yang disimpan.
+func (h *AdminUserHandler) Patch(c echo.Context) error {
+	id := c.Param("id")
+	user, err := h.Usecase.GetByID(c.Request().Context(), id)
+	if err != nil || user.DeletedAt != nil {
+		return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+			Message: i18n.T(c, "user.not_found_title"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
+			},
+		})
+	}
+	if err := precondition.Check(c, user.Version, true); err != nil {
+		return precondition.Write(c, err)
+	}
+
+	req := userPatch{Username: user.Username, Email: user.Email}
+	if err := binding.BindMergePatch(c, &req); err != nil {
+		return binding.Write(c, err)
+	}
+
+	if err := h.Usecase.Update(c.Request().Context(), id, user.Version, req.Username, req.Email, req.Password, auditActor(c)); err != nil {
+		if response, ok := conflictResponse(c, err); ok {
+			return envelope.JSON(c, http.StatusConflict, response)
+		}
+		if errors.Is(err, domains.ErrVersionConflict) {
+			return precondition.Write(c, err)
+		}
+		if errors.Is(err, domains.ErrUserNotFound) {
+			return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+				Message: i18n.T(c, "user.not_found_title"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
+				},
+			})
+		}
+		return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+			Message: i18n.T(c, "error.validation"),
+			Errors:  splitUsecaseErrors(c, err),
+		})
+	}
+
+	updated, err := h.Usecase.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+			Message: i18n.T(c, "user.not_found_title"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "id"},
+			},
+		})
+	}
+
+	precondition.SetETag(c, updated.Version)
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "user.updated"),
+		Data:    toProfile(updated),
+	})
+}
+
+func (h *AdminUserHandler) ListDeleted(c echo.Context) error {
+	users, err := h.Usecase.ListDeleted(c.Request().Context())
+	if err != nil {
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "admin.deleted_users_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "admin.deleted_users"),
+		Data:    users,
+	})
+}
+
+func (h *AdminUserHandler) Restore(c echo.Context) error {
+	id := c.Param("id")
+	if err := h.Usecase.Restore(c.Request().Context(), id, auditActor(c)); err != nil {
+		if response, ok := conflictResponse(c, err); ok {
+			return envelope.JSON(c, http.StatusConflict, response)
+		}
+		if err == domains.ErrUserNotDeleted {
+			return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+				Message: i18n.T(c, "admin.no_restorable_user"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.Err(c, err), Parameter: "id"},
+				},
+			})
+		}
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "admin.restore_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "admin.restored"),
+		Data:    domains.DeleteRequest{ID: id},
+	})
+}
+
+func (h *AdminUserHandler) Purge(c echo.Context) error {
+	id := c.Param("id")
+	if err := h.Usecase.Purge(c.Request().Context(), id, auditActor(c)); err != nil {
+		if err == domains.ErrUserNotDeleted {
+			return envelope.JSON(c, http.StatusConflict, envelope.Response{
+				Message: i18n.T(c, "admin.purge_not_deleted"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.Err(c, err), Parameter: "id"},
+				},
+			})
+		}
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "admin.purge_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "admin.purged"),
+		Data:    domains.DeleteRequest{ID: id},
+	})
+}
+
+type changeStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+// ChangeStatus mengubah status akun, alasan wajib diisi dan dicatat ke riwayat status
+func (h *AdminUserHandler) ChangeStatus(c echo.Context) error {
+	var req changeStatusRequest
+	if err := binding.Bind(c, &req); err != nil {
+		return binding.Write(c, err)
+	}
+
+	id := c.Param("id")
+	change, err := h.Usecase.ChangeStatus(c.Request().Context(), id, req.Status, req.Reason, auditActor(c))
+	if err != nil {
+		switch err {
+		case domains.ErrUserNotFound:
+			return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+				Message: i18n.T(c, "user.not_found_title"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.Err(c, err), Parameter: "id"},
+				},
+			})
+		case domains.ErrInvalidStatus, domains.ErrStatusReasonRequired:
+			parameter := "status"
+			if err == domains.ErrStatusReasonRequired {
+				parameter = "reason"
+			}
+			return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+				Message: i18n.T(c, "error.validation"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.Err(c, err), Parameter: parameter},
+				},
+			})
+		}
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "admin.status_change_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "admin.status_changed"),
+		Data:    change,
+	})
+}
+
+// StatusHistory menampilkan riwayat perubahan status akun
+func (h *AdminUserHandler) StatusHistory(c echo.Context) error {
+	changes, err := h.Usecase.StatusHistory(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if err == domains.ErrUserNotFound {
+			return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+				Message: i18n.T(c, "user.not_found_title"),
+				Errors: []envelope.ErrorDetail{
+					{Message: i18n.Err(c, err), Parameter: "id"},
+				},
+			})
+		}
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "admin.status_history_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "admin.status_history"),
+		Data:    changes,
+	})
+}
@@ -0,0 +1,85 @@
+package delivery
+
+import (
+	"net/http"
+	"project-golang-crud/middleware"
+	"time"
+
+	"auditlog"
+	"envelope"
+	"i18n"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	Store  *auditlog.Store
+	Logger *auditlog.Logger
+}
+
+func NewAuditHandler(e *echo.Echo, store *auditlog.Store, logger *auditlog.Logger, auth, adminOnly echo.MiddlewareFunc) {
+	handler := &AuditHandler{Store: store, Logger: logger}
+
+	e.GET("/admin/audit-events", handler.ListEvents, auth, adminOnly)
+	e.GET("/admin/stats/audit", handler.Stats, auth, adminOnly)
+}
+
+// ListEvents menerima filter actor_id, subject_id, action, outcome, from, to (RFC 3339), limit dan offset.
+// ?format=csv mengembalikan hasil sebagai file CSV.
+func (h *AuditHandler) ListEvents(c echo.Context) error {
+	filter, err := auditlog.ParseFilter(c.QueryParams())
+	if err != nil {
+		return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+			Message: i18n.T(c, "audit.invalid_filter"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "query"},
+			},
+		})
+	}
+
+	csvExport := c.QueryParam("format") == "csv"
+	if csvExport && c.QueryParam("limit") == "" {
+		filter.Limit = auditlog.MaxLimit
+	}
+
+	events, err := h.Store.Find(c.Request().Context(), filter)
+	if err != nil {
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.T(c, "audit.events_failed"),
+			Errors: []envelope.ErrorDetail{
+				{Message: i18n.Err(c, err), Parameter: "database"},
+			},
+		})
+	}
+
+	if csvExport {
+		filename := "audit-events-" + time.Now().UTC().Format("20060102-150405") + ".csv"
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+		c.Response().WriteHeader(http.StatusOK)
+		return auditlog.WriteCSV(c.Response(), events)
+	}
+
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "audit.events"),
+		Data:    events,
+	})
+}
+
+func (h *AuditHandler) Stats(c echo.Context) error {
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "audit.stats"),
+		Data:    h.Logger.Stats(),
+	})
+}
+
+// auditActor mengambil user yang sedang login, IP dan User-Agent dari request untuk audit log
+func auditActor(c echo.Context) auditlog.Actor {
+	actor := auditlog.Actor{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+	if principal, ok := middleware.GetPrincipal(c); ok {
+		actor.ID = principal.ID
+	}
+	return actor
+}
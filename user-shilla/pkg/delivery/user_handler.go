@@ -3,153 +3,108 @@ package delivery
 import (
 	"errors"
 	"net/http"
-	"os"
 	"project-golang-crud/domains"
-	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
+	"binding"
+	"envelope"
+	"i18n"
+	"jwtauth"
+	"metrics"
+	"precondition"
 	"project-golang-crud/middleware" 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserHandler struct {
 	Usecase domains.UserUsecase
+	Tokens  jwtauth.TokenConfig
+	Cookies *jwtauth.CookieConfig // nil jika mode cookie tidak aktif
 }
 
-func NewUserHandler(e *echo.Echo, u domains.UserUsecase) {
-	handler := &UserHandler{Usecase: u}
+func NewUserHandler(e *echo.Echo, u domains.UserUsecase, tokens jwtauth.TokenConfig, cookies *jwtauth.CookieConfig, auth echo.MiddlewareFunc) {
+	handler := &UserHandler{Usecase: u, Tokens: tokens, Cookies: cookies}
 
 	e.POST("/register", handler.Register)
 	e.PUT("/update/:id", handler.Update)
 	e.DELETE("/delete", handler.Delete)
 	e.POST("/validate", handler.Validate)
     e.POST("/login", handler.Login)
-    e.GET("/users", handler.WelcomeMessage, middleware.JWTMiddleware())
+    e.GET("/users", handler.WelcomeMessage, auth)
+
+    // Profil milik user yang sedang login. Token OAuth dari auth-user-api hanya boleh mengubah
+    // atau menghapus akun jika diberi scope users:write.
+    profileScope := jwtauth.RequireScope("profile", middleware.RenderError)
+    writeScope := jwtauth.RequireScope("users:write", middleware.RenderError)
+    e.GET("/me", handler.GetMe, auth, profileScope)
+    e.PATCH("/me", handler.UpdateMe, auth, writeScope)
+    e.DELETE("/me", handler.DeleteMe, auth, writeScope)
+
+    // Cookie HttpOnly tidak bisa dihapus dari JavaScript, jadi frontend memanggil /logout
+    if cookies != nil {
+        e.POST("/logout", handler.Logout, auth, writeScope)
+    }
 }
 
 func (h *UserHandler) WelcomeMessage(c echo.Context) error {
-    return c.JSON(http.StatusOK, "Hello! Welcome to the main page.")
+    return envelope.OK(c, http.StatusOK, i18n.T(c, "welcome"), nil)
 }
 
 func (h *UserHandler) GetAll(c echo.Context) error {
-	users, err := h.Usecase.GetAll()  
+	users, err := h.Usecase.GetAll(c.Request().Context())  
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, domains.Response{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
+		return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+			Message: i18n.Err(c, err),
 			Data:    nil,
 		})
 	}
-	return c.JSON(http.StatusOK, domains.Response{
-		Code:    http.StatusOK,
-		Message: "Books retrieved successfully",
+	return envelope.JSON(c, http.StatusOK, envelope.Response{
+		Message: i18n.T(c, "user.list"),
 		Data:    users,
 	})
 }
 
 func (h *UserHandler) Register(c echo.Context) error {
     var req struct {
-        Username  interface{} `json:"username"`
-        Email     interface{} `json:"email"`
-        Password1 interface{} `json:"password_1"`
-        Password2 interface{} `json:"password_2"`
+        Username  string `json:"username" validate:"required"`
+        Email     string `json:"email" validate:"required"`
+        Password1 string `json:"password_1" validate:"required"`
+        Password2 string `json:"password_2" validate:"required"`
     }
 
-    // Bind request body ke struct
-    if err := c.Bind(&req); err != nil {
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Invalid Request",
-            Data: nil,
-            Errors: []domains.ErrorDetail{
-                {Message: "Failed to parse request body", Parameter: "Request Body"},
-            },
-            Code: http.StatusBadRequest,
-        })
-    }
-    var validationErrors []domains.ErrorDetail
-
-    // Cek tipe data untuk setiap field
-    if _, ok := req.Username.(string); !ok {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Field must be a string", 
-            Parameter: "username",
-        })
-    }
-    if _, ok := req.Email.(string); !ok {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Field must be a string", 
-            Parameter: "email",
-        })
-    }
-    if _, ok := req.Password1.(string); !ok {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Field must be a string", 
-            Parameter: "password_1",
-        })
-    }
-    if _, ok := req.Password2.(string); !ok {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Field must be a string", 
-            Parameter: "password_2",
-        })
-    }
-
-    // Jika ada error validasi, kembalikan respons dengan semua error
-    if len(validationErrors) > 0 {
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Validation Errors",
-            Data: nil,
-            Errors:   validationErrors,
-            Code:    http.StatusBadRequest,
-        })
+    // Tipe data, field yang tidak dikenal dan field wajib dicek sekaligus
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
     }
 
     // Cek apakah password1 dan password2 cocok
     if req.Password1 != req.Password2 {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Passwords don't match", 
-            Parameter: "password",
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "error.validation"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "password.mismatch"), Parameter: "password_2"},
+            },
         })
     }
 
     // Panggil usecase untuk registrasi
-    user, err := h.Usecase.Register(req.Username.(string), req.Email.(string), req.Password1.(string))
+    user, err := h.Usecase.Register(c.Request().Context(), req.Username, req.Email, req.Password1, auditActor(c))
     if err != nil {
-        // Jika validasi gagal, tampilkan semua error validasi
-        // Misalkan error dari `usecase` berisi beberapa error
-        for _, msg := range strings.Split(err.Error(), "; ") {
-            if strings.Contains(msg, "Username") {
-                validationErrors = append(validationErrors, domains.ErrorDetail{
-                    Message: msg,
-                    Parameter: "username",
-                })
-            } else if strings.Contains(msg, "Invalid email") {
-                validationErrors = append(validationErrors, domains.ErrorDetail{
-                    Message: msg,
-                    Parameter: "email",
-                })
-            } else if strings.Contains(msg, "Password") {
-                validationErrors = append(validationErrors, domains.ErrorDetail{
-                    Message: msg,
-                    Parameter: "password",
-                })
-            }
+        if response, ok := conflictResponse(c, err); ok {
+            return envelope.JSON(c, http.StatusConflict, response)
         }
-        
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Validation Errors",
+        // Jika validasi gagal, tampilkan semua error validasi per field
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "error.validation"),
             Data: nil,
-            Errors: validationErrors,
-            Code: http.StatusBadRequest,
+            Errors: splitUsecaseErrors(c, err),
         })
     }
 
     // Menyusun response dengan field deleted_at
-    return c.JSON(http.StatusCreated, domains.Response{
-        Message: "User created successfully",
+    return envelope.JSON(c, http.StatusCreated, envelope.Response{
+        Message: i18n.T(c, "user.created"),
         Data: domains.User{
             ID:        user.ID,
             Username:  user.Username,
@@ -159,151 +114,97 @@ func (h *UserHandler) Register(c echo.Context) error {
             DeletedAt: user.DeletedAt,
         },
         Errors: nil,
-        Code: http.StatusCreated,
     })
 }
 
 func (h *UserHandler) Update(c echo.Context) error {
     var req struct {
-        Username  interface{} `json:"username"`  
-        Email     interface{} `json:"email"`
-        Password1 interface{} `json:"password_1"`
-        Password2 interface{} `json:"password_2"`
+        Username  string `json:"username" validate:"required"`
+        Email     string `json:"email"`
+        Password1 string `json:"password_1"`
+        Password2 string `json:"password_2"`
     }
 
     id := c.Param("id")
-    if err := c.Bind(&req); err != nil {
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Invalid Request",
-            Data: nil,
-            Errors: []domains.ErrorDetail{
-                {Message: "Failed to parse request body", Parameter: "Request Body"},
-            },
-            Code: http.StatusBadRequest,
-        })
-    }
-
-    var validationErrors []domains.ErrorDetail
-
-    // Validasi tipe data username harus string
-    if _, ok := req.Username.(string); !ok {
-        validationErrors = append(validationErrors, domains.ErrorDetail{
-            Message: "Field must be a string",
-            Parameter: "username",
-        })
+    // Email dan password opsional, field yang tidak dikirim tidak diubah
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
     }
 
-    // Validasi opsional email
-    var email string
-    if req.Email != nil {
-        if emailStr, ok := req.Email.(string); ok {
-            email = emailStr
-        } else {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: "Field must be a string",
-                Parameter: "email",
-            })
-        }
-    }
+    var validationErrors []envelope.ErrorDetail
+    email, password1, password2 := req.Email, req.Password1, req.Password2
 
-    // Validasi opsional password1 dan password2
-    var password1, password2 string
-    if req.Password1 != nil {
-        if passwordStr, ok := req.Password1.(string); ok {
-            password1 = passwordStr
-        } else {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: "Field must be a string",
-                Parameter: "password_1",
-            })
-        }
-    }
-
-    if req.Password2 != nil {
-        if passwordStr, ok := req.Password2.(string); ok {
-            password2 = passwordStr
-        } else {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: "Field must be a string",
-                Parameter: "password_2",
+    // Cek apakah password1 dan password2 cocok jika keduanya diisi
+    if password1 != "" || password2 != "" {
+        if password1 != password2 {
+            validationErrors = append(validationErrors, envelope.ErrorDetail{
+                Message: i18n.T(c, "password.mismatch"),
+                Parameter: "password",
             })
         }
     }
 
-    // Jika ada error validasi, kembalikan respons dengan semua error
+    // Password yang tidak cocok tidak boleh diteruskan ke usecase, sama seperti UpdateMe
     if len(validationErrors) > 0 {
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Validation Errors",
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "error.validation"),
             Data: nil,
-            Errors:   validationErrors,
-            Code:    http.StatusBadRequest,
+            Errors: validationErrors,
         })
     }
 
-    // Cek apakah password1 dan password2 cocok jika keduanya diisi
-    if password1 != "" || password2 != "" {
-        if password1 != password2 {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: "Passwords don't match",
-                Parameter: "password",
-            })
+    // If-Match opsional pada PUT agar client lama tetap jalan, tetapi jika dikirim harus cocok
+    version := 0
+    if c.Request().Header.Get(precondition.HeaderIfMatch) != "" {
+        if current, err := h.Usecase.GetByID(c.Request().Context(), id); err == nil {
+            if err := precondition.Check(c, current.Version, false); err != nil {
+                return precondition.Write(c, err)
+            }
+            version = current.Version
         }
     }
 
     // Panggil usecase untuk update
     // Panggil usecase untuk update
-err := h.Usecase.Update(id, req.Username.(string), email, password1)
+err := h.Usecase.Update(c.Request().Context(), id, version, req.Username, email, password1, auditActor(c))
 if err != nil {
+    if response, ok := conflictResponse(c, err); ok {
+        return envelope.JSON(c, http.StatusConflict, response)
+    }
+    if errors.Is(err, domains.ErrVersionConflict) {
+        return precondition.Write(c, err)
+    }
     if errors.Is(err, gorm.ErrRecordNotFound) {
-        return c.JSON(http.StatusNotFound, domains.Response{
-            Message: "User not found",
+        return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+            Message: i18n.T(c, "user.not_found_title"),
             Data: nil,
             Errors: validationErrors,
-            Code:    http.StatusNotFound,
         })
     }
 
     // Menangani kesalahan validasi yang berasal dari usecase
-    for _, msg := range strings.Split(err.Error(), "; ") {
-        if strings.Contains(msg, "Username") {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: msg,
-                Parameter: "username",
-            })
-        } else if strings.Contains(msg, "Invalid email") {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: msg,
-                Parameter: "email",
-            })
-        } else if strings.Contains(msg, "Password") {
-            validationErrors = append(validationErrors, domains.ErrorDetail{
-                Message: msg,
-                Parameter: "password",
-            })
-        }
-    }
-    
-    return c.JSON(http.StatusNotFound, domains.Response{
-        Message: "User not found",
+    validationErrors = append(validationErrors, splitUsecaseErrors(c, err)...)
+
+    return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+        Message: i18n.T(c, "user.not_found_title"),
         Data: nil,
         Errors: validationErrors,
-        Code: http.StatusNotFound,
     })
 }
 
 // Ambil data pengguna setelah update
-user, err := h.Usecase.GetByID(id)
+user, err := h.Usecase.GetByID(c.Request().Context(), id)
 if err != nil {
-    return c.JSON(http.StatusNotFound, domains.Response{
-        Message: "User not found",
+    return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+        Message: i18n.T(c, "user.not_found_title"),
         Data: nil,
         Errors: validationErrors,
-        Code:    http.StatusNotFound,
     })
 }
 
-return c.JSON(http.StatusOK, domains.Response{
-    Message: "User updated successfully",
+precondition.SetETag(c, user.Version)
+return envelope.JSON(c, http.StatusOK, envelope.Response{
+    Message: i18n.T(c, "user.updated"),
     Data: domains.User{
         ID:        user.ID,
         Username:  user.Username,
@@ -313,61 +214,50 @@ return c.JSON(http.StatusOK, domains.Response{
         DeletedAt: user.DeletedAt,
     },
     Errors: nil,
-    Code:    http.StatusOK,
 })
 }
 
 func (h *UserHandler) Delete(c echo.Context) error {
     var req domains.DeleteRequest
 
-    if err := c.Bind(&req); err != nil {
-        return c.JSON(http.StatusBadRequest, domains.Response{
-            Message: "Invalid Request Body",
-            Data: nil,
-            Errors: []domains.ErrorDetail{
-                {Message: "Failed to parse request body", Parameter: "Request Body"},
-            },
-            Code:    http.StatusBadRequest,
-        })
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
     }
 
     // Dapatkan pengguna yang dihapus
-    user, err := h.Usecase.Delete(req.ID)
+    user, err := h.Usecase.Delete(c.Request().Context(), req.ID, auditActor(c))
     if err != nil {
         if errors.Is(err, domains.ErrUserNotFound) {
-            return c.JSON(http.StatusNotFound, domains.Response{
-                Message: "User not found",
+            return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+                Message: i18n.T(c, "user.not_found_title"),
                 Data: nil,
-                Errors: []domains.ErrorDetail{
-                    {Message: "User with the given ID does not exist", Parameter: "id"},
+                Errors: []envelope.ErrorDetail{
+                    {Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
                 },
-                Code:    http.StatusNotFound,
             })
         }
 
-        return c.JSON(http.StatusNotFound, domains.Response{
-            Message: "User not found",
+        return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+            Message: i18n.T(c, "user.not_found_title"),
             Data: nil,
             Errors: nil,
-            Code:    http.StatusNotFound,
         })
     }
 
     // Ambil kembali data user yang sudah dihapus untuk response
-    updatedUser, err := h.Usecase.GetByID(user.ID)
+    updatedUser, err := h.Usecase.GetByID(c.Request().Context(), user.ID)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, domains.Response{
-            Message: "Failed to retrieve deleted user data",
+        return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+            Message: i18n.T(c, "user.deleted_data_failed"),
             Data: nil,
-            Errors: []domains.ErrorDetail{
-                {Message: "Error retrieving user data after deletion", Parameter: "user"},
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "user.deleted_data_error"), Parameter: "user"},
             },
-            Code:    http.StatusInternalServerError,
         })
     }
 
-    return c.JSON(http.StatusOK, domains.Response{
-        Message: "User Deleted",
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "user.deleted"),
         Data: domains.User{
             ID:        updatedUser.ID,
             Username:  updatedUser.Username,
@@ -377,149 +267,356 @@ func (h *UserHandler) Delete(c echo.Context) error {
             DeletedAt: updatedUser.DeletedAt, 
         },
         Errors: nil,
-        Code: http.StatusOK,
     })
 }
 
 func (h *UserHandler) Validate(c echo.Context) error {
     var req struct {
-        Username string `json:"username"`
-        Password string `json:"password"`
+        Username string `json:"username" validate:"required"`
+        Password string `json:"password" validate:"required"`
     }
 
     // Bind JSON request ke struct
-    if err := c.Bind(&req); err != nil {
-        return c.JSON(http.StatusInternalServerError, domains.Response{
-            Message: "Invalid request",
-            Errors: []domains.ErrorDetail{
-                {Message: "Invalid Server Error", Parameter: "request body"},
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
+    }
+
+    // Username yang tidak ada dan password yang salah mendapat response yang sama
+    if err := h.Usecase.Validate(c.Request().Context(), req.Username, req.Password); err != nil {
+        return envelope.JSON(c, http.StatusUnauthorized, envelope.Response{
+            Message: i18n.T(c, "auth.failed"),
+            Data: nil,
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.Err(c, domains.ErrInvalidCredentials)},
+            },
+        })
+    }
+
+    // Jika tidak ada error, kembalikan response yang sukses
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "auth.valid_credentials"),
+        Data: map[string]interface{}{
+            "username": req.Username,
+        },
+        Errors: nil,
+    })
+}
+
+func (h *UserHandler) Login(c echo.Context) error {
+    var req struct {
+        Username string `json:"username" validate:"required"`
+        Password string `json:"password" validate:"required"`
+    }
+
+    // ?mode=cookie dipakai frontend browser agar token tidak perlu disimpan di localStorage
+    cookieMode := c.QueryParam("mode") == "cookie"
+    if cookieMode && h.Cookies == nil {
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "auth.cookie_mode_disabled"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "auth.cookie_mode_disabled"), Parameter: "mode"},
             },
-            Code: http.StatusInternalServerError,
         })
     }
 
-    var errorDetails []domains.ErrorDetail
+    // Bind JSON request ke struct
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
+    }
 
-    // Ambil user berdasarkan username
-    user, err := h.Usecase.GetByUsername(req.Username)
+    // Verifikasi username dan password, hasilnya dicatat ke audit log oleh usecase
+    user, err := h.Usecase.Authenticate(c.Request().Context(), req.Username, req.Password, auditActor(c))
+    if domains.IsAccountStatusError(err) {
+        return envelope.JSON(c, http.StatusForbidden, envelope.Response{
+            Message: i18n.T(c, "auth.failed"),
+            Data: nil,
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.Err(c, err), Parameter: "username"},
+            },
+        })
+    }
+    // Username yang tidak ada dan password yang salah mendapat response yang sama
     if err != nil {
-        errorDetails = append(errorDetails, domains.ErrorDetail{
-            Message: "User not found",
-            Parameter: "username",
+        return envelope.JSON(c, http.StatusUnauthorized, envelope.Response{
+            Message: i18n.T(c, "auth.failed"),
+            Data: nil,
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.Err(c, domains.ErrInvalidCredentials)},
+            },
         })
-    } 
+    }
 
-	if user != nil {
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-            errorDetails = append(errorDetails, domains.ErrorDetail{
-                Message: "Invalid Password",
-                Parameter: "password",
+    // Jika user ditemukan dan password benar, buat JWT token dengan subject berupa ID user
+    tokenString, err := h.Tokens.Issue(&jwtauth.Claims{
+        Username: user.Username,
+        Roles:    []string{user.Role},
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject: user.ID,
+        },
+    })
+    if err != nil {
+        return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+            Message: i18n.T(c, "auth.token_generate_failed"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "auth.token_sign_error"), Parameter: "token generation"},
+            },
+        })
+    }
+    metrics.TokensIssued.WithLabelValues("access").Inc()
+
+    if cookieMode {
+        // Token hanya dikirim sebagai cookie HttpOnly, body berisi CSRF token untuk header X-CSRF-Token
+        csrfToken, err := h.Cookies.SetSession(c, tokenString, h.Tokens.TTL)
+        if err != nil {
+            return envelope.JSON(c, http.StatusInternalServerError, envelope.Response{
+                Message: i18n.T(c, "auth.cookie_set_failed"),
+                Errors: []envelope.ErrorDetail{
+                    {Message: i18n.Err(c, err), Parameter: "cookie"},
+                },
             })
         }
-	} else {
-        // Jika user tidak ditemukan, kita juga perlu memberi tahu bahwa password salah
-        errorDetails = append(errorDetails, domains.ErrorDetail{
-            Message: "Invalid Password",
-            Parameter: "password",
+        return envelope.JSON(c, http.StatusOK, envelope.Response{
+            Message: i18n.T(c, "auth.login_success"),
+            Data: map[string]interface{}{
+                "csrf_token": csrfToken,
+            },
+            Errors: nil,
         })
     }
 
-    // Kirimkan response error jika ada
-    if len(errorDetails) > 0 {
-        return c.JSON(http.StatusUnauthorized, domains.Response{
-            Message: "Authentication Failed",
+    // Jika tidak ada error, kembalikan response yang sukses dengan token JWT
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "auth.login_success"),
+        Data: map[string]interface{}{
+            "token": tokenString,
+        },
+        Errors: nil,
+    })
+}
+
+func (h *UserHandler) Logout(c echo.Context) error {
+    h.Cookies.ClearSession(c)
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "auth.logout_success"),
+        Data:    nil,
+        Errors:  nil,
+    })
+}
+
+func (h *UserHandler) GetMe(c echo.Context) error {
+    principal, ok := middleware.GetPrincipal(c)
+    if !ok {
+        return envelope.JSON(c, http.StatusUnauthorized, envelope.Response{
+            Message: i18n.T(c, "auth.unauthorized"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "auth.missing_user"), Parameter: "Authorization"},
+            },
+        })
+    }
+
+    user, err := h.Usecase.GetByID(c.Request().Context(), principal.ID)
+    if err != nil {
+        return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+            Message: i18n.T(c, "user.not_found_title"),
             Data: nil,
-            Errors:   errorDetails,
-            Code:    http.StatusUnauthorized,
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
+            },
         })
     }
 
-    // Jika tidak ada error, kembalikan response yang sukses
-    return c.JSON(http.StatusOK, domains.Response{
-        Message: "Valid Credentials",
-        Data: map[string]interface{}{
-            "username": req.Username,
-        },
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "profile.retrieved"),
+        Data: toProfile(user),
         Errors: nil,
-        Code: http.StatusOK,
     })
 }
 
-func (h *UserHandler) Login(c echo.Context) error {
+func (h *UserHandler) UpdateMe(c echo.Context) error {
     var req struct {
-        Username string `json:"username"`
-        Password string `json:"password"`
+        Username  string `json:"username"`
+        Email     string `json:"email"`
+        Password1 string `json:"password_1"`
+        Password2 string `json:"password_2"`
+        // Wajib diisi jika password_1 atau password_2 diisi
+        CurrentPassword string `json:"current_password"`
     }
 
-    // Bind JSON request ke struct
-    if err := c.Bind(&req); err != nil {
-        return c.JSON(http.StatusInternalServerError, domains.Response{
-            Message: "Invalid request",
-            Errors: []domains.ErrorDetail{
-                {Message: "Invalid Server Error", Parameter: "request body"},
+    principal, ok := middleware.GetPrincipal(c)
+    if !ok {
+        return envelope.JSON(c, http.StatusUnauthorized, envelope.Response{
+            Message: i18n.T(c, "auth.unauthorized"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "auth.missing_user"), Parameter: "Authorization"},
             },
-            Code: http.StatusInternalServerError,
         })
     }
 
-    var errorDetails []domains.ErrorDetail
+    if err := binding.Bind(c, &req); err != nil {
+        return binding.Write(c, err)
+    }
+
+    // Semua field opsional, field yang tidak dikirim tidak diubah
+    var validationErrors []envelope.ErrorDetail
+    username, email, password1, password2 := req.Username, req.Email, req.Password1, req.Password2
 
-    // Ambil user berdasarkan username
-    user, err := h.Usecase.GetByUsername(req.Username)
-    if err != nil || user == nil {
-        errorDetails = append(errorDetails, domains.ErrorDetail{
-            Message: "User not found",
-            Parameter: "username",
+    if password1 != password2 {
+        validationErrors = append(validationErrors, envelope.ErrorDetail{
+            Message: i18n.T(c, "password.mismatch"),
+            Parameter: "password",
         })
-    } 
+    }
 
-	if user != nil {
-		// Validasi password
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-            errorDetails = append(errorDetails, domains.ErrorDetail{
-                Message: "Invalid Password",
-                Parameter: "password",
+    // Token yang dicuri tidak boleh cukup untuk mengganti password
+    if (password1 != "" || password2 != "") && req.CurrentPassword == "" {
+        validationErrors = append(validationErrors, envelope.ErrorDetail{
+            Message: i18n.T(c, "password.current_required"),
+            Parameter: "current_password",
+        })
+    }
+
+    if len(validationErrors) > 0 {
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "error.validation"),
+            Data: nil,
+            Errors: validationErrors,
+        })
+    }
+
+    if password1 != "" {
+        if err := h.Usecase.VerifyPassword(c.Request().Context(), principal.ID, req.CurrentPassword); err != nil {
+            status := http.StatusInternalServerError
+            if errors.Is(err, domains.ErrCurrentPasswordInvalid) {
+                status = http.StatusForbidden
+            }
+            return envelope.JSON(c, status, envelope.Response{
+                Message: i18n.Err(c, err),
+                Data: nil,
+                Errors: []envelope.ErrorDetail{
+                    {Message: i18n.Err(c, err), Parameter: "current_password"},
+                },
+            })
+        }
+    }
+
+    if username == "" {
+        username = principal.Username
+    }
+
+    if err := h.Usecase.Update(c.Request().Context(), principal.ID, 0, username, email, password1, auditActor(c)); err != nil {
+        if response, ok := conflictResponse(c, err); ok {
+            return envelope.JSON(c, http.StatusConflict, response)
+        }
+        if errors.Is(err, domains.ErrVersionConflict) {
+            return precondition.Write(c, err)
+        }
+        if errors.Is(err, domains.ErrUserNotFound) {
+            return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+                Message: i18n.T(c, "user.not_found_title"),
+                Data: nil,
+                Errors: []envelope.ErrorDetail{
+                    {Message: i18n.T(c, "user.not_found_detail"), Parameter: "id"},
+                },
             })
         }
-	}
 
-    // Kirimkan response error jika ada
-    if len(errorDetails) > 0 {
-        return c.JSON(http.StatusUnauthorized, domains.Response{
-            Message: "Authentication Failed",
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "error.validation"),
             Data: nil,
-            Errors:   errorDetails,
-            Code:    http.StatusUnauthorized,
+            Errors: splitUsecaseErrors(c, err),
         })
     }
 
-    // Jika user ditemukan dan password benar, buat JWT token
-    secretKey := os.Getenv("JWT_SECRET")
-    claims := jwt.MapClaims{
-        "user_id": user.ID,
-        "exp":     time.Now().Add(1 * time.Minute).Unix(), // Token berlaku 72 jam
+    user, err := h.Usecase.GetByID(c.Request().Context(), principal.ID)
+    if err != nil {
+        return envelope.JSON(c, http.StatusNotFound, envelope.Response{
+            Message: i18n.T(c, "user.not_found_title"),
+            Data: nil,
+            Errors: nil,
+        })
     }
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-    // Tanda tangani token dengan secret key
-    tokenString, err := token.SignedString([]byte(secretKey))
-    if err != nil {
-        return c.JSON(http.StatusInternalServerError, domains.Response{
-            Message: "Failed to generate token",
-            Errors: []domains.ErrorDetail{
-                {Message: "Error signing token", Parameter: "token generation"},
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "profile.updated"),
+        Data: toProfile(user),
+        Errors: nil,
+    })
+}
+
+func (h *UserHandler) DeleteMe(c echo.Context) error {
+    principal, ok := middleware.GetPrincipal(c)
+    if !ok {
+        return envelope.JSON(c, http.StatusUnauthorized, envelope.Response{
+            Message: i18n.T(c, "auth.unauthorized"),
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.T(c, "auth.missing_user"), Parameter: "Authorization"},
             },
-            Code: http.StatusInternalServerError,
         })
     }
 
-    // Jika tidak ada error, kembalikan response yang sukses dengan token JWT
-    return c.JSON(http.StatusOK, domains.Response{
-        Message: "Login successful",
+    if _, err := h.Usecase.Delete(c.Request().Context(), principal.ID, auditActor(c)); err != nil {
+        return envelope.JSON(c, http.StatusBadRequest, envelope.Response{
+            Message: i18n.T(c, "account.delete_failed"),
+            Data: nil,
+            Errors: []envelope.ErrorDetail{
+                {Message: i18n.Err(c, err), Parameter: "id"},
+            },
+        })
+    }
+
+    return envelope.JSON(c, http.StatusOK, envelope.Response{
+        Message: i18n.T(c, "account.deleted"),
         Data: map[string]interface{}{
-            "token": tokenString,
+            "id": principal.ID,
         },
         Errors: nil,
-        Code: http.StatusOK,
     })
 }
+
+// conflictResponse membuat response 409 jika err berarti username atau email sudah dipakai user aktif lain
+func conflictResponse(c echo.Context, err error) (envelope.Response, bool) {
+	var parameter string
+	switch {
+	case errors.Is(err, domains.ErrDuplicateUsername):
+		parameter = "username"
+	case errors.Is(err, domains.ErrDuplicateEmail):
+		parameter = "email"
+	default:
+		return envelope.Response{}, false
+	}
+	return envelope.Response{
+		Message: i18n.T(c, "error.conflict"),
+		Errors: []envelope.ErrorDetail{
+			{Message: i18n.Err(c, err), Parameter: parameter},
+		},
+	}, true
+}
+
+// splitUsecaseErrors memecah error validasi dari usecase menjadi ErrorDetail per parameter
+func splitUsecaseErrors(c echo.Context, err error) []envelope.ErrorDetail {
+    var validationErrs domains.ValidationErrors
+    if !errors.As(err, &validationErrs) {
+        return []envelope.ErrorDetail{{Message: i18n.Err(c, err), Parameter: "request body"}}
+    }
+
+    details := make([]envelope.ErrorDetail, 0, len(validationErrs))
+    for _, fe := range validationErrs {
+        details = append(details, envelope.ErrorDetail{
+            Message: i18n.Err(c, fe.Err),
+            Parameter: fe.Field,
+        })
+    }
+    return details
+}
+
+func toProfile(user *domains.User) domains.ProfileResponse {
+    return domains.ProfileResponse{
+        ID:        user.ID,
+        Username:  user.Username,
+        Email:     user.Email,
+        Role:      user.Role,
+        Status:    user.Status,
+        CreatedAt: user.CreatedAt,
+        UpdatedAt: user.UpdatedAt,
+    }
+}
@@ -1,12 +1,63 @@
 package repository
 
 import (
+	"context"
 	"project-golang-crud/domains"
 	"time"
 	"gorm.io/gorm"
 	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm/clause"
+)
+
+// Nama partial unique index pada tabel users, lihat migrations.sql
+const (
+	usersUsernameIndex = "idx_users_username_active"
+	usersEmailIndex    = "idx_users_email_active"
 )
 
+// DropUserUniqueConstraints menghapus constraint UNIQUE lama pada username dan email. Harus dijalankan
+// sebelum AutoMigrate, karena AutoMigrate hanya bisa menghapus constraint bernama uni_users_*.
+func DropUserUniqueConstraints(db *gorm.DB) error {
+	for _, name := range []string{"users_username_key", "users_email_key", "uni_users_username", "uni_users_email"} {
+		if err := db.Exec("ALTER TABLE IF EXISTS users DROP CONSTRAINT IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateUserUniqueIndexes membuat unique index username dan email yang hanya berlaku untuk user
+// yang belum dihapus, sehingga nama dari akun yang sudah dihapus bisa didaftarkan ulang
+func CreateUserUniqueIndexes(db *gorm.DB) error {
+	statements := []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS " + usersUsernameIndex + " ON users (LOWER(username)) WHERE deleted_at IS NULL",
+		"CREATE UNIQUE INDEX IF NOT EXISTS " + usersEmailIndex + " ON users (LOWER(email)) WHERE deleted_at IS NULL",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateUniqueViolation mengubah unique violation dari Postgres menjadi domains.ErrDuplicateUsername atau domains.ErrDuplicateEmail
+func translateUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case usersUsernameIndex:
+		return domains.ErrDuplicateUsername
+	case usersEmailIndex:
+		return domains.ErrDuplicateEmail
+	}
+	return err
+}
+
 type userRepository struct {
 	db *gorm.DB 
 }
@@ -15,33 +66,47 @@ func NewUserRepository(db *gorm.DB) domains.UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) GetAll() ([]domains.User, error) {
+func (r *userRepository) GetAll(ctx context.Context) ([]domains.User, error) {
 	var users []domains.User
-	err := r.db.Where("deleted_at IS NULL").Find(&users).Error // Mengambil hanya users yang deleted_at = null
+	err := r.db.WithContext(ctx).Where("deleted_at IS NULL").Find(&users).Error // Mengambil hanya users yang deleted_at = null
 	return users, err
 }
 
 
-func (r *userRepository) Create(user *domains.User) error {
+func (r *userRepository) Create(ctx context.Context, user *domains.User) error {
 	if user.DeletedAt != nil {
         return errors.New("user cannot be updated because it is marked as deleted")
     }
-    return r.db.Create(&user).Error
+    return translateUniqueViolation(r.db.WithContext(ctx).Create(&user).Error)
 }
 
-func (r *userRepository) Update(user *domains.User) error {
+// Update menyimpan user hanya jika versinya belum diubah request lain sejak user dibaca, lalu
+// menaikkan versinya. Mengembalikan domains.ErrVersionConflict jika versinya sudah berubah.
+func (r *userRepository) Update(ctx context.Context, user *domains.User) error {
 	// Cek apakah user dengan deleted_at yang terisi sudah ada
 	var existingUser domains.User
-	if err := r.db.Where("id = ? AND deleted_at IS NOT NULL", user.ID).First(&existingUser).Error; err == nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NOT NULL", user.ID).First(&existingUser).Error; err == nil {
 		return errors.New("cannot update user: user is marked as deleted")
 	}
-	return r.db.Save(user).Error
+
+	// Save tidak bisa dipakai, saat tidak ada row yang cocok Save malah melakukan insert
+	version := user.Version
+	user.Version++
+	result := r.db.WithContext(ctx).Model(user).Where("version = ?", version).Select("*").Updates(user)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = domains.ErrVersionConflict
+	}
+	if result.Error != nil {
+		user.Version = version
+		return translateUniqueViolation(result.Error)
+	}
+	return nil
 }
 
-func (r *userRepository) Delete(id string) error {
+func (r *userRepository) Delete(ctx context.Context, id string) error {
 	user := &domains.User{}
     
-    if err := r.db.First(user, "id = ?", id).Error; err != nil {
+    if err := r.db.WithContext(ctx).First(user, "id = ?", id).Error; err != nil {
         return err 
     }
     
@@ -50,23 +115,121 @@ func (r *userRepository) Delete(id string) error {
     }
 
     now := time.Now() 
-	return r.db.Model(&domains.User{}).
+	return r.db.WithContext(ctx).Model(&domains.User{}).
     Where("id = ?", id).
-    Updates(map[string]interface{}{"deleted_at": now, "updated_at": gorm.Expr("updated_at")}).Error
+    Updates(map[string]interface{}{"deleted_at": now, "updated_at": gorm.Expr("updated_at"), "version": gorm.Expr("version + 1")}).Error
 }
 
-func (r *userRepository) GetByUsername(username string) (*domains.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*domains.User, error) {
 	var user domains.User
-	if err :=r.db.Where("username = ?", username).First(&user).Error; err != nil{
+	if err :=r.db.WithContext(ctx).Where("LOWER(username) = LOWER(?) AND deleted_at IS NULL", username).First(&user).Error; err != nil{
 		return nil, err
 	}
 	return &user, nil
 }
-func (r *userRepository) GetByID(id string) (*domains.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id string) (*domains.User, error) {
 	var user domains.User
-	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
 		return nil, err // Mengembalikan error jika tidak ditemukan
 	}
 	return &user, nil
 }
 
+
+// GetDeleted mengambil user yang sudah dihapus tetapi belum di-purge
+func (r *userRepository) GetDeleted(ctx context.Context) ([]domains.User, error) {
+	var users []domains.User
+	err := r.db.WithContext(ctx).Where("deleted_at IS NOT NULL AND purged_at IS NULL").Order("deleted_at DESC").Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) GetDeletedBefore(ctx context.Context, cutoff time.Time) ([]domains.User, error) {
+	var users []domains.User
+	err := r.db.WithContext(ctx).Where("deleted_at < ? AND purged_at IS NULL", cutoff).Find(&users).Error
+	return users, err
+}
+
+// Restore mengembalikan user yang dihapus, gorm.ErrRecordNotFound jika user tidak dalam status terhapus
+func (r *userRepository) Restore(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Model(&domains.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		// Username atau email sudah dipakai user baru selama akun ini terhapus
+		return translateUniqueViolation(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge menganonimkan data pribadi user yang sudah dihapus. Row user tetap ada agar
+// riwayat yang mereferensikan users(id), misalnya peminjaman buku, tetap utuh. audit_events tidak
+// diubah karena append-only: metadata-nya hanya berisi ID user, sedangkan IP dan user agent actor
+// sengaja disimpan sebagai catatan keamanan.
+func (r *userRepository) Purge(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domains.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"username":      "purged-" + id,
+				"email":         id + "@purged.invalid",
+				"password":      "",
+				"status_reason": "",
+				"purged_at":     time.Now(),
+				"version":       gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Riwayat status tetap ada, alasan yang ditulis admin bisa berisi data pribadi
+		return tx.Model(&domains.UserStatusChange{}).Where("user_id = ?", id).Update("reason", "").Error
+	})
+}
+
+// ChangeStatus mengubah status user yang belum dihapus dan mencatatnya ke user_status_history dalam
+// satu transaksi, row user dikunci agar from_status di riwayat selalu benar
+func (r *userRepository) ChangeStatus(ctx context.Context, id, status, reason, changedBy string) (*domains.UserStatusChange, error) {
+	var change *domains.UserStatusChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domains.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&domains.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":            status,
+			"status_reason":     reason,
+			"status_changed_at": time.Now(),
+			"version":           gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		change = &domains.UserStatusChange{
+			UserID:     id,
+			FromStatus: user.Status,
+			ToStatus:   status,
+			Reason:     reason,
+			ChangedBy:  changedBy,
+		}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// GetStatusHistory mengambil riwayat perubahan status user, yang terbaru lebih dulu
+func (r *userRepository) GetStatusHistory(ctx context.Context, id string) ([]domains.UserStatusChange, error) {
+	var changes []domains.UserStatusChange
+	err := r.db.WithContext(ctx).Where("user_id = ?", id).Order("created_at DESC").Find(&changes).Error
+	return changes, err
+}
@@ -1,64 +1,88 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"project-golang-crud/domains"
 	"regexp"
 	"strings"
+	"time"
 
+	"auditlog"
+	"i18n"
+	"metrics"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// dummyPasswordHash dibandingkan saat username tidak ada, agar waktu response sama dengan password yang salah
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 type userUsecase struct {
-	Repo domains.UserRepository
+	Repo  domains.UserRepository
+	Audit *auditlog.Logger
+}
+
+func NewUserUsecase(repo domains.UserRepository, audit *auditlog.Logger) domains.UserUsecase {
+	return &userUsecase{Repo: repo, Audit: audit}
 }
 
-func NewUserUsecase(repo domains.UserRepository) domains.UserUsecase {
-	return &userUsecase{Repo: repo}
+// auditEvent membuat event dengan outcome success, atau failure beserta alasannya jika err tidak nil
+func auditEvent(actor auditlog.Actor, action string, err error) auditlog.Event {
+	if err != nil {
+		return actor.Event(action, auditlog.Failure).With("reason", err.Error())
+	}
+	return actor.Event(action, auditlog.Success)
 }
 
 // userUsecase.go
-func (u *userUsecase) GetAll() ([]domains.User, error) {
-	return u.Repo.GetAll() // Memanggil repository untuk mendapatkan pengguna yang aktif
+func (u *userUsecase) GetAll(ctx context.Context) ([]domains.User, error) {
+	return u.Repo.GetAll(ctx) // Memanggil repository untuk mendapatkan pengguna yang aktif
 }
 
 
-func (u *userUsecase) Register(username, email, password string) (*domains.User, error) {
-	var validationErrors []domains.ErrorDetail
+func (u *userUsecase) Register(ctx context.Context, username, email, password string, actor auditlog.Actor) (created *domains.User, err error) {
+	defer func() {
+		event := auditEvent(actor, domains.AuditUserRegister, err)
+		if created != nil {
+			event = event.On("user", created.ID)
+		}
+		u.Audit.Record(event)
+
+		outcome := metrics.Outcome(err)
+		var invalid domains.ValidationErrors
+		if errors.As(err, &invalid) {
+			outcome = "validation.failed"
+		}
+		metrics.Registrations.WithLabelValues(outcome).Inc()
+	}()
+
+	var validationErrors domains.ValidationErrors
 
 	// Validasi username
 	if err := validateUsername(username); err != nil {
-		validationErrors = append(validationErrors, domains.ErrorDetail{
-			Message: err.Error(),
-			Parameter: "username",
-		})
+		validationErrors = append(validationErrors, domains.FieldError{Field: "username", Err: err})
 	}
 
 	// Validasi email
 	if err := validateEmail(email); err != nil {
-		validationErrors = append(validationErrors, domains.ErrorDetail{
-			Message: err.Error(),
-			Parameter: "email",
-		})
+		validationErrors = append(validationErrors, domains.FieldError{Field: "email", Err: err})
 	}
 
 	// Validasi password
 	if err := validatePassword(password); err != nil {
-		validationErrors = append(validationErrors, domains.ErrorDetail{
-			Message: err.Error(),
-			Parameter: "password",
-		})
+		validationErrors = append(validationErrors, domains.FieldError{Field: "password", Err: err})
 	}
 
 	// Jika ada error validasi, return semua error
 	if len(validationErrors) > 0 {
-		return nil, errors.New(mergeValidationErrors(validationErrors))
+		return nil, validationErrors
 	}
 
 	// Cek apakah username sudah ada
-	existingUser, err := u.Repo.GetByUsername(username)
+	existingUser, err := u.Repo.GetByUsername(ctx, username)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("duplicate username")
+		return nil, domains.ErrDuplicateUsername
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -71,45 +95,43 @@ func (u *userUsecase) Register(username, email, password string) (*domains.User,
 		Email:    email,
 		Password: string(hashedPassword),
 	}
-	if err := u.Repo.Create(user); err != nil {
+	if err := u.Repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func mergeValidationErrors(errors []domains.ErrorDetail) string {
-	var messages []string
-	for _, err := range errors {
-		messages = append(messages, err.Message)
-	}
-	return strings.Join(messages, "; ")
-}
-
-
-func mergeErrors(errors []string) string {
-	return strings.Join(errors, "; ")
-}
+func (u *userUsecase) Update(ctx context.Context, id string, version int, username, email, password string, actor auditlog.Actor) (err error) {
+	passwordChanged := false
+	defer func() {
+		u.Audit.Record(auditEvent(actor, domains.AuditUserUpdate, err).On("user", id))
+		if passwordChanged {
+			u.Audit.Record(auditEvent(actor, domains.AuditUserPasswordChange, err).On("user", id))
+		}
+	}()
 
-func (u *userUsecase) Update(id string, username, email, password string) error {
-	user, err := u.Repo.GetByID(id)
+	user, err := u.Repo.GetByID(ctx, id)
 	if err != nil {
 		return domains.ErrUserNotFound
 	}
 
 	if user.DeletedAt != nil {
-		return errors.New("User cannot be updated because it is marked as deleted")
+		return i18n.NewError("user.update_deleted")
+	}
+	if version != 0 && user.Version != version {
+		return domains.ErrVersionConflict
 	}
 
-	var validationErrors []string
+	var validationErrors domains.ValidationErrors
 
 	// Validasi username
 	if username == "" {
-		return errors.New("Username is required")
+		return domains.ValidationErrors{{Field: "username", Err: i18n.NewError("user.username_required")}}
 	} else if username != user.Username {
 		if err := validateUsername(username); err != nil {
-			validationErrors = append(validationErrors, err.Error())
-		} else if existingUser, _ := u.Repo.GetByUsername(username); existingUser != nil {
-			validationErrors = append(validationErrors, "duplicate username")
+			validationErrors = append(validationErrors, domains.FieldError{Field: "username", Err: err})
+		} else if existingUser, _ := u.Repo.GetByUsername(ctx, username); existingUser != nil && existingUser.ID != user.ID {
+			return domains.ErrDuplicateUsername
 		} else {
 			user.Username = username // Update username
 		}
@@ -118,7 +140,7 @@ func (u *userUsecase) Update(id string, username, email, password string) error
 	// Validasi email
 	if email != "" && email != user.Email {
 		if err := validateEmail(email); err != nil {
-			validationErrors = append(validationErrors, err.Error())
+			validationErrors = append(validationErrors, domains.FieldError{Field: "email", Err: err})
 		} else {
 			user.Email = email // Update email
 		}
@@ -127,57 +149,106 @@ func (u *userUsecase) Update(id string, username, email, password string) error
 	// Validasi password
 	if password != "" {
 		if err := validatePassword(password); err != nil {
-			validationErrors = append(validationErrors, err.Error())
+			validationErrors = append(validationErrors, domains.FieldError{Field: "password", Err: err})
 		} else {
 			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 			if err != nil {
 				return err // Handle hashing error
 			}
 			user.Password = string(hashedPassword) // Update password
+			passwordChanged = true
 		}
 	}
 
 	// Jika ada error validasi, return semua error
 	if len(validationErrors) > 0 {
-		return errors.New(mergeErrors(validationErrors))
+		return validationErrors
 	}
 
-	return u.Repo.Update(user) // Lakukan pembaruan ke repositori
+	return u.Repo.Update(ctx, user) // Lakukan pembaruan ke repositori
 }
 
-func (u *userUsecase) Delete(id string) (*domains.User, error) {
-	user, err := u.Repo.GetByID(id)
+func (u *userUsecase) Delete(ctx context.Context, id string, actor auditlog.Actor) (deleted *domains.User, err error) {
+	defer func() {
+		u.Audit.Record(auditEvent(actor, domains.AuditUserDelete, err).On("user", id))
+	}()
+
+	user, err := u.Repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, domains.ErrUserNotFound
 	}
 
 	if user.DeletedAt != nil {
-		return nil, errors.New("User cannot be deleted because it is already marked as deleted")
+		return nil, i18n.NewError("user.delete_deleted")
 	}
 
-	if err := u.Repo.Delete(id); err != nil {
+	if err := u.Repo.Delete(ctx, id); err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (u *userUsecase) Validate(username, password string) (string, error) {
-	user, err := u.Repo.GetByUsername(username)
-	if err != nil {
-		return "User not found", nil
+// Authenticate memeriksa username dan password untuk login, hasilnya dicatat ke audit log
+func (u *userUsecase) Authenticate(ctx context.Context, username, password string, actor auditlog.Actor) (authenticated *domains.User, err error) {
+	defer func() {
+		event := auditEvent(actor, domains.AuditUserLogin, err)
+		if authenticated != nil {
+			event = event.On("user", authenticated.ID)
+			event.ActorID = authenticated.ID
+		}
+		u.Audit.Record(event)
+		metrics.LoginAttempts.WithLabelValues("password", metrics.Outcome(err)).Inc()
+	}()
+
+	user, err := u.Repo.GetByUsername(ctx, username)
+	if err != nil || user == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, domains.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, domains.ErrInvalidCredentials
+	}
+
+	// Status diperiksa setelah password agar status akun tidak bocor ke orang yang tidak tahu password-nya
+	if err := user.StatusError(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Validate memeriksa username dan password tanpa login, username yang tidak ada dan password yang salah
+// sama-sama mengembalikan ErrInvalidCredentials
+func (u *userUsecase) Validate(ctx context.Context, username, password string) error {
+	user, err := u.Repo.GetByUsername(ctx, username)
+	if err != nil || user == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return domains.ErrInvalidCredentials
 	}
 
 	// Cek apakah password valid
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "Invalid Password", nil // Password salah, tapi tidak melempar error
+		return domains.ErrInvalidCredentials
 	}
 
-	return "Valid Credentials", nil
+	return nil
 }
 
-func (u *userUsecase) GetByUsername(username string) (*domains.User, error) {
-	user, err := u.Repo.GetByUsername(username)
+// VerifyPassword memeriksa password saat ini sebelum user mengganti password-nya sendiri
+func (u *userUsecase) VerifyPassword(ctx context.Context, id, password string) error {
+	user, err := u.Repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return domains.ErrCurrentPasswordInvalid
+	}
+	return nil
+}
+
+func (u *userUsecase) GetByUsername(ctx context.Context, username string) (*domains.User, error) {
+	user, err := u.Repo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -192,25 +263,108 @@ func validatePassword(password string) error {
 		hasSpecial = regexp.MustCompile(`[!@#\$%\^&\*\(\)_\+\-=\[\]\{\};:'"<>,\./?\\|]`).MatchString(password)
 	)
 	if !hasMinLen || !hasNumber || !hasUpper || !hasSpecial{
-		return errors.New("Password must be at least 8 characters long, contain an uppercase letter, a number, and a special character")
+		return i18n.NewError("user.password_rules")
 	}
 	return nil
 }
 
 func validateUsername(username string) error {
 	if match, _ := regexp.MatchString(`^[a-zA-Z0-9_]+$`, username); !match {
-		return errors.New("Username can only contain letters, numbers, and underscores")
+		return i18n.NewError("user.username_format")
 	}
 	return nil
 }
 
 func validateEmail(email string) error {
 	if match, _ := regexp.MatchString(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`, email); !match {
-		return errors.New("Invalid email format")
+		return i18n.NewError("user.email_format")
 	}
 	return nil
 }
 
-func (u *userUsecase) GetByID(id string) (*domains.User, error) {
-	return u.Repo.GetByID(id)
+func (u *userUsecase) GetByID(ctx context.Context, id string) (*domains.User, error) {
+	return u.Repo.GetByID(ctx, id)
+}
+
+// ListDeleted mengambil user yang sudah dihapus dan masih bisa di-restore
+func (u *userUsecase) ListDeleted(ctx context.Context) ([]domains.User, error) {
+	return u.Repo.GetDeleted(ctx)
+}
+
+// Restore mengembalikan user yang dihapus selama belum di-purge
+func (u *userUsecase) Restore(ctx context.Context, id string, actor auditlog.Actor) error {
+	err := u.Repo.Restore(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = domains.ErrUserNotDeleted
+	}
+	u.Audit.Record(auditEvent(actor, domains.AuditUserRestore, err).On("user", id))
+	return err
+}
+
+// Purge menganonimkan data pribadi user yang sudah dihapus, tidak bisa dibatalkan
+func (u *userUsecase) Purge(ctx context.Context, id string, actor auditlog.Actor) error {
+	err := u.Repo.Purge(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = domains.ErrUserNotDeleted
+	}
+	u.Audit.Record(auditEvent(actor, domains.AuditUserPurge, err).On("user", id))
+	return err
+}
+
+// PurgeExpired melakukan purge pada semua user yang dihapus lebih lama dari retention
+func (u *userUsecase) PurgeExpired(ctx context.Context, retention time.Duration) (int, error) {
+	users, err := u.Repo.GetDeletedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	// Dijalankan oleh sistem, bukan oleh user tertentu
+	purged := 0
+	for _, user := range users {
+		if err := u.Purge(ctx, user.ID, auditlog.Actor{}); err != nil {
+			// User yang sudah di-restore atau di-purge proses lain dilewati, tidak ikut dihitung
+			if err == domains.ErrUserNotDeleted {
+				continue
+			}
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ChangeStatus mengubah status akun, alasan wajib diisi dan dicatat ke riwayat status
+func (u *userUsecase) ChangeStatus(ctx context.Context, id, status, reason string, actor auditlog.Actor) (change *domains.UserStatusChange, err error) {
+	reason = strings.TrimSpace(reason)
+	defer func() {
+		event := auditEvent(actor, domains.AuditUserStatusChange, err).On("user", id).With("status", status)
+		if change != nil {
+			event = event.With("from_status", change.FromStatus)
+		}
+		u.Audit.Record(event) // Alasan hanya disimpan di user_status_history, yang dikosongkan saat purge
+	}()
+
+	if !domains.ValidStatus(status) {
+		return nil, domains.ErrInvalidStatus
+	}
+	if reason == "" {
+		return nil, domains.ErrStatusReasonRequired
+	}
+
+	change, err = u.Repo.ChangeStatus(ctx, id, status, reason, actor.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domains.ErrUserNotFound
+	}
+	return change, err
+}
+
+// StatusHistory mengambil riwayat perubahan status akun, yang terbaru lebih dulu
+func (u *userUsecase) StatusHistory(ctx context.Context, id string) ([]domains.UserStatusChange, error) {
+	if _, err := u.Repo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domains.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return u.Repo.GetStatusHistory(ctx, id)
 }